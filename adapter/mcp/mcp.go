@@ -7,6 +7,7 @@ import (
 
 	"github.com/sashabaranov/go-openai"
 	mcpclient "github.com/smallnest/goskills/mcp"
+	"github.com/smallnest/langgraphgo/tool"
 	"github.com/tmc/langchaingo/tools"
 )
 
@@ -18,7 +19,7 @@ type MCPTool struct {
 	parameters  any // JSON schema for the tool parameters
 }
 
-var _ tools.Tool = &MCPTool{}
+var _ tool.SchemaTool = &MCPTool{}
 
 func (t *MCPTool) Name() string {
 	return t.name
@@ -28,6 +29,27 @@ func (t *MCPTool) Description() string {
 	return t.description
 }
 
+// Parameters returns the JSON schema of the tool parameters, so that agents pass
+// the model's arguments to the tool as they are
+func (t *MCPTool) Parameters() map[string]any {
+	switch params := t.parameters.(type) {
+	case nil:
+		return nil
+	case map[string]any:
+		return params
+	default:
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil
+		}
+		var schema map[string]any
+		if err := json.Unmarshal(data, &schema); err != nil {
+			return nil
+		}
+		return schema
+	}
+}
+
 func (t *MCPTool) Call(ctx context.Context, input string) (string, error) {
 	// Parse input JSON into a map
 	var args map[string]interface{}
@@ -96,12 +118,16 @@ func NewClientFromConfig(ctx context.Context, configPath string) (*mcpclient.Cli
 	return client, nil
 }
 
-// GetToolSchema returns the JSON schema for a tool's parameters.
+// GetToolSchema returns the JSON schema for a tool's parameters. It supports MCP
+// tools and any tool.SchemaTool, such as the tools created with tool.FromFunc.
 // This can be useful for debugging or generating documentation.
-func GetToolSchema(tool tools.Tool) (any, bool) {
-	if mcpTool, ok := tool.(*MCPTool); ok {
+func GetToolSchema(t tools.Tool) (any, bool) {
+	if mcpTool, ok := t.(*MCPTool); ok {
 		return mcpTool.parameters, true
 	}
+	if st, ok := t.(tool.SchemaTool); ok {
+		return st.Parameters(), true
+	}
 	return nil, false
 }
 
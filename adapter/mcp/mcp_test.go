@@ -2,8 +2,10 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 
+	"github.com/smallnest/langgraphgo/tool"
 	"github.com/stretchr/testify/assert"
 	"github.com/tmc/langchaingo/tools"
 )
@@ -34,13 +36,13 @@ func TestGetToolSchema(t *testing.T) {
 		},
 	}
 
-	tool := &MCPTool{
+	mcpTool := &MCPTool{
 		name:        "search",
 		description: "Search tool",
 		parameters:  schema,
 	}
 
-	retrievedSchema, ok := GetToolSchema(tool)
+	retrievedSchema, ok := GetToolSchema(mcpTool)
 	assert.True(t, ok)
 	assert.Equal(t, schema, retrievedSchema)
 
@@ -53,6 +55,17 @@ func TestGetToolSchema(t *testing.T) {
 	retrievedSchema, ok = GetToolSchema(nonMCPTool)
 	assert.True(t, ok)
 	assert.Nil(t, retrievedSchema)
+
+	// Other tools with a schema, such as typed Go functions
+	type queryInput struct {
+		Query string `json:"query"`
+	}
+	funcTool := tool.FromFunc("query", "Query tool", func(ctx context.Context, in queryInput) (string, error) {
+		return in.Query, nil
+	})
+	retrievedSchema, ok = GetToolSchema(funcTool)
+	assert.True(t, ok)
+	assert.Equal(t, funcTool.Parameters(), retrievedSchema)
 }
 
 // TestMCPToTools_EmptyClient tests the conversion with no tools
@@ -104,3 +117,17 @@ func ExampleNewClientFromConfig() {
 	// Now you can use the client to get tools or call them directly
 	_ = client
 }
+
+func TestMCPTool_Parameters(t *testing.T) {
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"query": map[string]any{"type": "string"}},
+	}
+	assert.Equal(t, schema, (&MCPTool{parameters: schema}).Parameters())
+
+	// Schemas in other forms are converted through JSON
+	raw := json.RawMessage(`{"type": "object", "required": ["query"]}`)
+	assert.Equal(t, map[string]any{"type": "object", "required": []any{"query"}}, (&MCPTool{parameters: raw}).Parameters())
+
+	assert.Nil(t, (&MCPTool{}).Parameters())
+}
@@ -157,7 +157,7 @@ func (s *PostgresCheckpointStore) Load(ctx context.Context, checkpointID string)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("checkpoint not found: %s", checkpointID)
+			return nil, fmt.Errorf("%w: %s", graph.ErrCheckpointNotFound, checkpointID)
 		}
 		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
 	}
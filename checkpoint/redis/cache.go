@@ -0,0 +1,98 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisCache implements graph.Cache using Redis.
+// Values are stored as JSON and read back as json.RawMessage, which the graph
+// decodes with the node's CachePolicy (see graph.JSONCache).
+type RedisCache struct {
+	client *redis.Client
+	prefix string
+}
+
+// RedisCacheOptions configuration for the Redis cache
+type RedisCacheOptions struct {
+	Prefix string // Key prefix, default "<store prefix>cache:"
+}
+
+// NewRedisCache creates a node result cache that shares the client of the checkpoint store
+func NewRedisCache(store *RedisCheckpointStore, opts RedisCacheOptions) *RedisCache {
+	prefix := opts.Prefix
+	if prefix == "" {
+		prefix = store.prefix + "cache:"
+	}
+
+	return &RedisCache{
+		client: store.client,
+		prefix: prefix,
+	}
+}
+
+func (c *RedisCache) cacheKey(key string) string {
+	return c.prefix + key
+}
+
+// StoresJSON implements graph.JSONCache
+func (c *RedisCache) StoresJSON() {}
+
+// Get retrieves the JSON of a cached value
+func (c *RedisCache) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	data, err := c.client.Get(ctx, c.cacheKey(key)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to load cache entry from redis: %w", err)
+	}
+
+	return json.RawMessage(data), true, nil
+}
+
+// Set stores a value with an optional TTL
+func (c *RedisCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+
+	if err := c.client.Set(ctx, c.cacheKey(key), data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save cache entry to redis: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a cached value
+func (c *RedisCache) Delete(ctx context.Context, key string) error {
+	if err := c.client.Del(ctx, c.cacheKey(key)).Err(); err != nil {
+		return fmt.Errorf("failed to delete cache entry: %w", err)
+	}
+	return nil
+}
+
+// Clear removes all cached values under the cache prefix
+func (c *RedisCache) Clear(ctx context.Context) error {
+	iter := c.client.Scan(ctx, 0, c.prefix+"*", 100).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("failed to scan cache keys: %w", err)
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if err := c.client.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("failed to clear cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisCache(t *testing.T) {
+	mr, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer mr.Close()
+
+	store := NewRedisCheckpointStore(RedisOptions{
+		Addr: mr.Addr(),
+	})
+	cache := NewRedisCache(store, RedisCacheOptions{})
+
+	ctx := context.Background()
+
+	// Miss
+	_, found, err := cache.Get(ctx, "node:key")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	// Set and hit
+	err = cache.Set(ctx, "node:key", map[string]interface{}{"answer": "42"}, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, mr.Exists("langgraph:cache:node:key"))
+
+	value, found, err := cache.Get(ctx, "node:key")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.JSONEq(t, `{"answer":"42"}`, string(value.(json.RawMessage)))
+
+	// TTL expiry
+	mr.FastForward(2 * time.Minute)
+	_, found, err = cache.Get(ctx, "node:key")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	// Clear only removes cache keys
+	assert.NoError(t, cache.Set(ctx, "a", 1, 0))
+	assert.NoError(t, cache.Set(ctx, "b", 2, 0))
+	assert.NoError(t, mr.Set("langgraph:checkpoint:keep", "x"))
+	assert.NoError(t, cache.Clear(ctx))
+	_, found, _ = cache.Get(ctx, "a")
+	assert.False(t, found)
+	assert.True(t, mr.Exists("langgraph:checkpoint:keep"))
+}
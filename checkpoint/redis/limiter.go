@@ -0,0 +1,168 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/smallnest/langgraphgo/graph"
+)
+
+// The scripts run atomically in Redis and use its clock, so that all the processes
+// sharing a limiter agree on its state. Times are in milliseconds and the circuit
+// states match graph.CircuitClosed (0), graph.CircuitOpen (1) and graph.CircuitHalfOpen (2).
+
+var takeTokenScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+if capacity <= 0 or window <= 0 then
+  return redis.error_reply('invalid token bucket')
+end
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(bucket[1]) or capacity
+local last = tonumber(bucket[2]) or now
+local rate = capacity / window
+tokens = math.min(capacity, tokens + math.max(0, now - last) * rate)
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+else
+  wait = math.ceil((1 - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', tostring(now))
+redis.call('PEXPIRE', KEYS[1], window)
+return wait
+`)
+
+var allowCallScript = redis.NewScript(`
+local timeout = tonumber(ARGV[1])
+local halfOpenMaxCalls = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local circuit = redis.call('HMGET', KEYS[1], 'state', 'last_failure', 'half_open_calls')
+local state = tonumber(circuit[1]) or 0
+if state == 1 then
+  if now - (tonumber(circuit[2]) or 0) <= timeout then
+    return 0
+  end
+  redis.call('HSET', KEYS[1], 'state', '2', 'half_open_calls', '0')
+elseif state == 2 then
+  local calls = tonumber(circuit[3]) or 0
+  if calls >= halfOpenMaxCalls then
+    redis.call('HSET', KEYS[1], 'state', '1')
+    return 0
+  end
+  redis.call('HSET', KEYS[1], 'half_open_calls', tostring(calls + 1))
+end
+return 1
+`)
+
+var recordFailureScript = redis.NewScript(`
+local threshold = tonumber(ARGV[1])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local circuit = redis.call('HMGET', KEYS[1], 'state', 'failures')
+local state = tonumber(circuit[1]) or 0
+local failures = (tonumber(circuit[2]) or 0) + 1
+redis.call('HSET', KEYS[1], 'failures', tostring(failures), 'successes', '0', 'last_failure', tostring(now))
+if failures >= threshold and state ~= 1 then
+  redis.call('HSET', KEYS[1], 'state', '1')
+  return 1
+end
+return 0
+`)
+
+var recordSuccessScript = redis.NewScript(`
+local threshold = tonumber(ARGV[1])
+local circuit = redis.call('HMGET', KEYS[1], 'state', 'successes')
+local state = tonumber(circuit[1]) or 0
+local successes = (tonumber(circuit[2]) or 0) + 1
+redis.call('HSET', KEYS[1], 'failures', '0', 'successes', tostring(successes))
+if state == 2 and successes >= threshold then
+  redis.call('HSET', KEYS[1], 'state', '0')
+end
+return 0
+`)
+
+// RedisLimiterStore implements graph.LimiterStore using Redis, sharing the state of
+// rate limiters and circuit breakers across processes
+type RedisLimiterStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// RedisLimiterOptions configuration for the Redis limiter store
+type RedisLimiterOptions struct {
+	Prefix string // Key prefix, default "<store prefix>limiter:"
+}
+
+// NewRedisLimiterStore creates a limiter store that shares the client of the checkpoint store
+func NewRedisLimiterStore(store *RedisCheckpointStore, opts RedisLimiterOptions) *RedisLimiterStore {
+	prefix := opts.Prefix
+	if prefix == "" {
+		prefix = store.prefix + "limiter:"
+	}
+
+	return &RedisLimiterStore{
+		client: store.client,
+		prefix: prefix,
+	}
+}
+
+func (s *RedisLimiterStore) bucketKey(key string) string {
+	return s.prefix + "bucket:" + key
+}
+
+func (s *RedisLimiterStore) circuitKey(key string) string {
+	return s.prefix + "circuit:" + key
+}
+
+// milliseconds converts d to whole milliseconds, rounding up to at least one
+func milliseconds(d time.Duration) int64 {
+	ms := (d + time.Millisecond - 1).Milliseconds()
+	if ms < 1 {
+		return 1
+	}
+	return ms
+}
+
+// TakeToken takes a token from the bucket for key
+func (s *RedisLimiterStore) TakeToken(ctx context.Context, key string, capacity int, window time.Duration) (time.Duration, error) {
+	if capacity <= 0 || window <= 0 {
+		return 0, fmt.Errorf("invalid token bucket: capacity %d, window %v", capacity, window)
+	}
+	wait, err := takeTokenScript.Run(ctx, s.client, []string{s.bucketKey(key)}, capacity, milliseconds(window)).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+	return time.Duration(wait) * time.Millisecond, nil
+}
+
+// AllowCall reports whether the circuit for key lets a call through
+func (s *RedisLimiterStore) AllowCall(ctx context.Context, key string, config graph.CircuitBreakerConfig) (bool, error) {
+	allowed, err := allowCallScript.Run(ctx, s.client, []string{s.circuitKey(key)}, milliseconds(config.Timeout), config.HalfOpenMaxCalls).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to check circuit state: %w", err)
+	}
+	return allowed == 1, nil
+}
+
+// RecordFailure records a failed call and reports whether it tripped the circuit open
+func (s *RedisLimiterStore) RecordFailure(ctx context.Context, key string, config graph.CircuitBreakerConfig) (bool, error) {
+	opened, err := recordFailureScript.Run(ctx, s.client, []string{s.circuitKey(key)}, config.FailureThreshold).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to record circuit failure: %w", err)
+	}
+	return opened == 1, nil
+}
+
+// RecordSuccess records a successful call
+func (s *RedisLimiterStore) RecordSuccess(ctx context.Context, key string, config graph.CircuitBreakerConfig) error {
+	if err := recordSuccessScript.Run(ctx, s.client, []string{s.circuitKey(key)}, config.SuccessThreshold).Err(); err != nil {
+		return fmt.Errorf("failed to record circuit success: %w", err)
+	}
+	return nil
+}
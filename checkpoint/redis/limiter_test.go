@@ -0,0 +1,124 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/smallnest/langgraphgo/graph"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisLimiterStore_TokenBucket(t *testing.T) {
+	mr, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer mr.Close()
+	now := time.Now()
+	mr.SetTime(now)
+
+	// Two stores with their own clients, as in two processes
+	first := NewRedisLimiterStore(NewRedisCheckpointStore(RedisOptions{Addr: mr.Addr()}), RedisLimiterOptions{})
+	second := NewRedisLimiterStore(NewRedisCheckpointStore(RedisOptions{Addr: mr.Addr()}), RedisLimiterOptions{})
+	ctx := context.Background()
+
+	wait, err := first.TakeToken(ctx, "llm", 2, time.Second)
+	assert.NoError(t, err)
+	assert.Zero(t, wait)
+	assert.True(t, mr.Exists("langgraph:limiter:bucket:llm"))
+	wait, err = second.TakeToken(ctx, "llm", 2, time.Second)
+	assert.NoError(t, err)
+	assert.Zero(t, wait)
+
+	// The shared bucket is empty and refills one token every 500ms
+	wait, err = first.TakeToken(ctx, "llm", 2, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	mr.SetTime(now.Add(250 * time.Millisecond))
+	wait, err = second.TakeToken(ctx, "llm", 2, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, 250*time.Millisecond, wait)
+
+	mr.SetTime(now.Add(500 * time.Millisecond))
+	wait, err = second.TakeToken(ctx, "llm", 2, time.Second)
+	assert.NoError(t, err)
+	assert.Zero(t, wait)
+
+	// Buckets that never refill are rejected
+	_, err = first.TakeToken(ctx, "llm", 0, time.Second)
+	assert.Error(t, err)
+	_, err = takeTokenScript.Run(ctx, first.client, []string{first.bucketKey("empty")}, 0, 1000).Int64()
+	assert.ErrorContains(t, err, "invalid token bucket")
+}
+
+func TestRedisLimiterStore_CircuitBreaker(t *testing.T) {
+	mr, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer mr.Close()
+	now := time.Now()
+	mr.SetTime(now)
+
+	store := NewRedisLimiterStore(NewRedisCheckpointStore(RedisOptions{Addr: mr.Addr()}), RedisLimiterOptions{Prefix: "app:"})
+	config := graph.CircuitBreakerConfig{FailureThreshold: 2, SuccessThreshold: 1, Timeout: time.Minute, HalfOpenMaxCalls: 1, Store: store, Key: "upstream"}
+
+	healthy := false
+	fn := func(ctx context.Context, state interface{}) (interface{}, error) {
+		if healthy {
+			return "ok", nil
+		}
+		return nil, errors.New("unavailable")
+	}
+	first := graph.NewCircuitBreaker(graph.Node{Name: "a", Function: fn}, config)
+	second := graph.NewCircuitBreaker(graph.Node{Name: "b", Function: fn}, config)
+	ctx := context.Background()
+
+	// Failures in both breakers trip the shared circuit
+	_, err = first.Execute(ctx, nil)
+	assert.NotErrorIs(t, err, graph.ErrCircuitOpen)
+	_, err = second.Execute(ctx, nil)
+	assert.NotErrorIs(t, err, graph.ErrCircuitOpen)
+	assert.Equal(t, "1", mr.HGet("app:circuit:upstream", "state"))
+
+	_, err = first.Execute(ctx, nil)
+	assert.ErrorIs(t, err, graph.ErrCircuitOpen)
+
+	// After the timeout a half-open call closes the circuit again
+	mr.SetTime(now.Add(2 * time.Minute))
+	healthy = true
+	result, err := second.Execute(ctx, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", result)
+	assert.Equal(t, "0", mr.HGet("app:circuit:upstream", "state"))
+
+	result, err = first.Execute(ctx, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", result)
+}
+
+func TestRedisLimiterStore_RateLimitedGraph(t *testing.T) {
+	mr, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer mr.Close()
+
+	store := NewRedisLimiterStore(NewRedisCheckpointStore(RedisOptions{Addr: mr.Addr()}), RedisLimiterOptions{})
+	g := graph.NewStateGraph()
+	g.AddNode("llm", "llm", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return state, nil
+	}, graph.WithRateLimit(graph.RateLimitConfig{MaxCalls: 1, Window: time.Hour, Store: store}))
+	g.AddEdge("llm", graph.END)
+	g.SetEntryPoint("llm")
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	_, err = runnable.Invoke(context.Background(), "input")
+	assert.NoError(t, err)
+	_, err = runnable.Invoke(context.Background(), "input")
+	assert.ErrorIs(t, err, graph.ErrRateLimited)
+
+	// Store errors fail the node
+	mr.Close()
+	_, err = runnable.Invoke(context.Background(), "input")
+	assert.ErrorContains(t, err, "rate limiter store error for llm")
+}
@@ -89,7 +89,7 @@ func (s *RedisCheckpointStore) Load(ctx context.Context, checkpointID string) (*
 	data, err := s.client.Get(ctx, key).Bytes()
 	if err != nil {
 		if err == redis.Nil {
-			return nil, fmt.Errorf("checkpoint not found: %s", checkpointID)
+			return nil, fmt.Errorf("%w: %s", graph.ErrCheckpointNotFound, checkpointID)
 		}
 		return nil, fmt.Errorf("failed to load checkpoint from redis: %w", err)
 	}
@@ -0,0 +1,129 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SqliteCache implements graph.Cache using SQLite.
+// Values are stored as JSON and read back as json.RawMessage, which the graph
+// decodes with the node's CachePolicy (see graph.JSONCache).
+type SqliteCache struct {
+	db        *sql.DB
+	tableName string
+}
+
+// SqliteCacheOptions configuration for the SQLite cache
+type SqliteCacheOptions struct {
+	TableName string // Default "node_cache"
+}
+
+// NewSqliteCache creates a node result cache that shares the database connection of the checkpoint store
+func NewSqliteCache(store *SqliteCheckpointStore, opts SqliteCacheOptions) (*SqliteCache, error) {
+	tableName := opts.TableName
+	if tableName == "" {
+		tableName = "node_cache"
+	}
+
+	cache := &SqliteCache{
+		db:        store.db,
+		tableName: tableName,
+	}
+
+	if err := cache.InitSchema(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return cache, nil
+}
+
+// InitSchema creates the cache table if it doesn't exist
+func (c *SqliteCache) InitSchema(ctx context.Context) error {
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL,
+			expires_at INTEGER NOT NULL
+		);
+	`, c.tableName)
+
+	_, err := c.db.ExecContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to create cache schema: %w", err)
+	}
+	return nil
+}
+
+// StoresJSON implements graph.JSONCache
+func (c *SqliteCache) StoresJSON() {}
+
+// Get retrieves the JSON of a cached value
+func (c *SqliteCache) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	query := fmt.Sprintf("SELECT value, expires_at FROM %s WHERE key = ?", c.tableName)
+
+	var valueJSON string
+	var expiresAt int64
+	err := c.db.QueryRowContext(ctx, query, key).Scan(&valueJSON, &expiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to load cache entry: %w", err)
+	}
+
+	if expiresAt > 0 && time.Now().UnixNano() > expiresAt {
+		if err := c.Delete(ctx, key); err != nil {
+			return nil, false, err
+		}
+		return nil, false, nil
+	}
+
+	return json.RawMessage(valueJSON), true, nil
+}
+
+// Set stores a value with an optional TTL
+func (c *SqliteCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+
+	var expiresAt int64
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl).UnixNano()
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (key, value, expires_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET
+			value = excluded.value,
+			expires_at = excluded.expires_at
+	`, c.tableName)
+
+	if _, err := c.db.ExecContext(ctx, query, key, string(valueJSON), expiresAt); err != nil {
+		return fmt.Errorf("failed to save cache entry: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a cached value
+func (c *SqliteCache) Delete(ctx context.Context, key string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE key = ?", c.tableName)
+	if _, err := c.db.ExecContext(ctx, query, key); err != nil {
+		return fmt.Errorf("failed to delete cache entry: %w", err)
+	}
+	return nil
+}
+
+// Clear removes all cached values
+func (c *SqliteCache) Clear(ctx context.Context) error {
+	query := fmt.Sprintf("DELETE FROM %s", c.tableName)
+	if _, err := c.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to clear cache: %w", err)
+	}
+	return nil
+}
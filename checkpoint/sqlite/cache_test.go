@@ -0,0 +1,58 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSqliteCache(t *testing.T) {
+	store, err := NewSqliteCheckpointStore(SqliteOptions{
+		Path: ":memory:",
+	})
+	assert.NoError(t, err)
+	defer store.Close()
+
+	cache, err := NewSqliteCache(store, SqliteCacheOptions{})
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+
+	// Miss
+	_, found, err := cache.Get(ctx, "node:key")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	// Set and hit
+	err = cache.Set(ctx, "node:key", map[string]interface{}{"answer": "42"}, 0)
+	assert.NoError(t, err)
+
+	value, found, err := cache.Get(ctx, "node:key")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.JSONEq(t, `{"answer":"42"}`, string(value.(json.RawMessage)))
+
+	// Expired entries are misses
+	err = cache.Set(ctx, "node:expiring", "v", time.Millisecond)
+	assert.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	_, found, err = cache.Get(ctx, "node:expiring")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	// Delete
+	err = cache.Delete(ctx, "node:key")
+	assert.NoError(t, err)
+	_, found, _ = cache.Get(ctx, "node:key")
+	assert.False(t, found)
+
+	// Clear
+	assert.NoError(t, cache.Set(ctx, "a", 1, 0))
+	assert.NoError(t, cache.Set(ctx, "b", 2, 0))
+	assert.NoError(t, cache.Clear(ctx))
+	_, found, _ = cache.Get(ctx, "a")
+	assert.False(t, found)
+}
@@ -143,7 +143,7 @@ func (s *SqliteCheckpointStore) Load(ctx context.Context, checkpointID string) (
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("checkpoint not found: %s", checkpointID)
+			return nil, fmt.Errorf("%w: %s", graph.ErrCheckpointNotFound, checkpointID)
 		}
 		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
 	}
@@ -57,7 +57,7 @@ func TestSqliteCheckpointStore(t *testing.T) {
 	assert.NoError(t, err)
 
 	_, err = store.Load(ctx, "cp-1")
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, graph.ErrCheckpointNotFound)
 
 	list, err = store.List(ctx, execID)
 	assert.NoError(t, err)
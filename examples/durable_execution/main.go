@@ -60,8 +60,8 @@ func (s *DiskStore) List(ctx context.Context, threadID string) ([]*graph.Checkpo
 	cps := s.loadAll()
 	var result []*graph.Checkpoint
 	for _, cp := range cps {
-		// Check metadata for thread_id
-		if tid, ok := cp.Metadata["thread_id"].(string); ok && tid == threadID {
+		// Checkpoints record their thread as the execution ID
+		if tid, ok := cp.Metadata["execution_id"].(string); ok && tid == threadID {
 			result = append(result, cp)
 		}
 	}
@@ -81,7 +81,7 @@ func (s *DiskStore) Delete(ctx context.Context, id string) error {
 func (s *DiskStore) Clear(ctx context.Context, threadID string) error {
 	cps := s.loadAll()
 	for id, cp := range cps {
-		if tid, ok := cp.Metadata["thread_id"].(string); ok && tid == threadID {
+		if tid, ok := cp.Metadata["execution_id"].(string); ok && tid == threadID {
 			delete(cps, id)
 		}
 	}
@@ -120,6 +120,16 @@ func main() {
 		fmt.Println("Executing Step 2...")
 		time.Sleep(500 * time.Millisecond)
 
+		// Side effects run in a task: its result is recorded in the checkpoint store,
+		// so resuming the step after a crash does not send the email twice
+		emailID, err := graph.Task(ctx, "send-confirmation", func(ctx context.Context) (string, error) {
+			fmt.Println("Sending confirmation email...")
+			return "email-42", nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
 		// Check if we should crash
 		if os.Getenv("CRASH") == "true" {
 			fmt.Println("!!! CRASHING AT STEP 2 !!!")
@@ -127,7 +137,7 @@ func main() {
 			os.Exit(1)
 		}
 
-		return map[string]interface{}{"steps": []string{"Step 2 Completed"}}, nil
+		return map[string]interface{}{"steps": []string{"Step 2 Completed (" + emailID + ")"}}, nil
 	})
 
 	// Step 3
@@ -151,51 +161,25 @@ func main() {
 	ctx := context.Background()
 	checkpoints, _ := store.List(ctx, threadID)
 
+	// Task checkpoints record side effects, not the graph state
+	var latest *graph.Checkpoint
+	for _, cp := range checkpoints {
+		if cp.Metadata["event"] != "task" {
+			latest = cp
+		}
+	}
+
 	var config *graph.Config
-	if len(checkpoints) > 0 {
-		latest := checkpoints[len(checkpoints)-1]
+	if latest != nil {
 		fmt.Printf("Found existing checkpoint: %s (Node: %s)\n", latest.ID, latest.NodeName)
 		fmt.Println("Resuming execution...")
 
-		// In a real Durable Execution setup, we would want to resume FROM the next node.
-		// Currently ResumeFromCheckpoint just loads state.
-		// We need to tell the runner to start from the *next* node of the checkpoint.
-		// Or, if the checkpoint was saved *after* a node completed, we continue from its edges.
-
-		// For this demo, we'll use the state to determine where we are,
-		// but ideally the framework handles this.
-		// Since LangGraphGo's ResumeFromCheckpoint is basic, we might need to manually inspect.
-
-		// However, let's try to use the ResumeFrom config if we implemented it.
-		// graph.Config has ResumeFrom []string.
-
-		// If latest checkpoint is from "step_1", we want to resume.
-		// But wait, if we crashed INSIDE step_2, step_2 didn't finish, so no checkpoint for step_2.
-		// So the latest checkpoint is "step_1".
-		// So we should resume from the state of "step_1".
-		// The graph execution logic should see that "step_1" is done and move to "step_2".
-
-		// To achieve this "continue" behavior, we pass the checkpoint ID.
-		// The runner *should* know that if we provide a checkpoint, we are continuing.
-		// But currently Invoke() starts from EntryPoint unless ResumeFrom is set.
-
-		// Let's manually set ResumeFrom based on the checkpoint's node.
-		// If checkpoint is at "step_1", we want to execute the edges out of "step_1".
-		// Which means the next node is "step_2".
-
-		// Actually, if we load the state of "step_1", and we invoke the graph,
-		// we want the graph to figure out "what's next".
-		// But the graph is stateless.
-
-		// Let's use a trick: We pass the checkpoint state as initial state,
-		// and we set ResumeFrom to the *next* nodes.
-		// But we need to know the next nodes.
-
-		// For this simple linear graph:
+		// Step checkpoints are saved after a node completes, so resume from the
+		// next node of this simple linear graph, using the checkpoint state as input
 		var nextNode string
-		if latest.NodeName == "step_1" {
+		if latest.NodeName == "step:[step_1]" {
 			nextNode = "step_2"
-		} else if latest.NodeName == "step_2" {
+		} else if latest.NodeName == "step:[step_2]" {
 			nextNode = "step_3"
 		} else {
 			// Finished or unknown
@@ -211,12 +195,6 @@ func main() {
 			ResumeFrom: []string{nextNode},
 		}
 
-		// We use the checkpoint state as input
-		// runnable.Invoke(ctx, latest.State, config)
-		// Wait, Invoke expects input, not full state?
-		// If we provide ResumeFrom, the runner uses that as start nodes.
-		// And it uses the provided state.
-
 		fmt.Printf("Continuing from %s...\n", nextNode)
 		res, err := runnable.InvokeWithConfig(ctx, latest.State, config)
 		if err != nil {
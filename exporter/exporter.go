@@ -0,0 +1,532 @@
+// Package exporter sends graph runs to LLM observability platforms.
+//
+// An Exporter is passed in graph.Config.Callbacks. It turns the chain, LLM, tool and
+// retriever callbacks of a run into a run tree, using their runID/parentRunID, and
+// ships finished runs in batches to a LangSmith or Langfuse compatible endpoint:
+//
+//	exp := exporter.New("https://api.smith.langchain.com",
+//		exporter.WithAPIKey(os.Getenv("LANGSMITH_API_KEY")),
+//		exporter.WithProject("agents"))
+//	defer exp.Shutdown(context.Background())
+//
+// Batches are flushed in the background every flush interval, or as soon as the batch
+// size is reached, and failed requests are retried with exponential backoff.
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/smallnest/langgraphgo/graph"
+	"github.com/smallnest/langgraphgo/log"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Format selects the wire format of the exported runs
+type Format string
+
+const (
+	// FormatLangSmith posts run trees to the LangSmith /runs/batch endpoint
+	FormatLangSmith Format = "langsmith"
+	// FormatLangfuse posts traces, spans and generations to the Langfuse ingestion API
+	FormatLangfuse Format = "langfuse"
+)
+
+// Option configures an Exporter
+type Option func(*Exporter)
+
+// WithFormat sets the wire format, default FormatLangSmith
+func WithFormat(format Format) Option {
+	return func(e *Exporter) {
+		e.format = format
+	}
+}
+
+// WithAPIKey sets the LangSmith API key sent in the x-api-key header
+func WithAPIKey(apiKey string) Option {
+	return func(e *Exporter) {
+		e.apiKey = apiKey
+	}
+}
+
+// WithBasicAuth sets the Langfuse public and secret keys
+func WithBasicAuth(publicKey, secretKey string) Option {
+	return func(e *Exporter) {
+		e.publicKey = publicKey
+		e.secretKey = secretKey
+	}
+}
+
+// WithProject sets the LangSmith project (session) the runs belong to
+func WithProject(project string) Option {
+	return func(e *Exporter) {
+		e.project = project
+	}
+}
+
+// WithBatchSize sets the number of finished runs that triggers a flush, default 20
+func WithBatchSize(size int) Option {
+	return func(e *Exporter) {
+		e.batchSize = size
+	}
+}
+
+// WithFlushInterval sets how often queued runs are flushed in the background, default 5s
+func WithFlushInterval(interval time.Duration) Option {
+	return func(e *Exporter) {
+		e.flushInterval = interval
+	}
+}
+
+// WithRetry sets the number of retries of a failed batch and the initial backoff
+// between them, which doubles after each attempt. Defaults to 3 retries and 500ms.
+func WithRetry(maxRetries int, backoff time.Duration) Option {
+	return func(e *Exporter) {
+		e.maxRetries = maxRetries
+		e.backoff = backoff
+	}
+}
+
+// WithMaxQueueSize caps the number of finished runs waiting to be exported, default
+// 10000. When the endpoint is unavailable for long, the oldest runs are dropped first.
+func WithMaxQueueSize(size int) Option {
+	return func(e *Exporter) {
+		e.maxQueueSize = size
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send batches
+func WithHTTPClient(client *http.Client) Option {
+	return func(e *Exporter) {
+		e.client = client
+	}
+}
+
+// run is a callback run of the run tree
+type run struct {
+	ID          string
+	ParentID    string
+	TraceID     string
+	DottedOrder string
+	Name        string
+	RunType     string
+	Start       time.Time
+	End         time.Time
+	Inputs      map[string]interface{}
+	Outputs     map[string]interface{}
+	Error       string
+	Tags        []string
+	Metadata    map[string]interface{}
+	Model       string
+	Usage       graph.TokenUsage
+}
+
+// Exporter is a callback handler exporting runs to an observability platform
+type Exporter struct {
+	graph.NoOpCallbackHandler
+
+	endpoint      string
+	format        Format
+	apiKey        string
+	publicKey     string
+	secretKey     string
+	project       string
+	batchSize     int
+	maxQueueSize  int
+	flushInterval time.Duration
+	maxRetries    int
+	backoff       time.Duration
+	client        *http.Client
+
+	mu      sync.Mutex
+	active  map[string]*run
+	queue   []*run
+	flushMu sync.Mutex
+
+	kick     chan struct{}
+	done     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// New creates an exporter sending runs to the platform at endpoint, e.g.
+// https://api.smith.langchain.com or https://cloud.langfuse.com, and starts its
+// background flushing
+func New(endpoint string, opts ...Option) *Exporter {
+	e := &Exporter{
+		endpoint:      endpoint,
+		format:        FormatLangSmith,
+		batchSize:     20,
+		maxQueueSize:  10000,
+		flushInterval: 5 * time.Second,
+		maxRetries:    3,
+		backoff:       500 * time.Millisecond,
+		client:        &http.Client{Timeout: 30 * time.Second},
+		active:        make(map[string]*run),
+		kick:          make(chan struct{}, 1),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	go e.loop()
+	return e
+}
+
+// loop flushes queued runs periodically and when a batch is full
+func (e *Exporter) loop() {
+	defer close(e.stopped)
+
+	ticker := time.NewTicker(e.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-ticker.C:
+		case <-e.kick:
+		}
+		if err := e.Flush(context.Background()); err != nil {
+			log.WarnContext(context.Background(), "failed to export runs", "endpoint", e.endpoint, "error", err)
+		}
+	}
+}
+
+// Flush sends all finished runs queued so far. Batches failing with a retryable
+// error stay queued for the next flush, other failed batches are dropped.
+func (e *Exporter) Flush(ctx context.Context) error {
+	e.flushMu.Lock()
+	defer e.flushMu.Unlock()
+
+	var firstErr error
+	for {
+		e.mu.Lock()
+		n := len(e.queue)
+		if n > e.batchSize && e.batchSize > 0 {
+			n = e.batchSize
+		}
+		batch := e.queue[:n:n]
+		e.queue = e.queue[n:]
+		e.mu.Unlock()
+
+		if len(batch) == 0 {
+			return firstErr
+		}
+		err := e.send(ctx, batch)
+		if err == nil {
+			continue
+		}
+		if isRetryable(err) {
+			// Put the batch back so a later flush can deliver it
+			e.mu.Lock()
+			e.queue = append(batch, e.queue...)
+			e.trimQueue(ctx)
+			e.mu.Unlock()
+			return err
+		}
+		log.WarnContext(ctx, "dropping runs that cannot be exported", "endpoint", e.endpoint, "runs", len(batch), "error", err)
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+}
+
+// trimQueue drops the oldest queued runs beyond the maximum queue size. The caller
+// holds e.mu.
+func (e *Exporter) trimQueue(ctx context.Context) {
+	if e.maxQueueSize <= 0 || len(e.queue) <= e.maxQueueSize {
+		return
+	}
+	dropped := len(e.queue) - e.maxQueueSize
+	e.queue = append([]*run(nil), e.queue[dropped:]...)
+	log.WarnContext(ctx, "export queue is full, dropping oldest runs", "endpoint", e.endpoint, "runs", dropped)
+}
+
+// Shutdown stops background flushing and sends the runs still queued
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	e.stopOnce.Do(func() {
+		close(e.done)
+	})
+	select {
+	case <-e.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return e.Flush(ctx)
+}
+
+// retryableError marks failures worth retrying
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// isRetryable reports whether a failed batch may be delivered by a later flush
+func isRetryable(err error) bool {
+	var retryable *retryableError
+	return errors.As(err, &retryable) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// send posts a batch, retrying network errors, 429 and 5xx responses
+func (e *Exporter) send(ctx context.Context, batch []*run) error {
+	url, body, err := e.encode(batch)
+	if err != nil {
+		return err
+	}
+
+	backoff := e.backoff
+	for attempt := 0; ; attempt++ {
+		err = e.post(ctx, url, body)
+		var retryable *retryableError
+		if err == nil || !errors.As(err, &retryable) || attempt >= e.maxRetries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post sends one request
+func (e *Exporter) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("x-api-key", e.apiKey)
+	}
+	if e.publicKey != "" || e.secretKey != "" {
+		req.SetBasicAuth(e.publicKey, e.secretKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return &retryableError{fmt.Errorf("failed to send runs: %w", err)}
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("export endpoint returned %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return &retryableError{err}
+	}
+	return err
+}
+
+// encode renders a batch in the configured format
+func (e *Exporter) encode(batch []*run) (string, []byte, error) {
+	var url string
+	var payload interface{}
+	switch e.format {
+	case FormatLangSmith:
+		url, payload = e.endpoint+"/runs/batch", e.langSmithBatch(batch)
+	case FormatLangfuse:
+		url, payload = e.endpoint+"/api/public/ingestion", langfuseBatch(batch)
+	default:
+		return "", nil, fmt.Errorf("unsupported export format: %s", e.format)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal runs: %w", err)
+	}
+	return url, body, nil
+}
+
+// snapshot copies values reported by a callback through JSON, so the graph can keep
+// changing its state while the run waits to be exported
+func snapshot(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return map[string]interface{}{"error": fmt.Sprintf("failed to marshal values: %v", err)}
+	}
+	var copied map[string]interface{}
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return map[string]interface{}{"error": fmt.Sprintf("failed to unmarshal values: %v", err)}
+	}
+	return copied
+}
+
+// startRun records the start of a run, linking it to its parent
+func (e *Exporter) startRun(runID string, parentRunID *string, name, runType string, inputs map[string]interface{}, tags []string, metadata map[string]interface{}) *run {
+	r := &run{
+		ID:       runID,
+		TraceID:  runID,
+		Name:     name,
+		RunType:  runType,
+		Start:    time.Now().UTC(),
+		Inputs:   snapshot(inputs),
+		Tags:     append([]string(nil), tags...),
+		Metadata: snapshot(metadata),
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r.DottedOrder = dottedOrderPart(r.Start, runID)
+	if parentRunID != nil {
+		r.ParentID = *parentRunID
+		if parent, ok := e.active[*parentRunID]; ok {
+			r.TraceID = parent.TraceID
+			r.DottedOrder = parent.DottedOrder + "." + r.DottedOrder
+		}
+	}
+	e.active[runID] = r
+	return r
+}
+
+// endRun finishes a run with a snapshot of its outputs and queues it for export
+func (e *Exporter) endRun(runID string, outputs map[string]interface{}, finish func(r *run)) {
+	outputs = snapshot(outputs)
+
+	e.mu.Lock()
+	r, ok := e.active[runID]
+	if !ok {
+		e.mu.Unlock()
+		return
+	}
+	delete(e.active, runID)
+	r.End = time.Now().UTC()
+	r.Outputs = outputs
+	if finish != nil {
+		finish(r)
+	}
+	e.queue = append(e.queue, r)
+	e.trimQueue(context.Background())
+	full := e.batchSize > 0 && len(e.queue) >= e.batchSize
+	e.mu.Unlock()
+
+	if full {
+		select {
+		case e.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// dottedOrderPart is the LangSmith dotted order segment of a run
+func dottedOrderPart(start time.Time, runID string) string {
+	return fmt.Sprintf("%s%06dZ%s", start.Format("20060102T150405"), start.Nanosecond()/1000, runID)
+}
+
+func serializedName(serialized map[string]interface{}, fallback string) string {
+	if name, ok := serialized["name"].(string); ok && name != "" {
+		return name
+	}
+	return fallback
+}
+
+// OnChainStart implements graph.CallbackHandler
+func (e *Exporter) OnChainStart(ctx context.Context, serialized map[string]interface{}, inputs map[string]interface{}, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	e.startRun(runID, parentRunID, serializedName(serialized, "chain"), "chain", inputs, tags, metadata)
+}
+
+// OnChainEnd implements graph.CallbackHandler
+func (e *Exporter) OnChainEnd(ctx context.Context, outputs map[string]interface{}, runID string) {
+	e.endRun(runID, outputs, nil)
+}
+
+// OnChainError implements graph.CallbackHandler
+func (e *Exporter) OnChainError(ctx context.Context, err error, runID string) {
+	e.endRun(runID, nil, func(r *run) {
+		r.Error = err.Error()
+	})
+}
+
+// OnLLMStart implements graph.CallbackHandler
+func (e *Exporter) OnLLMStart(ctx context.Context, serialized map[string]interface{}, prompts []string, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	r := e.startRun(runID, parentRunID, serializedName(serialized, "llm"), "llm", map[string]interface{}{"prompts": prompts}, tags, metadata)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if model, ok := serialized["model"].(string); ok {
+		r.Model = model
+	}
+}
+
+// OnLLMEnd implements graph.CallbackHandler
+func (e *Exporter) OnLLMEnd(ctx context.Context, response interface{}, runID string) {
+	usage := graph.TokenUsageFromResponse(response)
+	outputs := map[string]interface{}{"response": response}
+	if resp, ok := response.(*llms.ContentResponse); ok && resp != nil {
+		var generations []string
+		for _, choice := range resp.Choices {
+			generations = append(generations, choice.Content)
+		}
+		outputs = map[string]interface{}{"generations": generations}
+	}
+
+	e.endRun(runID, outputs, func(r *run) {
+		r.Usage = usage
+	})
+}
+
+// OnLLMError implements graph.CallbackHandler
+func (e *Exporter) OnLLMError(ctx context.Context, err error, runID string) {
+	e.endRun(runID, nil, func(r *run) {
+		r.Error = err.Error()
+	})
+}
+
+// OnToolStart implements graph.CallbackHandler
+func (e *Exporter) OnToolStart(ctx context.Context, serialized map[string]interface{}, inputStr string, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	e.startRun(runID, parentRunID, serializedName(serialized, "tool"), "tool", map[string]interface{}{"input": inputStr}, tags, metadata)
+}
+
+// OnToolEnd implements graph.CallbackHandler
+func (e *Exporter) OnToolEnd(ctx context.Context, output string, runID string) {
+	e.endRun(runID, map[string]interface{}{"output": output}, nil)
+}
+
+// OnToolError implements graph.CallbackHandler
+func (e *Exporter) OnToolError(ctx context.Context, err error, runID string) {
+	e.endRun(runID, nil, func(r *run) {
+		r.Error = err.Error()
+	})
+}
+
+// OnRetrieverStart implements graph.CallbackHandler
+func (e *Exporter) OnRetrieverStart(ctx context.Context, serialized map[string]interface{}, query string, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	e.startRun(runID, parentRunID, serializedName(serialized, "retriever"), "retriever", map[string]interface{}{"query": query}, tags, metadata)
+}
+
+// OnRetrieverEnd implements graph.CallbackHandler
+func (e *Exporter) OnRetrieverEnd(ctx context.Context, documents []interface{}, runID string) {
+	e.endRun(runID, map[string]interface{}{"documents": documents}, nil)
+}
+
+// OnRetrieverError implements graph.CallbackHandler
+func (e *Exporter) OnRetrieverError(ctx context.Context, err error, runID string) {
+	e.endRun(runID, nil, func(r *run) {
+		r.Error = err.Error()
+	})
+}
+
+// OnGraphStep implements graph.GraphCallbackHandler
+func (e *Exporter) OnGraphStep(ctx context.Context, stepNode string, state interface{}) {}
+
+var _ graph.GraphCallbackHandler = (*Exporter)(nil)
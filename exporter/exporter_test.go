@@ -0,0 +1,294 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// stubServer records the JSON bodies posted to it, failing the first failures requests
+type stubServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	failures int
+	requests []*http.Request
+	bodies   []map[string]interface{}
+}
+
+func newStubServer(t *testing.T, failures int) *stubServer {
+	s := &stubServer{failures: failures}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.requests = append(s.requests, r)
+		if s.failures > 0 {
+			s.failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		s.bodies = append(s.bodies, body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *stubServer) snapshot() ([]*http.Request, []map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*http.Request(nil), s.requests...), append([]map[string]interface{}(nil), s.bodies...)
+}
+
+// emitRun reports a graph run with a node calling an LLM and a failing tool
+func emitRun(ctx context.Context, e *Exporter) {
+	root := "run-1"
+	node := "node-1"
+	e.OnChainStart(ctx, map[string]interface{}{"name": "graph"}, map[string]interface{}{"input": "hi"}, root, nil, []string{"test"}, map[string]interface{}{"thread_id": "t1"})
+	e.OnChainStart(ctx, map[string]interface{}{"name": "agent"}, nil, node, &root, nil, nil)
+	e.OnLLMStart(ctx, map[string]interface{}{"name": "fake", "model": "gpt-4o"}, []string{"hi"}, "llm-1", &node, nil, nil)
+	e.OnLLMEnd(ctx, &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+		Content:        "hello",
+		GenerationInfo: map[string]interface{}{"PromptTokens": 3, "CompletionTokens": 4},
+	}}}, "llm-1")
+	e.OnToolStart(ctx, map[string]interface{}{"name": "search"}, "q", "tool-1", &node, nil, nil)
+	e.OnToolError(ctx, errors.New("boom"), "tool-1")
+	e.OnChainEnd(ctx, map[string]interface{}{"output": "hello"}, node)
+	e.OnChainEnd(ctx, map[string]interface{}{"output": "hello"}, root)
+}
+
+func TestLangSmithExport(t *testing.T) {
+	server := newStubServer(t, 0)
+	e := New(server.URL, WithAPIKey("secret"), WithProject("agents"), WithFlushInterval(time.Hour))
+	ctx := context.Background()
+
+	emitRun(ctx, e)
+	assert.NoError(t, e.Shutdown(ctx))
+
+	requests, bodies := server.snapshot()
+	assert.Len(t, requests, 1)
+	assert.Equal(t, "/runs/batch", requests[0].URL.Path)
+	assert.Equal(t, "secret", requests[0].Header.Get("x-api-key"))
+
+	runs := bodies[0]["post"].([]interface{})
+	assert.Len(t, runs, 4)
+	byID := make(map[string]map[string]interface{})
+	for _, r := range runs {
+		run := r.(map[string]interface{})
+		byID[run["id"].(string)] = run
+		assert.Equal(t, "run-1", run["trace_id"])
+		assert.Equal(t, "agents", run["session_name"])
+	}
+
+	root := byID["run-1"]
+	assert.Nil(t, root["parent_run_id"])
+	assert.Equal(t, "chain", root["run_type"])
+	assert.Equal(t, []interface{}{"test"}, root["tags"])
+	assert.Equal(t, map[string]interface{}{"thread_id": "t1"}, root["extra"].(map[string]interface{})["metadata"])
+
+	llm := byID["llm-1"]
+	assert.Equal(t, "node-1", llm["parent_run_id"])
+	assert.Equal(t, "llm", llm["run_type"])
+	assert.Equal(t, "fake", llm["name"])
+	order := strings.Split(llm["dotted_order"].(string), ".")
+	assert.Len(t, order, 3)
+	assert.True(t, strings.HasSuffix(order[0], "run-1"))
+	assert.True(t, strings.HasSuffix(order[1], "node-1"))
+	assert.True(t, strings.HasSuffix(order[2], "llm-1"))
+	usage := llm["outputs"].(map[string]interface{})["llm_output"].(map[string]interface{})["token_usage"].(map[string]interface{})
+	assert.Equal(t, float64(7), usage["total_tokens"])
+
+	tool := byID["tool-1"]
+	assert.Equal(t, "tool", tool["run_type"])
+	assert.Equal(t, "boom", tool["error"])
+}
+
+func TestLangfuseExport(t *testing.T) {
+	server := newStubServer(t, 0)
+	e := New(server.URL, WithFormat(FormatLangfuse), WithBasicAuth("pk", "sk"), WithFlushInterval(time.Hour))
+	ctx := context.Background()
+
+	emitRun(ctx, e)
+	assert.NoError(t, e.Flush(ctx))
+
+	requests, bodies := server.snapshot()
+	assert.Len(t, requests, 1)
+	assert.Equal(t, "/api/public/ingestion", requests[0].URL.Path)
+	user, pass, ok := requests[0].BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "pk", user)
+	assert.Equal(t, "sk", pass)
+
+	var types []string
+	bodiesByID := make(map[string]map[string]interface{})
+	for _, ev := range bodies[0]["batch"].([]interface{}) {
+		event := ev.(map[string]interface{})
+		types = append(types, event["type"].(string))
+		body := event["body"].(map[string]interface{})
+		if event["type"] != "trace-create" {
+			bodiesByID[body["id"].(string)] = body
+		}
+	}
+	assert.ElementsMatch(t, []string{"generation-create", "span-create", "span-create", "trace-create", "span-create"}, types)
+
+	generation := bodiesByID["llm-1"]
+	assert.Equal(t, "run-1", generation["traceId"])
+	assert.Equal(t, "node-1", generation["parentObservationId"])
+	assert.Equal(t, "gpt-4o", generation["model"])
+	assert.Equal(t, float64(3), generation["usage"].(map[string]interface{})["input"])
+
+	tool := bodiesByID["tool-1"]
+	assert.Equal(t, "ERROR", tool["level"])
+	assert.Equal(t, "boom", tool["statusMessage"])
+	assert.NoError(t, e.Shutdown(ctx))
+}
+
+func TestExportRetries(t *testing.T) {
+	server := newStubServer(t, 2)
+	e := New(server.URL, WithRetry(3, time.Millisecond), WithFlushInterval(time.Hour))
+	ctx := context.Background()
+
+	emitRun(ctx, e)
+	assert.NoError(t, e.Flush(ctx))
+	requests, bodies := server.snapshot()
+	assert.Len(t, requests, 3)
+	assert.Len(t, bodies, 1)
+
+	// Runs stay queued when all retries fail and are delivered by the next flush
+	server.mu.Lock()
+	server.failures = 5
+	server.mu.Unlock()
+	e.maxRetries = 1
+	emitRun(ctx, e)
+	assert.Error(t, e.Flush(ctx))
+	server.mu.Lock()
+	server.failures = 0
+	server.mu.Unlock()
+	assert.NoError(t, e.Shutdown(ctx))
+	_, bodies = server.snapshot()
+	assert.Len(t, bodies, 2)
+	assert.Len(t, bodies[1]["post"].([]interface{}), 4)
+}
+
+func TestExportBackgroundFlush(t *testing.T) {
+	server := newStubServer(t, 0)
+	e := New(server.URL, WithBatchSize(2), WithFlushInterval(time.Hour))
+	defer e.Shutdown(context.Background())
+
+	// A full batch is flushed without waiting for the interval
+	emitRun(context.Background(), e)
+	assert.Eventually(t, func() bool {
+		_, bodies := server.snapshot()
+		total := 0
+		for _, body := range bodies {
+			total += len(body["post"].([]interface{}))
+		}
+		return total == 4
+	}, time.Second, 10*time.Millisecond)
+
+	_, bodies := server.snapshot()
+	for _, body := range bodies {
+		assert.LessOrEqual(t, len(body["post"].([]interface{})), 2)
+	}
+}
+
+func TestExportNonRetryableError(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "invalid api key", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	e := New(server.URL, WithRetry(3, time.Millisecond), WithFlushInterval(time.Hour))
+	emitRun(context.Background(), e)
+	err := e.Flush(context.Background())
+	assert.ErrorContains(t, err, "401")
+	assert.ErrorContains(t, err, "invalid api key")
+	assert.Equal(t, 1, calls)
+
+	// The rejected batch is dropped instead of blocking later runs
+	assert.Empty(t, e.queue)
+	assert.NoError(t, e.Flush(context.Background()))
+	assert.Equal(t, 1, calls)
+}
+
+func TestExportUnencodableValues(t *testing.T) {
+	server := newStubServer(t, 0)
+	e := New(server.URL, WithBatchSize(1), WithFlushInterval(time.Hour))
+	ctx := context.Background()
+
+	// A state holding NaN cannot be encoded as JSON, the run is exported without it
+	e.OnChainStart(ctx, map[string]interface{}{"name": "graph"}, map[string]interface{}{"score": math.NaN()}, "bad", nil, nil, nil)
+	e.OnChainEnd(ctx, nil, "bad")
+	emitRun(ctx, e)
+
+	assert.NoError(t, e.Shutdown(ctx))
+	_, bodies := server.snapshot()
+	assert.Len(t, bodies, 5)
+	var inputs map[string]interface{}
+	for _, body := range bodies {
+		run := body["post"].([]interface{})[0].(map[string]interface{})
+		if run["id"] == "bad" {
+			inputs = run["inputs"].(map[string]interface{})
+		}
+	}
+	assert.Contains(t, inputs["error"], "failed to marshal values")
+}
+
+func TestExportQueueLimit(t *testing.T) {
+	server := newStubServer(t, 100)
+	e := New(server.URL, WithRetry(0, time.Millisecond), WithMaxQueueSize(3), WithBatchSize(0), WithFlushInterval(time.Hour))
+	ctx := context.Background()
+
+	// The queue keeps the newest runs while the endpoint is down
+	emitRun(ctx, e)
+	emitRun(ctx, e)
+	assert.Error(t, e.Flush(ctx))
+	e.mu.Lock()
+	assert.Len(t, e.queue, 3)
+	assert.Equal(t, "run-1", e.queue[2].ID)
+	assert.Equal(t, "node-1", e.queue[1].ID)
+	e.mu.Unlock()
+
+	server.mu.Lock()
+	server.failures = 0
+	server.mu.Unlock()
+	assert.NoError(t, e.Shutdown(ctx))
+}
+
+func TestExportSnapshotsState(t *testing.T) {
+	server := newStubServer(t, 0)
+	e := New(server.URL, WithBatchSize(1), WithFlushInterval(time.Hour))
+	ctx := context.Background()
+
+	state := map[string]interface{}{"count": 1}
+	e.OnChainStart(ctx, map[string]interface{}{"name": "graph"}, state, "run-1", nil, nil, nil)
+	state["count"] = 2
+	e.OnChainEnd(ctx, state, "run-1")
+
+	// The graph keeps changing its state while the run is exported in the background
+	for i := 3; i < 100; i++ {
+		state["count"] = i
+	}
+	assert.NoError(t, e.Shutdown(ctx))
+
+	_, bodies := server.snapshot()
+	assert.Len(t, bodies, 1)
+	run := bodies[0]["post"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"count": float64(1)}, run["inputs"])
+	assert.Equal(t, map[string]interface{}{"count": float64(2)}, run["outputs"])
+}
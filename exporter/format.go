@@ -0,0 +1,144 @@
+package exporter
+
+import (
+	"time"
+)
+
+// langSmithRun is a run of the LangSmith run tree
+type langSmithRun struct {
+	ID          string                 `json:"id"`
+	TraceID     string                 `json:"trace_id"`
+	DottedOrder string                 `json:"dotted_order"`
+	ParentRunID string                 `json:"parent_run_id,omitempty"`
+	Name        string                 `json:"name"`
+	RunType     string                 `json:"run_type"`
+	StartTime   string                 `json:"start_time"`
+	EndTime     string                 `json:"end_time"`
+	Inputs      map[string]interface{} `json:"inputs"`
+	Outputs     map[string]interface{} `json:"outputs,omitempty"`
+	Error       string                 `json:"error,omitempty"`
+	Tags        []string               `json:"tags,omitempty"`
+	Extra       map[string]interface{} `json:"extra,omitempty"`
+	SessionName string                 `json:"session_name,omitempty"`
+}
+
+// langSmithBatch renders runs as a LangSmith /runs/batch request
+func (e *Exporter) langSmithBatch(batch []*run) map[string]interface{} {
+	runs := make([]langSmithRun, 0, len(batch))
+	for _, r := range batch {
+		lr := langSmithRun{
+			ID:          r.ID,
+			TraceID:     r.TraceID,
+			DottedOrder: r.DottedOrder,
+			ParentRunID: r.ParentID,
+			Name:        r.Name,
+			RunType:     r.RunType,
+			StartTime:   r.Start.Format(time.RFC3339Nano),
+			EndTime:     r.End.Format(time.RFC3339Nano),
+			Inputs:      r.Inputs,
+			Outputs:     r.Outputs,
+			Error:       r.Error,
+			Tags:        r.Tags,
+			SessionName: e.project,
+		}
+		if lr.Inputs == nil {
+			lr.Inputs = map[string]interface{}{}
+		}
+
+		extra := map[string]interface{}{}
+		if len(r.Metadata) > 0 {
+			extra["metadata"] = r.Metadata
+		}
+		if r.Model != "" {
+			extra["invocation_params"] = map[string]interface{}{"model": r.Model}
+		}
+		if r.Usage.TotalTokens > 0 {
+			if lr.Outputs == nil {
+				lr.Outputs = map[string]interface{}{}
+			}
+			lr.Outputs["llm_output"] = map[string]interface{}{
+				"token_usage": map[string]interface{}{
+					"prompt_tokens":     r.Usage.InputTokens,
+					"completion_tokens": r.Usage.OutputTokens,
+					"total_tokens":      r.Usage.TotalTokens,
+				},
+			}
+		}
+		if len(extra) > 0 {
+			lr.Extra = extra
+		}
+		runs = append(runs, lr)
+	}
+	return map[string]interface{}{"post": runs}
+}
+
+// langfuseEvent is an event of the Langfuse ingestion API
+type langfuseEvent struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	Timestamp string                 `json:"timestamp"`
+	Body      map[string]interface{} `json:"body"`
+}
+
+// langfuseBatch renders runs as a Langfuse ingestion request. Root runs become
+// traces, LLM runs generations and every other run a span of its trace.
+func langfuseBatch(batch []*run) map[string]interface{} {
+	events := make([]langfuseEvent, 0, len(batch))
+	for _, r := range batch {
+		timestamp := r.End.Format(time.RFC3339Nano)
+		if r.ParentID == "" {
+			body := map[string]interface{}{
+				"id":        r.ID,
+				"name":      r.Name,
+				"timestamp": r.Start.Format(time.RFC3339Nano),
+				"input":     r.Inputs,
+				"output":    r.Outputs,
+			}
+			if len(r.Tags) > 0 {
+				body["tags"] = r.Tags
+			}
+			if len(r.Metadata) > 0 {
+				body["metadata"] = r.Metadata
+			}
+			events = append(events, langfuseEvent{ID: r.ID + "-trace", Type: "trace-create", Timestamp: timestamp, Body: body})
+		}
+
+		body := map[string]interface{}{
+			"id":        r.ID,
+			"traceId":   r.TraceID,
+			"name":      r.Name,
+			"startTime": r.Start.Format(time.RFC3339Nano),
+			"endTime":   r.End.Format(time.RFC3339Nano),
+			"input":     r.Inputs,
+			"output":    r.Outputs,
+		}
+		if r.ParentID != "" {
+			body["parentObservationId"] = r.ParentID
+		}
+		if len(r.Metadata) > 0 {
+			body["metadata"] = r.Metadata
+		}
+		if r.Error != "" {
+			body["level"] = "ERROR"
+			body["statusMessage"] = r.Error
+		}
+
+		eventType := "span-create"
+		if r.RunType == "llm" {
+			eventType = "generation-create"
+			if r.Model != "" {
+				body["model"] = r.Model
+			}
+			if r.Usage.TotalTokens > 0 {
+				body["usage"] = map[string]interface{}{
+					"input":  r.Usage.InputTokens,
+					"output": r.Usage.OutputTokens,
+					"total":  r.Usage.TotalTokens,
+					"unit":   "TOKENS",
+				}
+			}
+		}
+		events = append(events, langfuseEvent{ID: r.ID + "-" + eventType, Type: eventType, Timestamp: timestamp, Body: body})
+	}
+	return map[string]interface{}{"batch": events}
+}
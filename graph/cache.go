@@ -0,0 +1,334 @@
+package graph
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/smallnest/langgraphgo/log"
+)
+
+// CacheBypassKey is the Config.Configurable key that disables node result caching
+// for a single invocation when set to true.
+const CacheBypassKey = "cache_bypass"
+
+// Cache defines the interface for node result cache backends
+type Cache interface {
+	// Get returns the cached value for key and whether it was found
+	Get(ctx context.Context, key string) (interface{}, bool, error)
+
+	// Set stores a value for key. A ttl of zero means the entry never expires.
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+
+	// Delete removes the entry for key
+	Delete(ctx context.Context, key string) error
+
+	// Clear removes all entries
+	Clear(ctx context.Context) error
+}
+
+// CachePolicy configures result caching for a single node
+type CachePolicy struct {
+	// KeyFunc derives the cache key from the node input state.
+	// If nil, DefaultCacheKey is used.
+	KeyFunc func(state interface{}) (string, error)
+
+	// TTL is how long a cached result stays valid. Zero means no expiration.
+	TTL time.Duration
+
+	// Decode restores a result read back from a JSONCache, e.g. DecodeJSON of the
+	// node's result type. Without it, a JSONCache only stores results that are plain
+	// JSON values (maps, slices, strings, float64, bool), which decode unchanged.
+	Decode func(data []byte) (interface{}, error)
+}
+
+// JSONCache is implemented by caches storing values as JSON, e.g. the Redis and
+// SQLite caches. Their Get returns the stored json.RawMessage, which the graph
+// decodes with the CachePolicy of the node.
+type JSONCache interface {
+	Cache
+	// StoresJSON marks the cache as storing values as JSON
+	StoresJSON()
+}
+
+// DecodeJSON is a CachePolicy.Decode restoring results of type T
+func DecodeJSON[T any](data []byte) (interface{}, error) {
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// NodeOption configures optional node behavior when adding a node to a graph
+type NodeOption func(*Node)
+
+// WithCachePolicy enables result caching for the node using the given policy
+func WithCachePolicy(policy CachePolicy) NodeOption {
+	return func(n *Node) {
+		n.CachePolicy = &policy
+	}
+}
+
+// CacheCallbackHandler can be implemented by callback handlers that want to be
+// notified when a node result is served from the cache instead of being executed
+type CacheCallbackHandler interface {
+	OnCacheHit(ctx context.Context, nodeName string, key string)
+}
+
+// DefaultCacheKey hashes the JSON encoding of the state
+func DefaultCacheKey(state interface{}) (string, error) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal state for cache key: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// cacheKey builds the namespaced cache key for a node and state
+func (p *CachePolicy) cacheKey(nodeName string, state interface{}) (string, error) {
+	keyFunc := p.KeyFunc
+	if keyFunc == nil {
+		keyFunc = DefaultCacheKey
+	}
+	key, err := keyFunc(state)
+	if err != nil {
+		return "", err
+	}
+	return nodeName + ":" + key, nil
+}
+
+// isCacheBypassed reports whether the config in ctx asks to skip the cache
+func isCacheBypassed(ctx context.Context) bool {
+	config := GetConfig(ctx)
+	if config == nil || config.Configurable == nil {
+		return false
+	}
+	bypass, _ := config.Configurable[CacheBypassKey].(bool)
+	return bypass
+}
+
+// executeWithCache runs fn for the node, serving and storing results through cache
+// when the node has a CachePolicy. It reports whether the result was a cache hit.
+func executeWithCache(
+	ctx context.Context,
+	cache Cache,
+	node Node,
+	state interface{},
+	fn func(ctx context.Context, state interface{}) (interface{}, error),
+) (interface{}, bool, error) {
+	if cache == nil || node.CachePolicy == nil || isCacheBypassed(ctx) {
+		res, err := fn(ctx, state)
+		return res, false, err
+	}
+
+	key, err := node.CachePolicy.cacheKey(node.Name, state)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to compute cache key: %w", err)
+	}
+
+	// The cache only saves work, so the node runs uncached when the cache fails
+	value, found, err := cache.Get(ctx, key)
+	if err != nil {
+		log.WarnContext(ctx, "cache lookup failed, running node uncached", "key", key, "error", err)
+	} else if found {
+		value, err := node.CachePolicy.decode(value)
+		if err == nil {
+			notifyCacheHit(ctx, node.Name, key)
+			return value, true, nil
+		}
+		log.WarnContext(ctx, "cache entry cannot be decoded, running node uncached", "key", key, "error", err)
+	}
+
+	res, err := fn(ctx, state)
+	if err != nil {
+		return res, false, err
+	}
+
+	if !node.CachePolicy.cacheable(cache, res) {
+		return res, false, nil
+	}
+	if err := cache.Set(ctx, key, res, node.CachePolicy.TTL); err != nil {
+		log.WarnContext(ctx, "cache store failed", "key", key, "error", err)
+	}
+
+	return res, false, nil
+}
+
+// cacheable reports whether a node result may be stored in the cache. Commands
+// are never cached, as they steer the run rather than carry a result, and a
+// JSONCache only stores results that it hands back with their original type.
+func (p *CachePolicy) cacheable(cache Cache, value interface{}) bool {
+	if _, ok := value.(*Command); ok {
+		return false
+	}
+	if _, ok := cache.(JSONCache); !ok || p.Decode != nil {
+		return true
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(value, decoded)
+}
+
+// decode restores a value read back from a JSONCache and returns other values as they are
+func (p *CachePolicy) decode(value interface{}) (interface{}, error) {
+	data, ok := value.(json.RawMessage)
+	if !ok {
+		return value, nil
+	}
+	if p.Decode != nil {
+		return p.Decode(data)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// notifyCacheHit reports a cache hit to callbacks in the config that support it
+func notifyCacheHit(ctx context.Context, nodeName, key string) {
+	config := GetConfig(ctx)
+	if config == nil {
+		return
+	}
+	for _, cb := range config.Callbacks {
+		if ccb, ok := cb.(CacheCallbackHandler); ok {
+			ccb.OnCacheHit(ctx, nodeName, key)
+		}
+	}
+}
+
+// MemoryCache is an in-memory LRU cache with optional per-entry expiration. It
+// stores and returns deep copies (see DeepCopy), so callers mutating a result do
+// not change later hits.
+type MemoryCache struct {
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+	mutex    sync.Mutex
+}
+
+type memoryCacheEntry struct {
+	key       string
+	value     interface{}
+	expiresAt time.Time
+}
+
+// NewMemoryCache creates an in-memory LRU cache holding at most capacity entries.
+// A capacity of zero or less means the cache is unbounded.
+func NewMemoryCache(capacity int) *MemoryCache {
+	return &MemoryCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get implements Cache interface
+func (c *MemoryCache) Get(_ context.Context, key string) (interface{}, bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := elem.Value.(*memoryCacheEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+
+	c.order.MoveToFront(elem)
+	value, err := DeepCopy(entry.value)
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set implements Cache interface
+func (c *MemoryCache) Set(_ context.Context, key string, value interface{}, ttl time.Duration) error {
+	value, err := DeepCopy(value)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*memoryCacheEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return nil
+	}
+
+	elem := c.order.PushFront(&memoryCacheEntry{
+		key:       key,
+		value:     value,
+		expiresAt: expiresAt,
+	})
+	c.entries[key] = elem
+
+	// Evict least recently used entries
+	for c.capacity > 0 && c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+
+	return nil
+}
+
+// Delete implements Cache interface
+func (c *MemoryCache) Delete(_ context.Context, key string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+	return nil
+}
+
+// Clear implements Cache interface
+func (c *MemoryCache) Clear(_ context.Context) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+	return nil
+}
+
+// Len returns the number of entries currently held by the cache
+func (c *MemoryCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.order.Len()
+}
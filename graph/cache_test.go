@@ -0,0 +1,326 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type cacheHitRecorder struct {
+	NoOpCallbackHandler
+	hits []string
+}
+
+func (r *cacheHitRecorder) OnCacheHit(_ context.Context, nodeName string, _ string) {
+	r.hits = append(r.hits, nodeName)
+}
+
+func TestMemoryCache_LRUEviction(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache(2)
+
+	assert.NoError(t, cache.Set(ctx, "a", 1, 0))
+	assert.NoError(t, cache.Set(ctx, "b", 2, 0))
+
+	// Touch "a" so "b" becomes least recently used
+	_, found, _ := cache.Get(ctx, "a")
+	assert.True(t, found)
+
+	assert.NoError(t, cache.Set(ctx, "c", 3, 0))
+	assert.Equal(t, 2, cache.Len())
+
+	_, found, _ = cache.Get(ctx, "b")
+	assert.False(t, found)
+
+	v, found, _ := cache.Get(ctx, "a")
+	assert.True(t, found)
+	assert.Equal(t, 1, v)
+}
+
+func TestMemoryCache_TTL(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache(0)
+
+	assert.NoError(t, cache.Set(ctx, "k", "v", 10*time.Millisecond))
+	_, found, _ := cache.Get(ctx, "k")
+	assert.True(t, found)
+
+	time.Sleep(20 * time.Millisecond)
+	_, found, _ = cache.Get(ctx, "k")
+	assert.False(t, found)
+}
+
+func TestMemoryCache_CopiesValues(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache(0)
+
+	value := map[string]interface{}{"items": []string{"a"}}
+	assert.NoError(t, cache.Set(ctx, "k", value, 0))
+	value["items"].([]string)[0] = "changed by the node"
+
+	hit, _, _ := cache.Get(ctx, "k")
+	hit.(map[string]interface{})["items"].([]string)[0] = "changed by the caller"
+
+	hit, _, _ = cache.Get(ctx, "k")
+	assert.Equal(t, map[string]interface{}{"items": []string{"a"}}, hit)
+}
+
+func TestStateGraph_NodeCache(t *testing.T) {
+	var calls int32
+
+	g := NewStateGraph()
+	g.AddNode("expensive", "expensive", func(ctx context.Context, state interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		m := state.(map[string]interface{})
+		return map[string]interface{}{"query": m["query"], "answer": "computed"}, nil
+	}, WithCachePolicy(CachePolicy{
+		KeyFunc: func(state interface{}) (string, error) {
+			return state.(map[string]interface{})["query"].(string), nil
+		},
+	}))
+	g.AddEdge("expensive", END)
+	g.SetEntryPoint("expensive")
+	g.SetCache(NewMemoryCache(10))
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	input := map[string]interface{}{"query": "q1"}
+
+	res, err := runnable.Invoke(ctx, input)
+	assert.NoError(t, err)
+	assert.Equal(t, "computed", res.(map[string]interface{})["answer"])
+
+	// Second run with the same key is served from the cache
+	recorder := &cacheHitRecorder{}
+	res, err = runnable.InvokeWithConfig(ctx, input, &Config{Callbacks: []CallbackHandler{recorder}})
+	assert.NoError(t, err)
+	assert.Equal(t, "computed", res.(map[string]interface{})["answer"])
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Equal(t, []string{"expensive"}, recorder.hits)
+
+	// Bypassing the cache re-executes the node
+	_, err = runnable.InvokeWithConfig(ctx, input, &Config{
+		Configurable: map[string]interface{}{CacheBypassKey: true},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	// A different key misses the cache
+	_, err = runnable.Invoke(ctx, map[string]interface{}{"query": "q2"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestStateGraph_NodeCacheTracing(t *testing.T) {
+	var calls int32
+
+	g := NewStateGraph()
+	g.AddNode("node", "node", func(ctx context.Context, state interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return state.(string) + "!", nil
+	}, WithCachePolicy(CachePolicy{}))
+	g.AddEdge("node", END)
+	g.SetEntryPoint("node")
+	g.SetCache(NewMemoryCache(10))
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	tracer := NewTracer()
+	runnable.SetTracer(tracer)
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		res, err := runnable.Invoke(ctx, "hi")
+		assert.NoError(t, err)
+		assert.Equal(t, "hi!", res)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	nodeSpans, cacheHits := 0, 0
+	for _, span := range tracer.GetSpans() {
+		if span.NodeName != "node" {
+			continue
+		}
+		nodeSpans++
+		assert.Equal(t, TraceEventNodeEnd, span.Event)
+		if span.Metadata["cache_hit"] == true {
+			cacheHits++
+		}
+	}
+	assert.Equal(t, 2, nodeSpans)
+	assert.Equal(t, 1, cacheHits)
+}
+
+func TestMessageGraph_NodeCacheTracing(t *testing.T) {
+	var calls int32
+
+	g := NewMessageGraph()
+	g.AddNode("node", "node", func(ctx context.Context, state interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return state.(string) + "!", nil
+	}, WithCachePolicy(CachePolicy{}))
+	g.AddEdge("node", END)
+	g.SetEntryPoint("node")
+	g.SetCache(NewMemoryCache(10))
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	tracer := NewTracer()
+	runnable.SetTracer(tracer)
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		res, err := runnable.Invoke(ctx, "hi")
+		assert.NoError(t, err)
+		assert.Equal(t, "hi!", res)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	cacheHits := 0
+	for _, span := range tracer.GetSpans() {
+		if span.NodeName == "node" && span.Metadata["cache_hit"] == true {
+			cacheHits++
+		}
+	}
+	assert.Equal(t, 1, cacheHits)
+}
+
+func TestListenableGraph_NodeCacheEvent(t *testing.T) {
+	g := NewListenableMessageGraph()
+	node := g.AddNode("node", "node", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return "done", nil
+	}, WithCachePolicy(CachePolicy{}))
+	g.AddEdge("node", END)
+	g.SetEntryPoint("node")
+	g.SetCache(NewMemoryCache(10))
+
+	var cacheEvents int32
+	node.AddListener(NodeListenerFunc(func(ctx context.Context, event NodeEvent, nodeName string, state interface{}, err error) {
+		if event == NodeEventCacheHit {
+			atomic.AddInt32(&cacheEvents, 1)
+		}
+	}))
+
+	runnable, err := g.CompileListenable()
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = runnable.Invoke(ctx, "in")
+	assert.NoError(t, err)
+	_, err = runnable.Invoke(ctx, "in")
+	assert.NoError(t, err)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&cacheEvents))
+}
+
+// failingCache is a cache whose backend is unavailable
+type failingCache struct{}
+
+func (failingCache) Get(context.Context, string) (interface{}, bool, error) {
+	return nil, false, errors.New("connection refused")
+}
+
+func (failingCache) Set(context.Context, string, interface{}, time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func (failingCache) Delete(context.Context, string) error { return nil }
+
+func (failingCache) Clear(context.Context) error { return nil }
+
+func TestStateGraph_NodeCacheUnavailable(t *testing.T) {
+	var calls int32
+
+	g := NewStateGraph()
+	g.AddNode("node", "node", func(ctx context.Context, state interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return state.(string) + "!", nil
+	}, WithCachePolicy(CachePolicy{}))
+	g.AddEdge("node", END)
+	g.SetEntryPoint("node")
+	g.SetCache(failingCache{})
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		res, err := runnable.Invoke(context.Background(), "hi")
+		assert.NoError(t, err)
+		assert.Equal(t, "hi!", res)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+// jsonCache stores values as JSON like the Redis and SQLite caches
+type jsonCache struct {
+	*MemoryCache
+}
+
+func (c jsonCache) StoresJSON() {}
+
+func (c jsonCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return c.MemoryCache.Set(ctx, key, json.RawMessage(data), ttl)
+}
+
+type cachedAnswer struct {
+	Text  string `json:"text"`
+	Score int    `json:"score"`
+}
+
+func TestStateGraph_NodeCacheJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		result interface{}
+		policy CachePolicy
+		calls  int32
+	}{
+		{"plain JSON", map[string]interface{}{"text": "ok"}, CachePolicy{}, 1},
+		{"typed with decode", cachedAnswer{Text: "ok", Score: 3}, CachePolicy{Decode: DecodeJSON[cachedAnswer]}, 1},
+		{"typed without decode", cachedAnswer{Text: "ok", Score: 3}, CachePolicy{}, 2},
+		{"command", &Command{Update: "ok"}, CachePolicy{Decode: DecodeJSON[*Command]}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+
+			g := NewStateGraph()
+			g.AddNode("node", "node", func(ctx context.Context, state interface{}) (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				return tt.result, nil
+			}, WithCachePolicy(tt.policy))
+			g.AddEdge("node", END)
+			g.SetEntryPoint("node")
+			cache := jsonCache{NewMemoryCache(10)}
+			g.SetCache(cache)
+
+			runnable, err := g.Compile()
+			assert.NoError(t, err)
+
+			for i := 0; i < 2; i++ {
+				_, err := runnable.Invoke(context.Background(), "in")
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.calls, atomic.LoadInt32(&calls))
+
+			// Hits have the type of the node result
+			res, hit, err := executeWithCache(context.Background(), cache, g.nodes["node"], "in", g.nodes["node"].Function)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.calls == 1, hit)
+			assert.Equal(t, tt.result, res)
+		})
+	}
+}
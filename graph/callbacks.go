@@ -36,6 +36,57 @@ type GraphCallbackHandler interface {
 	OnGraphStep(ctx context.Context, stepNode string, state interface{})
 }
 
+// RoutingCallbackHandler is implemented by callbacks that want to observe routing
+// decisions made by conditional edges and Command.Goto
+type RoutingCallbackHandler interface {
+	OnRoute(ctx context.Context, from string, to []string)
+}
+
+// notifyRoute reports a routing decision to the callbacks in the config that support it
+func notifyRoute(ctx context.Context, from string, to ...string) {
+	config := GetConfig(ctx)
+	if config == nil {
+		return
+	}
+	for _, cb := range config.Callbacks {
+		if rcb, ok := cb.(RoutingCallbackHandler); ok {
+			rcb.OnRoute(ctx, from, to)
+		}
+	}
+}
+
+// startNodeRun reports the start of a node execution, with the state it receives, to
+// the callbacks in the config as a tool run under the graph run in ctx. It returns the
+// context to execute the node with, so the calls made by the node are nested under its
+// run, and a function reporting the result of the execution.
+func startNodeRun(ctx context.Context, name string, state interface{}) (context.Context, func(result interface{}, err error)) {
+	ctx = withNodeName(ctx, name)
+	config := GetConfig(ctx)
+	if config == nil || len(config.Callbacks) == 0 {
+		return ctx, func(interface{}, error) {}
+	}
+
+	runID := generateRunID()
+	serialized := map[string]interface{}{
+		"name": name,
+		"type": "tool",
+	}
+	parentRunID := ParentRunIDFromContext(ctx)
+	for _, cb := range config.Callbacks {
+		cb.OnToolStart(ctx, serialized, convertStateToString(state), runID, parentRunID, config.Tags, config.Metadata)
+	}
+
+	return WithParentRunID(ctx, runID), func(result interface{}, err error) {
+		for _, cb := range config.Callbacks {
+			if err != nil {
+				cb.OnToolError(ctx, err, runID)
+			} else {
+				cb.OnToolEnd(ctx, convertStateToString(result), runID)
+			}
+		}
+	}
+}
+
 // Config represents configuration for graph invocation
 // This matches Python's config dict pattern
 type Config struct {
@@ -68,6 +119,10 @@ type Config struct {
 
 	// ResumeValue provides the value to return from an Interrupt() call when resuming
 	ResumeValue interface{} `json:"resume_value"`
+
+	// MaxConcurrency limits the nodes of the run executing at once, including the
+	// members of parallel and map-reduce groups; zero means no limit
+	MaxConcurrency int `json:"max_concurrency"`
 }
 
 // NoOpCallbackHandler provides a no-op implementation of CallbackHandler
@@ -3,12 +3,23 @@ package graph
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"sync"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/smallnest/langgraphgo/log"
 )
 
+// ErrNoPendingInterrupt is returned when resuming a thread that is not interrupted
+var ErrNoPendingInterrupt = errors.New("no pending interrupt")
+
+// ErrCheckpointNotFound is matched by the errors of checkpoint stores loading a
+// checkpoint that does not exist
+var ErrCheckpointNotFound = errors.New("checkpoint not found")
+
 // Checkpoint represents a saved state at a specific point in execution
 type Checkpoint struct {
 	ID        string                 `json:"id"`
@@ -66,7 +77,7 @@ func (m *MemoryCheckpointStore) Load(_ context.Context, checkpointID string) (*C
 
 	checkpoint, exists := m.checkpoints[checkpointID]
 	if !exists {
-		return nil, fmt.Errorf("checkpoint not found: %s", checkpointID)
+		return nil, fmt.Errorf("%w: %s", ErrCheckpointNotFound, checkpointID)
 	}
 
 	return checkpoint, nil
@@ -160,7 +171,7 @@ func (f *FileCheckpointStore) Load(_ context.Context, checkpointID string) (*Che
 	}
 
 	if checkpoint.ID != checkpointID {
-		return nil, fmt.Errorf("checkpoint not found: %s", checkpointID)
+		return nil, fmt.Errorf("%w: %s", ErrCheckpointNotFound, checkpointID)
 	}
 
 	return &checkpoint, nil
@@ -232,22 +243,89 @@ func (cr *CheckpointableRunnable) Invoke(ctx context.Context, initialState inter
 	return cr.InvokeWithConfig(ctx, initialState, nil)
 }
 
-// InvokeWithConfig executes the graph with checkpointing and config
+// InvokeWithConfig executes the graph with checkpointing and config.
+// Checkpoints are saved under the thread_id from config.Configurable, or the
+// runnable's execution ID when none is given. If the run is interrupted, an
+// interrupt checkpoint recording the next nodes is saved so it can be resumed.
 func (cr *CheckpointableRunnable) InvokeWithConfig(ctx context.Context, initialState interface{}, config *Config) (interface{}, error) {
+	threadID := cr.threadID(config)
+
 	// Create checkpointing listener
 	checkpointListener := &CheckpointListener{
 		store:       cr.config.Store,
-		executionID: cr.executionID,
+		executionID: threadID,
 		autoSave:    cr.config.AutoSave,
 	}
 
-	// Add checkpoint listener to config callbacks
-	if config == nil {
-		config = &Config{}
+	// Add checkpoint listener to a copy of the config callbacks
+	runConfig := &Config{}
+	if config != nil {
+		*runConfig = *config
+	}
+	runConfig.Callbacks = append(append([]CallbackHandler{}, runConfig.Callbacks...), checkpointListener)
+
+	// Make the thread visible to nodes and callbacks, even when it defaulted to the execution ID
+	configurable := make(map[string]interface{}, len(runConfig.Configurable)+1)
+	for k, v := range runConfig.Configurable {
+		configurable[k] = v
+	}
+	configurable["thread_id"] = threadID
+	runConfig.Configurable = configurable
+
+	// Let subgraphs checkpoint under this thread's namespace
+	ctx = withCheckpointScope(ctx, cr.config.Store, threadID)
+
+	result, err := cr.runnable.InvokeWithConfig(ctx, initialState, runConfig)
+	if err != nil {
+		var interrupt *GraphInterrupt
+		if errors.As(err, &interrupt) {
+			if saveErr := saveInterruptCheckpoint(WithConfig(ctx, runConfig), cr.config.Store, threadID, interrupt); saveErr != nil {
+				return result, fmt.Errorf("failed to save interrupt checkpoint: %w", saveErr)
+			}
+		}
+	}
+
+	return result, err
+}
+
+// Resume continues the interrupted run of the thread in config, e.g. a run stopped by
+// Runtime.Shutdown in another process, from the state and next nodes of its interrupt
+// checkpoint. It returns ErrNoPendingInterrupt when the thread is not interrupted.
+func (cr *CheckpointableRunnable) Resume(ctx context.Context, config *Config) (interface{}, error) {
+	threadID := cr.threadID(config)
+	pending, err := pendingInterrupt(ctx, cr.config.Store, threadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+	if pending == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoPendingInterrupt, threadID)
+	}
+
+	resumeConfig := &Config{}
+	if config != nil {
+		*resumeConfig = *config
+	}
+	resumeConfig.ResumeFrom = checkpointNext(pending)
+
+	// Keep resuming under the same thread, even when it defaulted to the execution ID
+	configurable := make(map[string]interface{}, len(resumeConfig.Configurable)+1)
+	for k, v := range resumeConfig.Configurable {
+		configurable[k] = v
 	}
-	config.Callbacks = append(config.Callbacks, checkpointListener)
+	configurable["thread_id"] = threadID
+	resumeConfig.Configurable = configurable
 
-	return cr.runnable.InvokeWithConfig(ctx, initialState, config)
+	return cr.InvokeWithConfig(ctx, pending.State, resumeConfig)
+}
+
+// threadID returns the thread ID from the config, defaulting to the execution ID
+func (cr *CheckpointableRunnable) threadID(config *Config) string {
+	if config != nil && config.Configurable != nil {
+		if tid, ok := config.Configurable["thread_id"].(string); ok && tid != "" {
+			return tid
+		}
+	}
+	return cr.executionID
 }
 
 // SaveCheckpoint manually saves a checkpoint
@@ -291,7 +369,10 @@ func (cr *CheckpointableRunnable) ResumeFromCheckpoint(ctx context.Context, chec
 
 // ClearCheckpoints removes all checkpoints for this execution
 func (cr *CheckpointableRunnable) ClearCheckpoints(ctx context.Context) error {
-	return cr.config.Store.Clear(ctx, cr.executionID)
+	if err := cr.config.Store.Clear(ctx, cr.executionID); err != nil {
+		return err
+	}
+	return cr.config.Store.Clear(ctx, taskNamespace(cr.executionID))
 }
 
 // CheckpointListener automatically creates checkpoints during execution
@@ -305,6 +386,11 @@ type CheckpointListener struct {
 
 // OnGraphStep implements GraphCallbackHandler
 func (cl *CheckpointListener) OnGraphStep(ctx context.Context, stepNode string, state interface{}) {
+	// Once the step is saved, later executions of its nodes run their tasks again
+	if scope := checkpointScopeFromContext(ctx); scope != nil {
+		defer scope.endTasks(ctx, checkpointStepNodes(stepNode))
+	}
+
 	if !cl.autoSave {
 		return
 	}
@@ -320,13 +406,12 @@ func (cl *CheckpointListener) OnGraphStep(ctx context.Context, stepNode string,
 			"event":        "step",
 		},
 	}
+	addCheckpointMetadata(ctx, checkpoint.Metadata)
 
-	// Save checkpoint asynchronously
-	go func(ctx context.Context) {
-		if saveErr := cl.store.Save(ctx, checkpoint); saveErr != nil {
-			_ = saveErr
-		}
-	}(ctx)
+	// Save synchronously so the checkpoint is visible before the next step runs
+	if err := cl.store.Save(ctx, checkpoint); err != nil {
+		log.WarnContext(ctx, "failed to save checkpoint", "execution_id", cl.executionID, "node", stepNode, "error", err)
+	}
 }
 
 // OnNodeEvent is no longer used for saving state, but kept if needed for interface compatibility
@@ -381,7 +466,109 @@ func generateExecutionID() string {
 }
 
 func generateCheckpointID() string {
-	return fmt.Sprintf("checkpoint_%d", time.Now().UnixNano())
+	return fmt.Sprintf("checkpoint_%s", uuid.New().String())
+}
+
+// CheckpointNamespaceSeparator separates a parent thread ID from a subgraph node name
+// in the checkpoint namespace of a subgraph
+const CheckpointNamespaceSeparator = "|"
+
+// SubgraphNamespace returns the checkpoint namespace used for a subgraph node
+// executed within the given parent thread
+func SubgraphNamespace(parentThreadID, subgraphNode string) string {
+	return parentThreadID + CheckpointNamespaceSeparator + subgraphNode
+}
+
+type checkpointScopeKey struct{}
+
+// checkpointScope carries the checkpoint store and thread of the running graph
+type checkpointScope struct {
+	store    CheckpointStore
+	threadID string
+
+	mu sync.Mutex
+	// tasks holds the IDs of the task records of the running node executions by node
+	tasks map[string][]string
+}
+
+// withCheckpointScope adds the checkpoint store and thread ID to the context
+func withCheckpointScope(ctx context.Context, store CheckpointStore, threadID string) context.Context {
+	return context.WithValue(ctx, checkpointScopeKey{}, &checkpointScope{store: store, threadID: threadID})
+}
+
+// checkpointScopeFromContext retrieves the checkpoint scope from the context
+func checkpointScopeFromContext(ctx context.Context) *checkpointScope {
+	if scope, ok := ctx.Value(checkpointScopeKey{}).(*checkpointScope); ok {
+		return scope
+	}
+	return nil
+}
+
+// latestCheckpoint returns the most recent checkpoint by timestamp
+func latestCheckpoint(checkpoints []*Checkpoint) *Checkpoint {
+	var latest *Checkpoint
+	for _, cp := range checkpoints {
+		if latest == nil || !cp.Timestamp.Before(latest.Timestamp) {
+			latest = cp
+		}
+	}
+	return latest
+}
+
+// checkpointNext returns the next nodes recorded in a checkpoint's metadata
+func checkpointNext(checkpoint *Checkpoint) []string {
+	switch next := checkpoint.Metadata["next"].(type) {
+	case []string:
+		return next
+	case []interface{}:
+		nodes := make([]string, 0, len(next))
+		for _, n := range next {
+			if s, ok := n.(string); ok {
+				nodes = append(nodes, s)
+			}
+		}
+		return nodes
+	}
+	return nil
+}
+
+// saveInterruptCheckpoint records an interrupted run so it can be resumed later
+func saveInterruptCheckpoint(ctx context.Context, store CheckpointStore, threadID string, interrupt *GraphInterrupt) error {
+	checkpoint := &Checkpoint{
+		ID:        generateCheckpointID(),
+		NodeName:  interrupt.Node,
+		State:     interrupt.State,
+		Timestamp: time.Now(),
+		Version:   1,
+		Metadata: map[string]interface{}{
+			"execution_id": threadID,
+			"event":        "interrupt",
+			"next":         interrupt.NextNodes,
+		},
+	}
+	if interrupt.InterruptValue != nil {
+		checkpoint.Metadata["interrupt_value"] = interrupt.InterruptValue
+	}
+	if errors.Is(interrupt, ErrShutdown) {
+		checkpoint.Metadata["reason"] = "shutdown"
+	}
+	addCheckpointMetadata(ctx, checkpoint.Metadata)
+
+	return store.Save(ctx, checkpoint)
+}
+
+// pendingInterrupt returns the latest checkpoint of a thread if it records an interrupt
+func pendingInterrupt(ctx context.Context, store CheckpointStore, threadID string) (*Checkpoint, error) {
+	checkpoints, err := store.List(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := latestCheckpoint(checkpoints)
+	if latest == nil || latest.Metadata["event"] != "interrupt" {
+		return nil, nil
+	}
+	return latest, nil
 }
 
 // StateSnapshot represents a snapshot of the graph state
@@ -392,58 +579,91 @@ type StateSnapshot struct {
 	Metadata  map[string]interface{}
 	CreatedAt time.Time
 	ParentID  string
+
+	// Subgraphs holds the snapshots of subgraph nodes keyed by node name.
+	// It is only populated when GetState is called with WithSubgraphs.
+	Subgraphs map[string]*StateSnapshot
+}
+
+// GetStateOption configures GetState
+type GetStateOption func(*getStateOptions)
+
+type getStateOptions struct {
+	subgraphs bool
+}
+
+// WithSubgraphs includes the snapshots of subgraph nodes in the returned StateSnapshot
+func WithSubgraphs() GetStateOption {
+	return func(o *getStateOptions) {
+		o.subgraphs = true
+	}
 }
 
 // GetState retrieves the state for the given config
-func (cr *CheckpointableRunnable) GetState(ctx context.Context, config *Config) (*StateSnapshot, error) {
-	var threadID string
-	var checkpointID string
+func (cr *CheckpointableRunnable) GetState(ctx context.Context, config *Config, opts ...GetStateOption) (*StateSnapshot, error) {
+	options := &getStateOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	threadID := cr.threadID(config)
 
+	var checkpointID string
 	if config != nil && config.Configurable != nil {
-		if tid, ok := config.Configurable["thread_id"].(string); ok {
-			threadID = tid
-		}
 		if cid, ok := config.Configurable["checkpoint_id"].(string); ok {
 			checkpointID = cid
 		}
 	}
 
-	// Default to current execution ID if thread_id not provided
-	if threadID == "" {
-		threadID = cr.executionID
+	snapshot, err := cr.loadSnapshot(ctx, threadID, checkpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.subgraphs {
+		if err := cr.loadSubgraphSnapshots(ctx, snapshot, threadID, cr.runnable.graph.subgraphs); err != nil {
+			return nil, err
+		}
 	}
 
+	return snapshot, nil
+}
+
+// loadSnapshot builds a snapshot from a specific checkpoint or the latest checkpoint of a thread
+func (cr *CheckpointableRunnable) loadSnapshot(ctx context.Context, threadID, checkpointID string) (*StateSnapshot, error) {
 	var checkpoint *Checkpoint
-	var err error
 
 	if checkpointID != "" {
+		var err error
 		checkpoint, err = cr.config.Store.Load(ctx, checkpointID)
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		// Get latest checkpoint for the thread
-		// Note: List returns all checkpoints. We need to find the latest one.
 		// This is inefficient for large histories. Real implementations should have GetLatest.
 		checkpoints, err := cr.config.Store.List(ctx, threadID)
-		if err == nil && len(checkpoints) > 0 {
-			// Assuming List returns in some order, or we sort.
-			// For now, assume the last one is latest (based on implementation of MemoryStore)
-			checkpoint = checkpoints[len(checkpoints)-1]
+		if err == nil {
+			checkpoint = latestCheckpoint(checkpoints)
 		}
 	}
 
-	if err != nil {
-		return nil, err
-	}
-
 	if checkpoint == nil {
 		return &StateSnapshot{
 			Values: nil,
-			Config: *config,
+			Config: Config{
+				Configurable: map[string]interface{}{
+					"thread_id": threadID,
+				},
+			},
 		}, nil
 	}
 
 	// Construct snapshot
-	snapshot := &StateSnapshot{
+	// "Next" nodes are only known for checkpoints that record them (e.g. interrupts)
+	return &StateSnapshot{
 		Values:    checkpoint.State,
+		Next:      checkpointNext(checkpoint),
 		CreatedAt: checkpoint.Timestamp,
 		Metadata:  checkpoint.Metadata,
 		Config: Config{
@@ -452,28 +672,37 @@ func (cr *CheckpointableRunnable) GetState(ctx context.Context, config *Config)
 				"checkpoint_id": checkpoint.ID,
 			},
 		},
-	}
+	}, nil
+}
 
-	// Determine "Next" nodes
-	// This is tricky without re-running the graph logic or storing it in the checkpoint.
-	// For now, we might leave it empty or try to infer it if we stored it.
-	// In the future, Checkpoint should store "Next" nodes.
+// loadSubgraphSnapshots recursively attaches the snapshots of subgraph nodes
+func (cr *CheckpointableRunnable) loadSubgraphSnapshots(ctx context.Context, snapshot *StateSnapshot, threadID string, subgraphs map[string]*Subgraph) error {
+	for name, sg := range subgraphs {
+		namespace := SubgraphNamespace(threadID, name)
+		child, err := cr.loadSnapshot(ctx, namespace, "")
+		if err != nil {
+			return fmt.Errorf("failed to load state of subgraph %s: %w", name, err)
+		}
+		if child.Values == nil {
+			// Subgraph has not run in this thread
+			continue
+		}
 
-	return snapshot, nil
-}
+		if err := cr.loadSubgraphSnapshots(ctx, child, namespace, sg.graph.subgraphs); err != nil {
+			return err
+		}
 
-// UpdateState updates the state for the given config
-func (cr *CheckpointableRunnable) UpdateState(ctx context.Context, config *Config, values interface{}, asNode string) (*Config, error) {
-	var threadID string
-	if config != nil && config.Configurable != nil {
-		if tid, ok := config.Configurable["thread_id"].(string); ok {
-			threadID = tid
+		if snapshot.Subgraphs == nil {
+			snapshot.Subgraphs = make(map[string]*StateSnapshot)
 		}
+		snapshot.Subgraphs[name] = child
 	}
+	return nil
+}
 
-	if threadID == "" {
-		threadID = cr.executionID
-	}
+// UpdateState updates the state for the given config
+func (cr *CheckpointableRunnable) UpdateState(ctx context.Context, config *Config, values interface{}, asNode string) (*Config, error) {
+	threadID := cr.threadID(config)
 
 	// 1. Get current state
 	// We need to find the latest checkpoint for this thread to merge against
@@ -481,9 +710,7 @@ func (cr *CheckpointableRunnable) UpdateState(ctx context.Context, config *Confi
 	var currentState interface{}
 	var currentVersion int
 
-	if err == nil && len(checkpoints) > 0 {
-		// Assume last is latest
-		latest := checkpoints[len(checkpoints)-1]
+	if latest := latestCheckpoint(checkpoints); err == nil && latest != nil {
 		currentState = latest.State
 		currentVersion = latest.Version
 	} else {
@@ -3,12 +3,14 @@ package graph_test
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/smallnest/langgraphgo/graph"
+	"github.com/smallnest/langgraphgo/log"
 )
 
 const (
@@ -69,7 +71,7 @@ func TestMemoryCheckpointStore_LoadNonExistent(t *testing.T) {
 		t.Error("Expected error for non-existent checkpoint")
 	}
 
-	if !strings.Contains(err.Error(), "checkpoint not found") {
+	if !errors.Is(err, graph.ErrCheckpointNotFound) {
 		t.Errorf("Expected 'checkpoint not found' error, got: %v", err)
 	}
 }
@@ -688,3 +690,43 @@ func TestCheckpointListener_ErrorHandling(t *testing.T) {
 		t.Errorf("Expected no checkpoints for failed execution, got %d", len(checkpoints))
 	}
 }
+
+// failingSaveStore is a checkpoint store whose saves fail
+type failingSaveStore struct {
+	graph.CheckpointStore
+}
+
+func (s *failingSaveStore) Save(ctx context.Context, checkpoint *graph.Checkpoint) error {
+	return fmt.Errorf("disk full")
+}
+
+func TestCheckpointListener_LogsSaveErrors(t *testing.T) {
+	originalLogger := log.GetDefaultLogger()
+	defer log.SetDefaultLogger(originalLogger)
+
+	var buf bytes.Buffer
+	log.SetDefaultLogger(log.NewCustomLogger(&buf, log.LogLevelWarn))
+
+	g := graph.NewCheckpointableMessageGraphWithConfig(graph.CheckpointConfig{
+		Store:    &failingSaveStore{CheckpointStore: graph.NewMemoryCheckpointStore()},
+		AutoSave: true,
+	})
+	g.AddNode(testNode, testNode, func(ctx context.Context, state interface{}) (interface{}, error) {
+		return testResult, nil
+	})
+	g.AddEdge(testNode, graph.END)
+	g.SetEntryPoint(testNode)
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+
+	if _, err := runnable.Invoke(context.Background(), "input"); err != nil {
+		t.Fatalf("Failed to invoke: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "failed to save checkpoint") || !strings.Contains(buf.String(), "disk full") {
+		t.Errorf("Expected the save error to be logged, got %q", buf.String())
+	}
+}
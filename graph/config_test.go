@@ -1,9 +1,12 @@
 package graph
 
 import (
+	"bytes"
 	"context"
+	"log/slog"
 	"testing"
 
+	"github.com/smallnest/langgraphgo/log"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -77,3 +80,52 @@ func TestStateGraph_RuntimeConfiguration(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "secret-123", result)
 }
+
+func TestRunContextLogAttributes(t *testing.T) {
+	type compiled interface {
+		InvokeWithConfig(ctx context.Context, state interface{}, config *Config) (interface{}, error)
+	}
+	build := map[string]func(node func(context.Context, interface{}) (interface{}, error)) compiled{
+		"StateGraph": func(node func(context.Context, interface{}) (interface{}, error)) compiled {
+			g := NewStateGraph()
+			g.AddNode("worker", "worker", node)
+			g.SetEntryPoint("worker")
+			g.AddEdge("worker", END)
+			runnable, err := g.Compile()
+			assert.NoError(t, err)
+			return runnable
+		},
+		"MessageGraph": func(node func(context.Context, interface{}) (interface{}, error)) compiled {
+			g := NewMessageGraph()
+			g.AddNode("worker", "worker", node)
+			g.SetEntryPoint("worker")
+			g.AddEdge("worker", END)
+			runnable, err := g.Compile()
+			assert.NoError(t, err)
+			return runnable
+		},
+	}
+
+	for name, compile := range build {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := log.NewSlogLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+
+			var runID string
+			runnable := compile(func(ctx context.Context, state interface{}) (interface{}, error) {
+				runID = RunIDFromContext(ctx)
+				assert.Equal(t, "worker", NodeNameFromContext(ctx))
+				logger.Log(ctx, slog.LevelInfo, "working")
+				return state, nil
+			})
+
+			_, err := runnable.InvokeWithConfig(context.Background(), "in", &Config{
+				Configurable: map[string]interface{}{"thread_id": "t1"},
+			})
+			assert.NoError(t, err)
+
+			assert.NotEmpty(t, runID)
+			assert.Contains(t, buf.String(), "msg=working run_id="+runID+" thread_id=t1 node=worker")
+		})
+	}
+}
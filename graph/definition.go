@@ -0,0 +1,480 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GraphDefinition is a declarative description of a StateGraph.
+// It can be loaded from YAML or JSON and built into a StateGraph using a Registry
+// that resolves node functions, conditions and reducers by name.
+// The Nodes and Edges fields share their layout with the workflow plans generated
+// by the planning agent, so a plan can be built directly into a graph.
+type GraphDefinition struct {
+	Name        string `json:"name,omitempty" yaml:"name,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+
+	// EntryPoint is the first node to execute. An edge from START may be used instead.
+	EntryPoint string `json:"entry_point,omitempty" yaml:"entry_point,omitempty"`
+
+	// State maps state keys to the names of their reducers in the registry
+	State map[string]string `json:"state,omitempty" yaml:"state,omitempty"`
+
+	// RetryPolicy is the graph-wide retry policy
+	RetryPolicy *RetryPolicyDefinition `json:"retry_policy,omitempty" yaml:"retry_policy,omitempty"`
+
+	Nodes []NodeDefinition `json:"nodes" yaml:"nodes"`
+	Edges []EdgeDefinition `json:"edges" yaml:"edges"`
+}
+
+// NodeDefinition describes a node of a GraphDefinition
+type NodeDefinition struct {
+	Name        string `json:"name" yaml:"name"`
+	Type        string `json:"type,omitempty" yaml:"type,omitempty"` // "start", "process", "end", "conditional"
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+
+	// Function is the name of the registered node function, defaults to Name
+	Function string `json:"function,omitempty" yaml:"function,omitempty"`
+
+	// Timeout limits the execution time of the node, e.g. "30s"
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+
+	// Retry retries the node on failure
+	Retry *RetryDefinition `json:"retry,omitempty" yaml:"retry,omitempty"`
+}
+
+// EdgeDefinition describes an edge of a GraphDefinition.
+// An edge with a Condition is conditional: the registered condition is evaluated
+// at runtime and its output is mapped to the next node through Paths.
+type EdgeDefinition struct {
+	From      string            `json:"from" yaml:"from"`
+	To        string            `json:"to,omitempty" yaml:"to,omitempty"`
+	Condition string            `json:"condition,omitempty" yaml:"condition,omitempty"`
+	Paths     map[string]string `json:"paths,omitempty" yaml:"paths,omitempty"`
+}
+
+// RetryDefinition describes a per-node retry configuration
+type RetryDefinition struct {
+	MaxAttempts   int     `json:"max_attempts,omitempty" yaml:"max_attempts,omitempty"`
+	InitialDelay  string  `json:"initial_delay,omitempty" yaml:"initial_delay,omitempty"`
+	MaxDelay      string  `json:"max_delay,omitempty" yaml:"max_delay,omitempty"`
+	BackoffFactor float64 `json:"backoff_factor,omitempty" yaml:"backoff_factor,omitempty"`
+}
+
+// RetryPolicyDefinition describes the graph-wide RetryPolicy
+type RetryPolicyDefinition struct {
+	MaxRetries      int      `json:"max_retries" yaml:"max_retries"`
+	Backoff         string   `json:"backoff,omitempty" yaml:"backoff,omitempty"` // "fixed", "exponential", "linear"
+	RetryableErrors []string `json:"retryable_errors,omitempty" yaml:"retryable_errors,omitempty"`
+}
+
+// graphDefinitionRefs records the definitions a StateGraph was built from
+type graphDefinitionRefs struct {
+	nodes      map[string]NodeDefinition
+	conditions map[string]string
+	state      map[string]string
+}
+
+var backoffNames = map[BackoffStrategy]string{
+	FixedBackoff:       "fixed",
+	ExponentialBackoff: "exponential",
+	LinearBackoff:      "linear",
+}
+
+// Registry resolves the names used in a GraphDefinition to Go functions
+type Registry struct {
+	mu         sync.RWMutex
+	nodes      map[string]func(ctx context.Context, state interface{}) (interface{}, error)
+	conditions map[string]func(ctx context.Context, state interface{}) string
+	reducers   map[string]Reducer
+}
+
+// NewRegistry creates a registry with the built-in "overwrite" and "append" reducers
+func NewRegistry() *Registry {
+	return &Registry{
+		nodes:      make(map[string]func(ctx context.Context, state interface{}) (interface{}, error)),
+		conditions: make(map[string]func(ctx context.Context, state interface{}) string),
+		reducers: map[string]Reducer{
+			"overwrite": OverwriteReducer,
+			"append":    AppendReducer,
+		},
+	}
+}
+
+// RegisterNode registers a node function under the given name
+func (r *Registry) RegisterNode(name string, fn func(ctx context.Context, state interface{}) (interface{}, error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nodes[name] = fn
+}
+
+// RegisterCondition registers a conditional edge function under the given name
+func (r *Registry) RegisterCondition(name string, condition func(ctx context.Context, state interface{}) string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.conditions[name] = condition
+}
+
+// RegisterReducer registers a reducer under the given name
+func (r *Registry) RegisterReducer(name string, reducer Reducer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reducers[name] = reducer
+}
+
+func (r *Registry) node(name string) (func(ctx context.Context, state interface{}) (interface{}, error), bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fn, ok := r.nodes[name]
+	return fn, ok
+}
+
+func (r *Registry) condition(name string) (func(ctx context.Context, state interface{}) string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fn, ok := r.conditions[name]
+	return fn, ok
+}
+
+func (r *Registry) reducer(name string) (Reducer, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fn, ok := r.reducers[name]
+	return fn, ok
+}
+
+// ParseGraphDefinition parses a graph definition from YAML or JSON
+func ParseGraphDefinition(data []byte) (*GraphDefinition, error) {
+	// YAML is a superset of JSON, so a single decoder handles both formats
+	var def GraphDefinition
+	if err := yaml.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("failed to parse graph definition: %w", err)
+	}
+	return &def, nil
+}
+
+// LoadGraphDefinition reads a graph definition from a YAML or JSON file
+func LoadGraphDefinition(path string) (*GraphDefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read graph definition: %w", err)
+	}
+	return ParseGraphDefinition(data)
+}
+
+// LoadStateGraph reads a graph definition file and builds it with the registry
+func LoadStateGraph(path string, registry *Registry) (*StateGraph, error) {
+	def, err := LoadGraphDefinition(path)
+	if err != nil {
+		return nil, err
+	}
+	return def.Build(registry)
+}
+
+// JSON serializes the definition as indented JSON
+func (d *GraphDefinition) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// YAML serializes the definition as YAML
+func (d *GraphDefinition) YAML() ([]byte, error) {
+	return yaml.Marshal(d)
+}
+
+// Build creates a StateGraph from the definition, resolving names with the registry
+func (d *GraphDefinition) Build(registry *Registry) (*StateGraph, error) {
+	if registry == nil {
+		registry = NewRegistry()
+	}
+
+	g := NewStateGraph()
+	g.definitions = &graphDefinitionRefs{
+		nodes:      make(map[string]NodeDefinition),
+		conditions: make(map[string]string),
+		state:      d.State,
+	}
+
+	// State schema
+	if len(d.State) > 0 {
+		schema := NewMapSchema()
+		for key, name := range d.State {
+			reducer, ok := registry.reducer(name)
+			if !ok {
+				return nil, fmt.Errorf("reducer %s for state key %s is not registered", name, key)
+			}
+			schema.RegisterReducer(key, reducer)
+		}
+		g.SetSchema(schema)
+	}
+
+	// Graph-wide retry policy
+	if d.RetryPolicy != nil {
+		policy, err := d.RetryPolicy.policy()
+		if err != nil {
+			return nil, err
+		}
+		g.SetRetryPolicy(policy)
+	}
+
+	// Nodes
+	for _, nodeDef := range d.Nodes {
+		if nodeDef.Name == START || nodeDef.Name == END {
+			continue // Special nodes have no function
+		}
+		if nodeDef.Name == "" {
+			return nil, fmt.Errorf("node definition has no name")
+		}
+
+		fnName := nodeDef.Function
+		if fnName == "" {
+			fnName = nodeDef.Name
+		}
+		fn, ok := registry.node(fnName)
+		if !ok {
+			return nil, fmt.Errorf("function %s for node %s is not registered", fnName, nodeDef.Name)
+		}
+
+		fn, err := nodeDef.wrap(fn)
+		if err != nil {
+			return nil, err
+		}
+
+		g.AddNode(nodeDef.Name, nodeDef.Description, fn)
+		g.definitions.nodes[nodeDef.Name] = nodeDef
+	}
+
+	// Edges
+	entryPoint := d.EntryPoint
+	for _, edge := range d.Edges {
+		if edge.From == START {
+			if entryPoint != "" && entryPoint != edge.To {
+				return nil, fmt.Errorf("conflicting entry points %s and %s", entryPoint, edge.To)
+			}
+			entryPoint = edge.To
+			continue
+		}
+
+		if err := d.checkNode(g, edge.From); err != nil {
+			return nil, fmt.Errorf("edge from %s: %w", edge.From, err)
+		}
+
+		if edge.Condition != "" {
+			condition, ok := registry.condition(edge.Condition)
+			if !ok {
+				return nil, fmt.Errorf("condition %s for edge from %s is not registered", edge.Condition, edge.From)
+			}
+			for _, target := range edge.Paths {
+				if err := d.checkNode(g, target); err != nil {
+					return nil, fmt.Errorf("conditional edge from %s: %w", edge.From, err)
+				}
+			}
+			g.AddConditionalEdges(edge.From, condition, edge.Paths)
+			g.definitions.conditions[edge.From] = edge.Condition
+			continue
+		}
+
+		if err := d.checkNode(g, edge.To); err != nil {
+			return nil, fmt.Errorf("edge from %s: %w", edge.From, err)
+		}
+		g.AddEdge(edge.From, edge.To)
+	}
+
+	if entryPoint == "" {
+		return nil, ErrEntryPointNotSet
+	}
+	if err := d.checkNode(g, entryPoint); err != nil {
+		return nil, fmt.Errorf("entry point: %w", err)
+	}
+	g.SetEntryPoint(entryPoint)
+
+	return g, nil
+}
+
+// checkNode verifies that an edge endpoint refers to a node of the graph
+func (d *GraphDefinition) checkNode(g *StateGraph, name string) error {
+	if name == END {
+		return nil
+	}
+	if _, ok := g.nodes[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrNodeNotFound, name)
+	}
+	return nil
+}
+
+// wrap applies the timeout and retry settings of the node definition
+func (nd NodeDefinition) wrap(fn func(ctx context.Context, state interface{}) (interface{}, error)) (func(ctx context.Context, state interface{}) (interface{}, error), error) {
+	if nd.Timeout != "" {
+		timeout, err := time.ParseDuration(nd.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout for node %s: %w", nd.Name, err)
+		}
+		fn = NewTimeoutNode(Node{Name: nd.Name, Function: fn}, timeout).Execute
+	}
+
+	if nd.Retry != nil {
+		config, err := nd.Retry.config()
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry for node %s: %w", nd.Name, err)
+		}
+		fn = NewRetryNode(Node{Name: nd.Name, Function: fn}, config).Execute
+	}
+
+	return fn, nil
+}
+
+// config converts the definition to a RetryConfig, using defaults for unset fields
+func (rd *RetryDefinition) config() (*RetryConfig, error) {
+	config := DefaultRetryConfig()
+	if rd.MaxAttempts > 0 {
+		config.MaxAttempts = rd.MaxAttempts
+	}
+	if rd.BackoffFactor > 0 {
+		config.BackoffFactor = rd.BackoffFactor
+	}
+	if rd.InitialDelay != "" {
+		delay, err := time.ParseDuration(rd.InitialDelay)
+		if err != nil {
+			return nil, err
+		}
+		config.InitialDelay = delay
+	}
+	if rd.MaxDelay != "" {
+		delay, err := time.ParseDuration(rd.MaxDelay)
+		if err != nil {
+			return nil, err
+		}
+		config.MaxDelay = delay
+	}
+	return config, nil
+}
+
+// policy converts the definition to a RetryPolicy
+func (rd *RetryPolicyDefinition) policy() (*RetryPolicy, error) {
+	policy := &RetryPolicy{
+		MaxRetries:      rd.MaxRetries,
+		RetryableErrors: rd.RetryableErrors,
+	}
+	if rd.Backoff != "" {
+		found := false
+		for strategy, name := range backoffNames {
+			if name == rd.Backoff {
+				policy.BackoffStrategy = strategy
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown backoff strategy: %s", rd.Backoff)
+		}
+	}
+	return policy, nil
+}
+
+// Export serializes the topology of the graph as a GraphDefinition.
+// Nodes and conditions are referenced by the names they were built from. For graphs
+// constructed in code, node functions and conditions are referenced by the name of the
+// node, so they must be registered under those names to build the definition again.
+func (g *StateGraph) Export() *GraphDefinition {
+	def := &GraphDefinition{
+		EntryPoint: g.entryPoint,
+	}
+
+	// Nodes, sorted for a stable output
+	names := make([]string, 0, len(g.nodes))
+	for name := range g.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if g.definitions != nil {
+			if nodeDef, ok := g.definitions.nodes[name]; ok {
+				def.Nodes = append(def.Nodes, nodeDef)
+				continue
+			}
+		}
+
+		node := g.nodes[name]
+		nodeType := "process"
+		if _, ok := g.conditionalEdges[name]; ok {
+			nodeType = "conditional"
+		}
+		def.Nodes = append(def.Nodes, NodeDefinition{
+			Name:        node.Name,
+			Type:        nodeType,
+			Description: node.Description,
+		})
+	}
+
+	// Edges
+	for _, edge := range g.edges {
+		def.Edges = append(def.Edges, EdgeDefinition{From: edge.From, To: edge.To})
+	}
+
+	conditionalFrom := make([]string, 0, len(g.conditionalEdges))
+	for from := range g.conditionalEdges {
+		conditionalFrom = append(conditionalFrom, from)
+	}
+	sort.Strings(conditionalFrom)
+
+	for _, from := range conditionalFrom {
+		edge := EdgeDefinition{
+			From:      from,
+			Condition: from,
+			Paths:     g.pathMaps[from],
+		}
+		if g.definitions != nil {
+			if name, ok := g.definitions.conditions[from]; ok {
+				edge.Condition = name
+			}
+		}
+		def.Edges = append(def.Edges, edge)
+	}
+
+	// State reducers
+	if schema, ok := g.Schema.(*MapSchema); ok && len(schema.Reducers) > 0 {
+		def.State = make(map[string]string)
+		for key, reducer := range schema.Reducers {
+			if name := g.reducerName(key, reducer); name != "" {
+				def.State[key] = name
+			}
+		}
+	}
+
+	// Retry policy
+	if g.retryPolicy != nil {
+		def.RetryPolicy = &RetryPolicyDefinition{
+			MaxRetries:      g.retryPolicy.MaxRetries,
+			Backoff:         backoffNames[g.retryPolicy.BackoffStrategy],
+			RetryableErrors: g.retryPolicy.RetryableErrors,
+		}
+	}
+
+	return def
+}
+
+// reducerName returns the registry name of a reducer, if known
+func (g *StateGraph) reducerName(key string, reducer Reducer) string {
+	if g.definitions != nil {
+		if name, ok := g.definitions.state[key]; ok {
+			return name
+		}
+	}
+
+	// Fall back to identifying the built-in reducers
+	ptr := reflect.ValueOf(reducer).Pointer()
+	for name, builtin := range NewRegistry().reducers {
+		if reflect.ValueOf(builtin).Pointer() == ptr {
+			return name
+		}
+	}
+	return ""
+}
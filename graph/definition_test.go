@@ -0,0 +1,261 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const reviewGraphYAML = `
+name: review
+state:
+  messages: append
+retry_policy:
+  max_retries: 2
+  backoff: fixed
+  retryable_errors: ["temporary"]
+nodes:
+  - name: draft
+    description: Writes a draft
+  - name: check
+    function: checker
+    timeout: 1s
+    retry:
+      max_attempts: 3
+      initial_delay: 1ms
+  - name: publish
+edges:
+  - from: START
+    to: draft
+  - from: draft
+    to: check
+  - from: check
+    condition: route
+    paths:
+      ok: publish
+      retry: draft
+  - from: publish
+    to: END
+`
+
+func newReviewRegistry(checkCalls *int32) *Registry {
+	registry := NewRegistry()
+	registry.RegisterNode("draft", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return map[string]interface{}{"messages": []string{"draft"}}, nil
+	})
+	registry.RegisterNode("checker", func(ctx context.Context, state interface{}) (interface{}, error) {
+		if atomic.AddInt32(checkCalls, 1) == 1 {
+			return nil, errors.New("flaky")
+		}
+		return map[string]interface{}{"messages": []string{"checked"}}, nil
+	})
+	registry.RegisterNode("publish", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return map[string]interface{}{"messages": []string{"published"}}, nil
+	})
+	registry.RegisterCondition("route", func(ctx context.Context, state interface{}) string {
+		return "ok"
+	})
+	return registry
+}
+
+func TestGraphDefinition_BuildFromYAML(t *testing.T) {
+	def, err := ParseGraphDefinition([]byte(reviewGraphYAML))
+	assert.NoError(t, err)
+	assert.Equal(t, "review", def.Name)
+
+	var checkCalls int32
+	g, err := def.Build(newReviewRegistry(&checkCalls))
+	assert.NoError(t, err)
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	res, err := runnable.Invoke(context.Background(), map[string]interface{}{"messages": []string{}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"draft", "checked", "published"}, res.(map[string]interface{})["messages"])
+
+	// The node-level retry recovered from the first failure
+	assert.Equal(t, int32(2), atomic.LoadInt32(&checkCalls))
+}
+
+func TestGraphDefinition_LoadJSONFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.json")
+	err := os.WriteFile(path, []byte(`{
+  "entry_point": "a",
+  "nodes": [{"name": "a", "type": "process"}],
+  "edges": [{"from": "a", "to": "END"}]
+}`), 0o644)
+	assert.NoError(t, err)
+
+	registry := NewRegistry()
+	registry.RegisterNode("a", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return "done", nil
+	})
+
+	g, err := LoadStateGraph(path, registry)
+	assert.NoError(t, err)
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+	res, err := runnable.Invoke(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "done", res)
+}
+
+func TestGraphDefinition_BuildErrors(t *testing.T) {
+	registry := NewRegistry()
+	registry.RegisterNode("a", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return state, nil
+	})
+
+	tests := []struct {
+		name string
+		def  GraphDefinition
+	}{
+		{
+			name: "unregistered function",
+			def: GraphDefinition{
+				EntryPoint: "b",
+				Nodes:      []NodeDefinition{{Name: "b"}},
+			},
+		},
+		{
+			name: "unknown edge target",
+			def: GraphDefinition{
+				EntryPoint: "a",
+				Nodes:      []NodeDefinition{{Name: "a"}},
+				Edges:      []EdgeDefinition{{From: "a", To: "missing"}},
+			},
+		},
+		{
+			name: "unregistered condition",
+			def: GraphDefinition{
+				EntryPoint: "a",
+				Nodes:      []NodeDefinition{{Name: "a"}},
+				Edges:      []EdgeDefinition{{From: "a", Condition: "route"}},
+			},
+		},
+		{
+			name: "unregistered reducer",
+			def: GraphDefinition{
+				EntryPoint: "a",
+				State:      map[string]string{"messages": "custom"},
+				Nodes:      []NodeDefinition{{Name: "a"}},
+			},
+		},
+		{
+			name: "invalid timeout",
+			def: GraphDefinition{
+				EntryPoint: "a",
+				Nodes:      []NodeDefinition{{Name: "a", Timeout: "soon"}},
+			},
+		},
+		{
+			name: "missing entry point",
+			def: GraphDefinition{
+				Nodes: []NodeDefinition{{Name: "a"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.def.Build(registry)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestStateGraph_ExportRoundTrip(t *testing.T) {
+	def, err := ParseGraphDefinition([]byte(reviewGraphYAML))
+	assert.NoError(t, err)
+
+	var checkCalls int32
+	registry := newReviewRegistry(&checkCalls)
+	g, err := def.Build(registry)
+	assert.NoError(t, err)
+
+	exported := g.Export()
+	assert.Equal(t, "draft", exported.EntryPoint)
+	assert.Equal(t, map[string]string{"messages": "append"}, exported.State)
+	assert.Equal(t, "fixed", exported.RetryPolicy.Backoff)
+	assert.Len(t, exported.Nodes, 3)
+	assert.Equal(t, "checker", exported.Nodes[0].Function)
+	assert.Equal(t, "1s", exported.Nodes[0].Timeout)
+	assert.Contains(t, exported.Edges, EdgeDefinition{
+		From:      "check",
+		Condition: "route",
+		Paths:     map[string]string{"ok": "publish", "retry": "draft"},
+	})
+
+	data, err := exported.YAML()
+	assert.NoError(t, err)
+
+	reloaded, err := ParseGraphDefinition(data)
+	assert.NoError(t, err)
+	_, err = reloaded.Build(registry)
+	assert.NoError(t, err)
+}
+
+func TestStateGraph_ExportCodeGraph(t *testing.T) {
+	g := NewStateGraph()
+	schema := NewMapSchema()
+	schema.RegisterReducer("messages", AppendReducer)
+	g.SetSchema(schema)
+	g.AddNode("a", "first", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return state, nil
+	})
+	g.AddNode("b", "second", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return state, nil
+	})
+	g.AddConditionalEdges("a", func(ctx context.Context, state interface{}) string {
+		return "next"
+	}, map[string]string{"next": "b", "stop": END})
+	g.AddEdge("b", END)
+	g.SetEntryPoint("a")
+	g.SetRetryPolicy(&RetryPolicy{MaxRetries: 1, BackoffStrategy: LinearBackoff})
+
+	def := g.Export()
+	assert.Equal(t, []NodeDefinition{
+		{Name: "a", Type: "conditional", Description: "first"},
+		{Name: "b", Type: "process", Description: "second"},
+	}, def.Nodes)
+	assert.Equal(t, []EdgeDefinition{
+		{From: "b", To: END},
+		{From: "a", Condition: "a", Paths: map[string]string{"next": "b", "stop": END}},
+	}, def.Edges)
+	assert.Equal(t, map[string]string{"messages": "append"}, def.State)
+	assert.Equal(t, "linear", def.RetryPolicy.Backoff)
+
+	data, err := def.JSON()
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"entry_point": "a"`)
+
+	// Exported conditional edges route through the path map once rebuilt
+	registry := NewRegistry()
+	registry.RegisterNode("a", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return map[string]interface{}{"messages": []string{"a"}}, nil
+	})
+	registry.RegisterNode("b", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return map[string]interface{}{"messages": []string{"b"}}, nil
+	})
+	registry.RegisterCondition("a", func(ctx context.Context, state interface{}) string {
+		return "next"
+	})
+	rebuilt, err := def.Build(registry)
+	assert.NoError(t, err)
+	runnable, err := rebuilt.Compile()
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	res, err := runnable.Invoke(ctx, map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, res.(map[string]interface{})["messages"])
+}
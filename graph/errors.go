@@ -8,6 +8,8 @@ type NodeInterrupt struct {
 	Node string
 	// Value is the data/query provided by the interrupt
 	Value interface{}
+	// Subgraph is the interrupt raised inside a subgraph node (if any)
+	Subgraph *GraphInterrupt
 }
 
 func (e *NodeInterrupt) Error() string {
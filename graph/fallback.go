@@ -0,0 +1,125 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// NodeError is the state received by an error handler node when the node it handles
+// fails. The handler returns the state to continue with, e.g. a repaired state or a
+// canned response, and execution continues along the handler's own edges.
+type NodeError struct {
+	// Node is the name of the node that failed
+	Node string
+	// Err is the error returned by the node, after its retries were exhausted
+	Err error
+	// State is the state the failed node received
+	State interface{}
+}
+
+func (e *NodeError) Error() string {
+	return fmt.Sprintf("node %s failed: %v", e.Node, e.Err)
+}
+
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+// WithFallback routes the errors of the node to the handler node instead of failing
+// the run, like AddErrorEdge
+func WithFallback(handler string) NodeOption {
+	return func(n *Node) {
+		n.Fallback = handler
+	}
+}
+
+// AddErrorEdge routes the errors of the from node to the handler node instead of
+// failing the run. The handler receives a *NodeError as its state, and its result is
+// merged and routed as if the handler had run in place of the failed node.
+func (g *MessageGraph) AddErrorEdge(from, handler string) {
+	if g.errorEdges == nil {
+		g.errorEdges = make(map[string]string)
+	}
+	g.errorEdges[from] = handler
+}
+
+// errorHandler returns the error handler of a node
+func (g *MessageGraph) errorHandler(name string) string {
+	return errorHandlerOf(g.errorEdges, g.nodes, name)
+}
+
+// AddErrorEdge routes the errors of the from node to the handler node instead of
+// failing the run. The handler receives a *NodeError as its state, and its result is
+// merged and routed as if the handler had run in place of the failed node.
+func (g *StateGraph) AddErrorEdge(from, handler string) {
+	if g.errorEdges == nil {
+		g.errorEdges = make(map[string]string)
+	}
+	g.errorEdges[from] = handler
+}
+
+// errorHandler returns the error handler of a node
+func (g *StateGraph) errorHandler(name string) string {
+	return errorHandlerOf(g.errorEdges, g.nodes, name)
+}
+
+func errorHandlerOf(errorEdges map[string]string, nodes map[string]Node, name string) string {
+	if handler, ok := errorEdges[name]; ok {
+		return handler
+	}
+	return nodes[name].Fallback
+}
+
+// validateErrorHandlers checks that the error handlers of the nodes of a graph exist
+// and differ from the nodes they handle
+func validateErrorHandlers(errorEdges map[string]string, nodes map[string]Node) error {
+	names := make([]string, 0, len(nodes))
+	for name := range nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		handler := errorHandlerOf(errorEdges, nodes, name)
+		if handler == "" {
+			continue
+		}
+		if handler == name {
+			return fmt.Errorf("node %s cannot be its own error handler", name)
+		}
+		if _, ok := nodes[handler]; !ok {
+			return fmt.Errorf("error handler of node %s: %w: %s", name, ErrNodeNotFound, handler)
+		}
+	}
+	return nil
+}
+
+// handleableError reports whether a node error may be routed to an error handler.
+// Interrupts and cancellations of the run are never handled.
+func handleableError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var nodeInterrupt *NodeInterrupt
+	var graphInterrupt *GraphInterrupt
+	return !errors.As(err, &nodeInterrupt) && !errors.As(err, &graphInterrupt)
+}
+
+// runErrorHandler executes fn, the error handler of a failed node
+func runErrorHandler(ctx context.Context, handler string, fn func(context.Context, interface{}) (interface{}, error), failed string, state interface{}, nodeErr error) (interface{}, error) {
+	if fn == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, handler)
+	}
+	notifyRoute(ctx, failed, handler)
+
+	input := &NodeError{Node: failed, Err: nodeErr, State: state}
+	nodeCtx, endNodeRun := startNodeRun(ctx, handler, input)
+	res, err := fn(nodeCtx, input)
+	endNodeRun(res, err)
+	if err != nil {
+		return nil, fmt.Errorf("error in error handler %s of node %s: %w", handler, failed, err)
+	}
+	return res, nil
+}
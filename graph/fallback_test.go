@@ -0,0 +1,199 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// routeRecorder records the routing decisions reported to the callbacks
+type routeRecorder struct {
+	NoOpCallbackHandler
+	routes [][2]string
+}
+
+func (r *routeRecorder) OnRoute(ctx context.Context, from string, to []string) {
+	for _, target := range to {
+		r.routes = append(r.routes, [2]string{from, target})
+	}
+}
+
+var errUpstream = errors.New("upstream unavailable")
+
+func failingNode(ctx context.Context, state interface{}) (interface{}, error) {
+	return nil, errUpstream
+}
+
+func TestErrorEdge_MessageGraph(t *testing.T) {
+	var received *NodeError
+	g := NewMessageGraph()
+	g.AddNode("primary", "primary", failingNode, WithRetry(RetryConfig{MaxAttempts: 2}))
+	g.AddNode("cheap", "cheap", func(ctx context.Context, state interface{}) (interface{}, error) {
+		received = state.(*NodeError)
+		return "canned response", nil
+	})
+	g.AddNode("finish", "finish", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return state.(string) + "!", nil
+	})
+	g.SetEntryPoint("primary")
+	g.AddEdge("primary", END)
+	g.AddErrorEdge("primary", "cheap")
+	g.AddEdge("cheap", "finish")
+	g.AddEdge("finish", END)
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	routes := &routeRecorder{}
+	result, err := runnable.InvokeWithConfig(context.Background(), "question", &Config{
+		Callbacks: []CallbackHandler{routes},
+	})
+	assert.NoError(t, err)
+	// The handler continues along its own edges, not those of the failed node
+	assert.Equal(t, "canned response!", result)
+
+	assert.Equal(t, "primary", received.Node)
+	assert.Equal(t, "question", received.State)
+	assert.ErrorIs(t, received, errUpstream)
+	var exhausted *RetryExhaustedError
+	assert.ErrorAs(t, received.Err, &exhausted)
+	assert.Equal(t, 2, exhausted.Attempts)
+	assert.Contains(t, routes.routes, [2]string{"primary", "cheap"})
+}
+
+func TestErrorEdge_StateGraphFallback(t *testing.T) {
+	g := NewStateGraph()
+	g.AddNode("start", "start", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return map[string]interface{}{"query": "q"}, nil
+	})
+	g.AddNode("llm", "llm", failingNode, WithFallback("repair"))
+	g.AddNode("search", "search", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return map[string]interface{}{"search": "results"}, nil
+	})
+	g.AddNode("repair", "repair", func(ctx context.Context, state interface{}) (interface{}, error) {
+		nodeErr := state.(*NodeError)
+		return map[string]interface{}{"answer": "fallback for " + nodeErr.State.(map[string]interface{})["query"].(string)}, nil
+	})
+	g.SetEntryPoint("start")
+	g.AddEdge("start", "llm")
+	g.AddEdge("start", "search")
+	g.AddEdge("llm", END)
+	g.AddEdge("search", END)
+	g.AddEdge("repair", END)
+
+	schema := NewMapSchema()
+	g.SetSchema(schema)
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	result, err := runnable.Invoke(context.Background(), map[string]interface{}{})
+	assert.NoError(t, err)
+	state := result.(map[string]interface{})
+	assert.Equal(t, "fallback for q", state["answer"])
+	assert.Equal(t, "results", state["search"])
+}
+
+func TestErrorEdge_HandlerFailure(t *testing.T) {
+	g := NewStateGraph()
+	g.AddNode("primary", "primary", failingNode)
+	g.AddNode("handler", "handler", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return nil, errors.New("handler broke")
+	})
+	g.SetEntryPoint("primary")
+	g.AddEdge("primary", END)
+	g.AddEdge("handler", END)
+	g.AddErrorEdge("primary", "handler")
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	_, err = runnable.Invoke(context.Background(), "state")
+	assert.EqualError(t, err, "error in error handler handler of node primary: handler broke")
+
+}
+
+func TestErrorEdge_InvalidHandlers(t *testing.T) {
+	tests := []struct {
+		name    string
+		build   func(g *StateGraph)
+		wantErr string
+	}{
+		{
+			name:    "missing error edge handler",
+			build:   func(g *StateGraph) { g.AddErrorEdge("primary", "missing") },
+			wantErr: "error handler of node primary: node not found: missing",
+		},
+		{
+			name: "missing fallback",
+			build: func(g *StateGraph) {
+				g.AddNode("other", "other", failingNode, WithFallback("missing"))
+				g.AddEdge("other", END)
+			},
+			wantErr: "error handler of node other: node not found: missing",
+		},
+		{
+			name:    "node handling itself",
+			build:   func(g *StateGraph) { g.AddErrorEdge("primary", "primary") },
+			wantErr: "node primary cannot be its own error handler",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewStateGraph()
+			g.AddNode("primary", "primary", failingNode)
+			g.SetEntryPoint("primary")
+			g.AddEdge("primary", END)
+			tt.build(g)
+
+			_, err := g.Compile()
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestErrorEdge_InterruptsAreNotHandled(t *testing.T) {
+	handled := false
+	g := NewMessageGraph()
+	g.AddNode("ask", "ask", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return Interrupt(ctx, "confirm?")
+	}, WithFallback("handler"))
+	g.AddNode("handler", "handler", func(ctx context.Context, state interface{}) (interface{}, error) {
+		handled = true
+		return state, nil
+	})
+	g.SetEntryPoint("ask")
+	g.AddEdge("ask", END)
+	g.AddEdge("handler", END)
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	_, err = runnable.Invoke(context.Background(), "state")
+	var interrupt *GraphInterrupt
+	assert.ErrorAs(t, err, &interrupt)
+	assert.False(t, handled)
+}
+
+func TestErrorEdge_ListenableGraph(t *testing.T) {
+	var events []string
+	listener := NodeListenerFunc(func(ctx context.Context, event NodeEvent, nodeName string, state interface{}, err error) {
+		events = append(events, nodeName+":"+string(event))
+	})
+
+	g := NewListenableMessageGraph()
+	g.AddNode("primary", "primary", failingNode).AddListener(listener)
+	g.AddNode("handler", "handler", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return "recovered from " + state.(*NodeError).Node, nil
+	}).AddListener(listener)
+	g.SetEntryPoint("primary")
+	g.AddEdge("primary", END)
+	g.AddEdge("handler", END)
+	g.AddErrorEdge("primary", "handler")
+	runnable, err := g.CompileListenable()
+	assert.NoError(t, err)
+
+	result, err := runnable.Invoke(context.Background(), "state")
+	assert.NoError(t, err)
+	assert.Equal(t, "recovered from primary", result)
+	assert.Equal(t, []string{"primary:start", "primary:error", "handler:start", "handler:complete"}, events)
+}
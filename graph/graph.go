@@ -5,11 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 )
 
 // END is a special constant used to represent the end node in the graph.
 const END = "END"
 
+// START is a special constant used in graph definitions to represent the entry of the graph.
+// An edge from START marks its target as the entry point.
+const START = "START"
+
 var (
 	// ErrEntryPointNotSet is returned when the entry point of the graph is not set.
 	ErrEntryPointNotSet = errors.New("entry point not set")
@@ -31,15 +36,28 @@ type GraphInterrupt struct {
 	NextNodes []string
 	// InterruptValue is the value provided by the dynamic interrupt (if any)
 	InterruptValue interface{}
+	// Subgraph is the nested interrupt when Node is a subgraph that was interrupted
+	Subgraph *GraphInterrupt
+	// Shutdown reports that the run was stopped by Runtime.Shutdown
+	Shutdown bool
 }
 
 func (e *GraphInterrupt) Error() string {
+	if e.Shutdown {
+		return fmt.Sprintf("graph interrupted before node %s by shutdown", e.Node)
+	}
 	if e.InterruptValue != nil {
 		return fmt.Sprintf("graph interrupted at node %s with value: %v", e.Node, e.InterruptValue)
 	}
 	return fmt.Sprintf("graph interrupted at node %s", e.Node)
 }
 
+// Is reports whether target is ErrShutdown and the run or its interrupted subgraph
+// was stopped by a shutdown
+func (e *GraphInterrupt) Is(target error) bool {
+	return target == ErrShutdown && (e.Shutdown || (e.Subgraph != nil && e.Subgraph.Is(target)))
+}
+
 // Interrupt pauses execution and waits for input.
 // If resuming, it returns the value provided in the resume command.
 func Interrupt(ctx context.Context, value interface{}) (interface{}, error) {
@@ -60,6 +78,40 @@ type Node struct {
 	// Function is the function associated with the node.
 	// It takes a context and any state as input and returns the updated state and an error.
 	Function func(ctx context.Context, state interface{}) (interface{}, error)
+
+	// CachePolicy enables result caching for the node when the graph has a Cache.
+	CachePolicy *CachePolicy
+
+	// Retry, Timeout, RateLimit and CircuitBreaker configure resilience policies
+	// wrapped around Function when the node is added to a graph.
+	Retry          *RetryConfig
+	Timeout        time.Duration
+	RateLimit      *RateLimitConfig
+	CircuitBreaker *CircuitBreakerConfig
+
+	// Fallback is the node that handles the errors of this node (see WithFallback)
+	Fallback string
+
+	// Priority orders the nodes waiting for a concurrency slot (see WithPriority)
+	Priority int
+
+	// MaxConcurrency limits the members of a group node running at once (see WithMaxConcurrency)
+	MaxConcurrency int
+}
+
+// newNode creates a node, applying its options and wrapping its function with the
+// resilience policies they configure
+func newNode(name string, description string, fn func(ctx context.Context, state interface{}) (interface{}, error), opts []NodeOption) Node {
+	node := Node{
+		Name:        name,
+		Description: description,
+		Function:    fn,
+	}
+	for _, opt := range opts {
+		opt(&node)
+	}
+	node.Function = withResilience(node)
+	return node
 }
 
 // Edge represents an edge in the message graph.
@@ -93,6 +145,22 @@ type MessageGraph struct {
 
 	// Schema defines the state structure and update logic
 	Schema StateSchema
+
+	// cache stores node results for nodes with a CachePolicy
+	cache Cache
+
+	// subgraphs records the nodes that were added as subgraphs
+	subgraphs map[string]*Subgraph
+
+	// errorEdges maps nodes to the handler nodes that receive their errors
+	errorEdges map[string]string
+
+	// nodeOrder is the order of the nodes of each superstep
+	nodeOrder NodeOrder
+
+	// isolation and stateCloner configure the copies of the state handed to nodes
+	isolation   StateIsolation
+	stateCloner StateCloner
 }
 
 // NewMessageGraph creates a new instance of MessageGraph.
@@ -100,16 +168,14 @@ func NewMessageGraph() *MessageGraph {
 	return &MessageGraph{
 		nodes:            make(map[string]Node),
 		conditionalEdges: make(map[string]func(ctx context.Context, state interface{}) string),
+		subgraphs:        make(map[string]*Subgraph),
 	}
 }
 
 // AddNode adds a new node to the message graph with the given name, description and function.
-func (g *MessageGraph) AddNode(name string, description string, fn func(ctx context.Context, state interface{}) (interface{}, error)) {
-	g.nodes[name] = Node{
-		Name:        name,
-		Description: description,
-		Function:    fn,
-	}
+// Optional NodeOptions configure additional behavior such as result caching, retries and timeouts.
+func (g *MessageGraph) AddNode(name string, description string, fn func(ctx context.Context, state interface{}) (interface{}, error), opts ...NodeOption) {
+	g.nodes[name] = newNode(name, description, fn, opts)
 }
 
 // AddEdge adds a new edge to the message graph between the "from" and "to" nodes.
@@ -141,6 +207,11 @@ func (g *MessageGraph) SetSchema(schema StateSchema) {
 	g.Schema = schema
 }
 
+// SetCache sets the cache backend used by nodes that have a CachePolicy.
+func (g *MessageGraph) SetCache(cache Cache) {
+	g.cache = cache
+}
+
 // Runnable represents a compiled message graph that can be invoked.
 type Runnable struct {
 	// graph is the underlying MessageGraph object.
@@ -155,6 +226,12 @@ func (g *MessageGraph) Compile() (*Runnable, error) {
 	if g.entryPoint == "" {
 		return nil, ErrEntryPointNotSet
 	}
+	if err := validateNodes(g.nodes); err != nil {
+		return nil, err
+	}
+	if err := validateErrorHandlers(g.errorEdges, g.nodes); err != nil {
+		return nil, err
+	}
 
 	return &Runnable{
 		graph:  g,
@@ -184,6 +261,12 @@ func (r *Runnable) Invoke(ctx context.Context, initialState interface{}) (interf
 // InvokeWithConfig executes the compiled message graph with the given input state and config.
 // It returns the resulting state and an error if any occurs during the execution.
 func (r *Runnable) InvokeWithConfig(ctx context.Context, initialState interface{}, config *Config) (interface{}, error) {
+	run, ctx, err := startRun(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer run.end()
+
 	state := initialState
 	currentNodes := []string{r.graph.entryPoint}
 
@@ -192,14 +275,17 @@ func (r *Runnable) InvokeWithConfig(ctx context.Context, initialState interface{
 		currentNodes = config.ResumeFrom
 	}
 
+	// Inject config into context before the run ID, which logs its thread
+	if config != nil {
+		ctx = WithConfig(ctx, config)
+	}
+
 	// Generate run ID for callbacks
 	runID := generateRunID()
+	ctx = withRunID(ctx, runID)
 
 	// Notify callbacks of graph start
 	if config != nil {
-		// Inject config into context
-		ctx = WithConfig(ctx, config)
-
 		// Inject ResumeValue
 		if config.ResumeValue != nil {
 			ctx = WithResumeValue(ctx, config.ResumeValue)
@@ -213,11 +299,17 @@ func (r *Runnable) InvokeWithConfig(ctx context.Context, initialState interface{
 			inputs := convertStateToMap(initialState)
 
 			for _, cb := range config.Callbacks {
-				cb.OnChainStart(ctx, serialized, inputs, runID, nil, config.Tags, config.Metadata)
+				cb.OnChainStart(ctx, serialized, inputs, runID, ParentRunIDFromContext(ctx), config.Tags, config.Metadata)
 			}
+			ctx = WithParentRunID(ctx, runID)
 		}
 	}
 
+	// Limit the nodes running at once
+	ctx, reacquireSlot := withRunScheduler(ctx, config)
+	defer reacquireSlot()
+	ctx = withStateCloner(ctx, r.graph.isolation.cloner(r.graph.stateCloner))
+
 	// Start graph tracing if tracer is set
 	var graphSpan *TraceSpan
 	if r.tracer != nil {
@@ -254,6 +346,8 @@ func (r *Runnable) InvokeWithConfig(ctx context.Context, initialState interface{
 		var wg sync.WaitGroup
 		results := make([]interface{}, len(currentNodes))
 		errorsList := make([]error, len(currentNodes))
+		// handledBy records the error handlers that ran in place of failed nodes
+		handledBy := make([]string, len(currentNodes))
 
 		for i, nodeName := range currentNodes {
 			node, ok := r.graph.nodes[nodeName]
@@ -265,6 +359,21 @@ func (r *Runnable) InvokeWithConfig(ctx context.Context, initialState interface{
 			go func(index int, n Node, name string) {
 				defer wg.Done()
 
+				// Wait for a concurrency slot of the run
+				ctx, slot, err := acquireSlot(ctx, n.Priority)
+				if err != nil {
+					errorsList[index] = fmt.Errorf("error in node %s: %w", name, err)
+					return
+				}
+				defer slot.release()
+
+				// Give the node its own copy of the state when isolation is enabled
+				state, err := isolateState(ctx, state)
+				if err != nil {
+					errorsList[index] = fmt.Errorf("error in node %s: %w", name, err)
+					return
+				}
+
 				// Start node tracing
 				var nodeSpan *TraceSpan
 				if r.tracer != nil {
@@ -272,12 +381,20 @@ func (r *Runnable) InvokeWithConfig(ctx context.Context, initialState interface{
 					nodeSpan.State = state
 				}
 
-				var err error
-				var res interface{}
+				// Notify callbacks of node execution (as tool), nesting the calls made
+				// by the node under its run
+				nodeCtx, endNodeRun := startNodeRun(ctx, name, state)
+				if nodeSpan != nil {
+					nodeCtx = ContextWithSpan(nodeCtx, nodeSpan)
+				}
 
-				// Pass the current state to the node
-				// Note: If state is mutable and shared, this is not thread-safe unless handled by user.
-				res, err = n.Function(ctx, state)
+				// Pass the state to the node; it is shared with the other nodes of the
+				// superstep unless the graph isolates it (see SetStateIsolation)
+				res, cacheHit, err := executeWithCache(nodeCtx, r.graph.cache, n, state, n.Function)
+				endNodeRun(res, err)
+				if cacheHit && nodeSpan != nil {
+					nodeSpan.Metadata["cache_hit"] = true
+				}
 
 				// End node tracing
 				if r.tracer != nil && nodeSpan != nil {
@@ -294,6 +411,18 @@ func (r *Runnable) InvokeWithConfig(ctx context.Context, initialState interface{
 				}
 
 				if err != nil {
+					// Route the error to the handler of the node, if any
+					if handler := r.graph.errorHandler(name); handler != "" && handleableError(ctx, err) {
+						res, err = runErrorHandler(ctx, handler, r.graph.nodes[handler].Function, name, state, err)
+						if err != nil {
+							errorsList[index] = err
+							return
+						}
+						handledBy[index] = handler
+						results[index] = res
+						return
+					}
+
 					var nodeInterrupt *NodeInterrupt
 					if errors.As(err, &nodeInterrupt) {
 						nodeInterrupt.Node = name
@@ -303,27 +432,25 @@ func (r *Runnable) InvokeWithConfig(ctx context.Context, initialState interface{
 				}
 
 				results[index] = res
-
-				// Notify callbacks of node execution (as tool)
-				if config != nil && len(config.Callbacks) > 0 {
-					nodeRunID := generateRunID()
-					serialized := map[string]interface{}{
-						"name": name,
-						"type": "tool",
-					}
-					for _, cb := range config.Callbacks {
-						cb.OnToolStart(ctx, serialized, convertStateToString(res), nodeRunID, &runID, config.Tags, config.Metadata)
-						cb.OnToolEnd(ctx, convertStateToString(res), nodeRunID)
-					}
-				}
 			}(i, node, nodeName)
 		}
 
 		wg.Wait()
 
+		// Continue from the error handlers that replaced failed nodes
+		for i, handler := range handledBy {
+			if handler != "" {
+				currentNodes[i] = handler
+			}
+		}
+
 		// Check for errors
 		for _, err := range errorsList {
 			if err != nil {
+				// Run the superstep again on resume when Shutdown cancelled it
+				if interrupt := run.cancelled(ctx, state, currentNodes); interrupt != nil {
+					return state, interrupt
+				}
 				// Check for NodeInterrupt
 				var nodeInterrupt *NodeInterrupt
 				if errors.As(err, &nodeInterrupt) {
@@ -332,6 +459,7 @@ func (r *Runnable) InvokeWithConfig(ctx context.Context, initialState interface{
 						State:          state,
 						InterruptValue: nodeInterrupt.Value,
 						NextNodes:      []string{nodeInterrupt.Node},
+						Subgraph:       nodeInterrupt.Subgraph,
 					}
 				}
 
@@ -358,8 +486,10 @@ func (r *Runnable) InvokeWithConfig(ctx context.Context, initialState interface{
 					switch g := cmd.Goto.(type) {
 					case string:
 						nextNodesFromCommands = append(nextNodesFromCommands, g)
+						notifyRoute(ctx, currentNodes[i], g)
 					case []string:
 						nextNodesFromCommands = append(nextNodesFromCommands, g...)
+						notifyRoute(ctx, currentNodes[i], g...)
 					}
 				}
 			} else {
@@ -396,17 +526,16 @@ func (r *Runnable) InvokeWithConfig(ctx context.Context, initialState interface{
 
 		if len(nextNodesFromCommands) > 0 {
 			// Command.Goto overrides static edges
-			// We deduplicate
-			seen := make(map[string]bool)
+			var nextNodesSet nodeSet
 			for _, n := range nextNodesFromCommands {
-				if !seen[n] && n != END {
-					seen[n] = true
-					nextNodesList = append(nextNodesList, n)
+				if n != END {
+					nextNodesSet.add(n)
 				}
 			}
+			nextNodesList = nextNodesSet.list(r.graph.nodeOrder)
 		} else {
 			// Use static edges
-			nextNodesSet := make(map[string]bool)
+			var nextNodesSet nodeSet
 
 			for _, nodeName := range currentNodes {
 				// First check for conditional edges
@@ -416,13 +545,14 @@ func (r *Runnable) InvokeWithConfig(ctx context.Context, initialState interface{
 					if nextNode == "" {
 						return nil, fmt.Errorf("conditional edge returned empty next node from %s", nodeName)
 					}
-					nextNodesSet[nextNode] = true
+					notifyRoute(ctx, nodeName, nextNode)
+					nextNodesSet.add(nextNode)
 				} else {
 					// Then check regular edges
 					foundNext := false
 					for _, edge := range r.graph.edges {
 						if edge.From == nodeName {
-							nextNodesSet[edge.To] = true
+							nextNodesSet.add(edge.To)
 							foundNext = true
 							// Do NOT break here, to allow fan-out (multiple edges from same node)
 						}
@@ -434,10 +564,8 @@ func (r *Runnable) InvokeWithConfig(ctx context.Context, initialState interface{
 				}
 			}
 
-			// Update currentNodes
-			for node := range nextNodesSet {
-				nextNodesList = append(nextNodesList, node)
-			}
+			// Update currentNodes in a stable order
+			nextNodesList = nextNodesSet.list(r.graph.nodeOrder)
 		}
 
 		// Check InterruptAfter
@@ -476,6 +604,21 @@ func (r *Runnable) InvokeWithConfig(ctx context.Context, initialState interface{
 				}
 			}
 		}
+
+		// Stop the run if a callback guard (e.g. a usage budget) says so
+		if err := checkRunGuards(ctx); err != nil {
+			if config != nil {
+				for _, cb := range config.Callbacks {
+					cb.OnChainError(ctx, err, runID)
+				}
+			}
+			return state, err
+		}
+
+		// Stop between supersteps when the runtime is shutting down
+		if err := run.interrupt(state, currentNodes); err != nil {
+			return state, err
+		}
 	}
 
 	// End graph tracing
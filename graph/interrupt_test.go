@@ -61,3 +61,35 @@ func TestGraphInterrupt(t *testing.T) {
 		assert.Equal(t, "StartAB", res)
 	})
 }
+
+func TestStateGraphInterrupt(t *testing.T) {
+	g := NewStateGraph()
+	for _, name := range []string{"A", "B", "C"} {
+		g.AddNode(name, name, func(ctx context.Context, state interface{}) (interface{}, error) {
+			return state.(string) + name, nil
+		})
+	}
+	g.SetEntryPoint("A")
+	g.AddEdge("A", "B")
+	g.AddEdge("B", "C")
+	g.AddEdge("C", END)
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	res, err := runnable.InvokeWithConfig(context.Background(), "Start", &Config{InterruptBefore: []string{"B"}})
+	var interrupt *GraphInterrupt
+	assert.ErrorAs(t, err, &interrupt)
+	assert.Equal(t, "B", interrupt.Node)
+	assert.Equal(t, "StartA", res)
+
+	res, err = runnable.InvokeWithConfig(context.Background(), "Start", &Config{InterruptAfter: []string{"B"}})
+	assert.ErrorAs(t, err, &interrupt)
+	assert.Equal(t, []string{"C"}, interrupt.NextNodes)
+	assert.Equal(t, "StartAB", res)
+
+	// Resuming continues from the next nodes
+	res, err = runnable.InvokeWithConfig(context.Background(), res, &Config{ResumeFrom: interrupt.NextNodes})
+	assert.NoError(t, err)
+	assert.Equal(t, "StartABC", res)
+}
@@ -0,0 +1,213 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// StateIsolation controls whether the nodes of a superstep, and the members of
+// parallel groups, receive their own copy of the state instead of sharing it
+type StateIsolation int
+
+const (
+	// IsolationNone hands the same state value to every node. Nodes must not
+	// mutate it when they can run in parallel. This is the default.
+	IsolationNone StateIsolation = iota
+	// IsolationShallow hands each node a copy of the top level of the state: the
+	// entries of a map or slice, or the struct behind a pointer
+	IsolationShallow
+	// IsolationDeep hands each node a recursive copy of the state
+	IsolationDeep
+)
+
+// StateCloner returns a copy of the state for a node to use
+type StateCloner func(state interface{}) (interface{}, error)
+
+// SetStateIsolation sets how the state is copied for each node
+func (g *MessageGraph) SetStateIsolation(isolation StateIsolation) {
+	g.isolation = isolation
+}
+
+// SetStateCloner sets the function copying the state for each node. It replaces
+// the copy made for the StateIsolation, and copies the state even with IsolationNone.
+func (g *MessageGraph) SetStateCloner(cloner StateCloner) {
+	g.stateCloner = cloner
+}
+
+// SetStateIsolation sets how the state is copied for each node
+func (g *StateGraph) SetStateIsolation(isolation StateIsolation) {
+	g.isolation = isolation
+}
+
+// SetStateCloner sets the function copying the state for each node. It replaces
+// the copy made for the StateIsolation, and copies the state even with IsolationNone.
+func (g *StateGraph) SetStateCloner(cloner StateCloner) {
+	g.stateCloner = cloner
+}
+
+// ShallowCopy copies the top level of a state: the entries of maps and slices and
+// the value behind pointers. Nested values are shared with the original.
+func ShallowCopy(state interface{}) (interface{}, error) {
+	if state == nil {
+		return nil, nil
+	}
+	v := reflect.ValueOf(state)
+	switch v.Kind() {
+	case reflect.Map:
+		if v.IsNil() {
+			return state, nil
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), iter.Value())
+		}
+		return out.Interface(), nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return state, nil
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(out, v)
+		return out.Interface(), nil
+	case reflect.Ptr:
+		if v.IsNil() {
+			return state, nil
+		}
+		out := reflect.New(v.Elem().Type())
+		out.Elem().Set(v.Elem())
+		return out.Interface(), nil
+	default:
+		// Other values are copied when passed around
+		return state, nil
+	}
+}
+
+// DeepCopy recursively copies maps, slices, arrays, pointers, interfaces and the
+// exported fields of structs, preserving shared and cyclic references. Unexported
+// fields, channels and functions are shared with the original.
+func DeepCopy(state interface{}) (interface{}, error) {
+	if state == nil {
+		return nil, nil
+	}
+	c := &deepCopier{seen: make(map[deepCopyKey]reflect.Value)}
+	return c.copy(reflect.ValueOf(state)).Interface(), nil
+}
+
+// deepCopyKey identifies a map, slice or pointer that was already copied
+type deepCopyKey struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+type deepCopier struct {
+	seen map[deepCopyKey]reflect.Value
+}
+
+func (c *deepCopier) copy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		key := deepCopyKey{ptr: v.Pointer(), typ: v.Type()}
+		if out, ok := c.seen[key]; ok {
+			return out
+		}
+		out := reflect.New(v.Elem().Type())
+		c.seen[key] = out
+		out.Elem().Set(c.copy(v.Elem()))
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		key := deepCopyKey{ptr: v.Pointer(), typ: v.Type()}
+		if out, ok := c.seen[key]; ok {
+			return out
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		c.seen[key] = out
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(c.copy(iter.Key()), c.copy(iter.Value()))
+		}
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		key := deepCopyKey{ptr: v.Pointer(), typ: v.Type(), len: v.Len()}
+		if out, ok := c.seen[key]; ok {
+			return out
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		c.seen[key] = out
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(c.copy(v.Index(i)))
+		}
+		return out
+	case reflect.Array:
+		out := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(c.copy(v.Index(i)))
+		}
+		return out
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(c.copy(v.Elem()))
+		return out
+	case reflect.Struct:
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if out.Field(i).CanSet() {
+				out.Field(i).Set(c.copy(v.Field(i)))
+			}
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// cloner returns the function copying the state for each node of the graph, or nil
+func (isolation StateIsolation) cloner(custom StateCloner) StateCloner {
+	if custom != nil {
+		return custom
+	}
+	switch isolation {
+	case IsolationShallow:
+		return ShallowCopy
+	case IsolationDeep:
+		return DeepCopy
+	default:
+		return nil
+	}
+}
+
+type stateClonerKey struct{}
+
+// withStateCloner sets the function copying the state for the nodes of the run,
+// including the members of its parallel groups
+func withStateCloner(ctx context.Context, cloner StateCloner) context.Context {
+	return context.WithValue(ctx, stateClonerKey{}, cloner)
+}
+
+// isolateState returns the copy of the state that a node of the run should use
+func isolateState(ctx context.Context, state interface{}) (interface{}, error) {
+	cloner, _ := ctx.Value(stateClonerKey{}).(StateCloner)
+	if cloner == nil {
+		return state, nil
+	}
+	clone, err := cloner(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy state: %w", err)
+	}
+	return clone, nil
+}
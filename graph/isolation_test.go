@@ -0,0 +1,187 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mutatingNode writes its name into the state it receives, at the top level and in
+// the nested "seen" map, and fails if it sees the writes of another node
+func mutatingNode(name string, nested bool) func(context.Context, interface{}) (interface{}, error) {
+	return func(ctx context.Context, state interface{}) (interface{}, error) {
+		m := state.(map[string]interface{})
+		if owner, ok := m["owner"]; ok {
+			return nil, fmt.Errorf("%s sees the state of %v", name, owner)
+		}
+		m["owner"] = name
+		if nested {
+			seen := m["seen"].(map[string]interface{})
+			if len(seen) > 0 {
+				return nil, fmt.Errorf("%s sees the nested state of %v", name, seen)
+			}
+			seen[name] = true
+		}
+		return map[string]interface{}{"done": []string{name}}, nil
+	}
+}
+
+func TestStateIsolation_Superstep(t *testing.T) {
+	tests := []struct {
+		name      string
+		isolation StateIsolation
+		nested    bool
+	}{
+		{name: "Shallow", isolation: IsolationShallow},
+		{name: "Deep", isolation: IsolationDeep, nested: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := NewMapSchema()
+			schema.RegisterReducer("done", AppendReducer)
+			g := NewStateGraph()
+			g.SetSchema(schema)
+			g.SetStateIsolation(tt.isolation)
+			g.AddNode("start", "start", func(ctx context.Context, state interface{}) (interface{}, error) {
+				return map[string]interface{}{}, nil
+			})
+			g.SetEntryPoint("start")
+			for i := 0; i < 8; i++ {
+				name := fmt.Sprintf("worker_%d", i)
+				g.AddNode(name, name, mutatingNode(name, tt.nested))
+				g.AddEdge("start", name)
+				g.AddEdge(name, END)
+			}
+			runnable, err := g.Compile()
+			assert.NoError(t, err)
+
+			input := map[string]interface{}{"seen": map[string]interface{}{}}
+			result, err := runnable.Invoke(context.Background(), input)
+			assert.NoError(t, err)
+			assert.Len(t, result.(map[string]interface{})["done"], 8)
+			assert.NotContains(t, result, "owner")
+			assert.Empty(t, input["seen"])
+		})
+	}
+}
+
+func TestStateIsolation_ParallelGroup(t *testing.T) {
+	members := make(map[string]func(context.Context, interface{}) (interface{}, error))
+	for i := 0; i < 8; i++ {
+		name := fmt.Sprintf("member_%d", i)
+		members[name] = mutatingNode(name, false)
+	}
+
+	g := NewMessageGraph()
+	g.SetStateIsolation(IsolationShallow)
+	g.AddParallelNodes("group", members)
+	g.SetEntryPoint("group")
+	g.AddEdge("group", END)
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	input := map[string]interface{}{}
+	result, err := runnable.Invoke(context.Background(), input)
+	assert.NoError(t, err)
+	assert.Len(t, result, 8)
+	assert.Empty(t, input)
+}
+
+func TestStateIsolation_CustomCloner(t *testing.T) {
+	var clones int32
+	g := NewMessageGraph()
+	g.SetStateCloner(func(state interface{}) (interface{}, error) {
+		atomic.AddInt32(&clones, 1)
+		data, err := json.Marshal(state)
+		if err != nil {
+			return nil, err
+		}
+		var clone map[string]interface{}
+		err = json.Unmarshal(data, &clone)
+		return clone, err
+	})
+	g.SetStateMerger(func(ctx context.Context, current interface{}, states []interface{}) (interface{}, error) {
+		return current, nil
+	})
+	g.AddNode("start", "start", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return state, nil
+	})
+	g.SetEntryPoint("start")
+	for _, name := range []string{"a", "b"} {
+		g.AddNode(name, name, mutatingNode(name, true))
+		g.AddEdge("start", name)
+		g.AddEdge(name, END)
+	}
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	input := map[string]interface{}{"seen": map[string]interface{}{}}
+	_, err = runnable.Invoke(context.Background(), input)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&clones))
+	assert.Empty(t, input["seen"])
+
+	// Cloner errors fail the node
+	_, err = runnable.Invoke(context.Background(), map[string]interface{}{"bad": func() {}})
+	assert.ErrorContains(t, err, "error in node start: failed to copy state")
+}
+
+type document struct {
+	Title  string
+	Tags   []string
+	Parent *document
+	Meta   map[string]interface{}
+	secret []int
+}
+
+func TestDeepCopy(t *testing.T) {
+	root := &document{Title: "root", Tags: []string{"a"}, secret: []int{1}}
+	root.Parent = root
+	root.Meta = map[string]interface{}{"self": root, "list": []interface{}{map[string]int{"n": 1}}}
+
+	out, err := DeepCopy(root)
+	assert.NoError(t, err)
+	clone := out.(*document)
+	assert.NotSame(t, root, clone)
+	assert.Same(t, clone, clone.Parent)
+	assert.Same(t, clone, clone.Meta["self"])
+
+	clone.Tags[0] = "changed"
+	clone.Meta["list"].([]interface{})[0].(map[string]int)["n"] = 2
+	assert.Equal(t, "a", root.Tags[0])
+	assert.Equal(t, 1, root.Meta["list"].([]interface{})[0].(map[string]int)["n"])
+
+	// Unexported fields are shared
+	assert.Equal(t, root.secret, clone.secret)
+
+	out, err = DeepCopy(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, out)
+}
+
+func TestShallowCopy(t *testing.T) {
+	nested := map[string]interface{}{}
+	state := map[string]interface{}{"nested": nested, "n": 1}
+	out, err := ShallowCopy(state)
+	assert.NoError(t, err)
+	clone := out.(map[string]interface{})
+	clone["n"] = 2
+	clone["nested"].(map[string]interface{})["shared"] = true
+	assert.Equal(t, 1, state["n"])
+	assert.Equal(t, true, nested["shared"])
+
+	doc := &document{Title: "a"}
+	out, err = ShallowCopy(doc)
+	assert.NoError(t, err)
+	out.(*document).Title = "b"
+	assert.Equal(t, "a", doc.Title)
+
+	out, err = ShallowCopy("value")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", out)
+}
@@ -0,0 +1,151 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// LimiterStore holds the state of rate limiters and circuit breakers. Limiters and
+// breakers using the same store and key share their state, e.g. across the replicas of
+// a service when the store is backed by Redis.
+type LimiterStore interface {
+	// TakeToken takes a token from the bucket for key, which holds up to capacity tokens
+	// and refills capacity tokens per window. It returns zero when a token was taken, or
+	// how long to wait until one is available.
+	TakeToken(ctx context.Context, key string, capacity int, window time.Duration) (time.Duration, error)
+
+	// AllowCall reports whether the circuit for key lets a call through, moving an open
+	// circuit to half-open once its timeout has passed
+	AllowCall(ctx context.Context, key string, config CircuitBreakerConfig) (bool, error)
+
+	// RecordFailure records a failed call and reports whether it tripped the circuit open
+	RecordFailure(ctx context.Context, key string, config CircuitBreakerConfig) (bool, error)
+
+	// RecordSuccess records a successful call, closing a half-open circuit
+	RecordSuccess(ctx context.Context, key string, config CircuitBreakerConfig) error
+}
+
+// MemoryLimiterStore is a LimiterStore that keeps its state in process memory
+type MemoryLimiterStore struct {
+	mu       sync.Mutex
+	buckets  map[string]*tokenBucket
+	circuits map[string]*circuitState
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type circuitState struct {
+	state           CircuitBreakerState
+	failures        int
+	successes       int
+	lastFailureTime time.Time
+	halfOpenCalls   int
+}
+
+// NewMemoryLimiterStore creates an in-memory limiter store
+func NewMemoryLimiterStore() *MemoryLimiterStore {
+	return &MemoryLimiterStore{
+		buckets:  make(map[string]*tokenBucket),
+		circuits: make(map[string]*circuitState),
+	}
+}
+
+// TakeToken implements LimiterStore
+func (s *MemoryLimiterStore) TakeToken(_ context.Context, key string, capacity int, window time.Duration) (time.Duration, error) {
+	if capacity <= 0 || window <= 0 {
+		return 0, fmt.Errorf("invalid token bucket: capacity %d, window %v", capacity, window)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(capacity), last: now}
+		s.buckets[key] = bucket
+	}
+
+	// Refill the tokens accrued since the last call
+	rate := float64(capacity) / float64(window)
+	bucket.tokens = math.Min(float64(capacity), bucket.tokens+float64(now.Sub(bucket.last))*rate)
+	bucket.last = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return 0, nil
+	}
+	return time.Duration(math.Ceil((1 - bucket.tokens) / rate)), nil
+}
+
+func (s *MemoryLimiterStore) circuit(key string) *circuitState {
+	c, ok := s.circuits[key]
+	if !ok {
+		c = &circuitState{state: CircuitClosed}
+		s.circuits[key] = c
+	}
+	return c
+}
+
+// AllowCall implements LimiterStore
+func (s *MemoryLimiterStore) AllowCall(_ context.Context, key string, config CircuitBreakerConfig) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.circuit(key)
+	switch c.state {
+	case CircuitOpen:
+		// Check if enough time has passed to try again
+		if time.Since(c.lastFailureTime) <= config.Timeout {
+			return false, nil
+		}
+		c.state = CircuitHalfOpen
+		c.halfOpenCalls = 0
+	case CircuitHalfOpen:
+		// Check if we've made too many calls in half-open state
+		if c.halfOpenCalls >= config.HalfOpenMaxCalls {
+			c.state = CircuitOpen
+			return false, nil
+		}
+		c.halfOpenCalls++
+	}
+	return true, nil
+}
+
+// RecordFailure implements LimiterStore
+func (s *MemoryLimiterStore) RecordFailure(_ context.Context, key string, config CircuitBreakerConfig) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.circuit(key)
+	c.failures++
+	c.successes = 0
+	c.lastFailureTime = time.Now()
+
+	if c.failures >= config.FailureThreshold && c.state != CircuitOpen {
+		c.state = CircuitOpen
+		return true, nil
+	}
+	return false, nil
+}
+
+// RecordSuccess implements LimiterStore
+func (s *MemoryLimiterStore) RecordSuccess(_ context.Context, key string, config CircuitBreakerConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.circuit(key)
+	c.successes++
+	c.failures = 0
+
+	if c.state == CircuitHalfOpen && c.successes >= config.SuccessThreshold {
+		c.state = CircuitClosed
+	}
+	return nil
+}
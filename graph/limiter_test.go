@@ -0,0 +1,173 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rateLimitRecorder records the waits reported by rate limiters
+type rateLimitRecorder struct {
+	NoOpCallbackHandler
+	mu    sync.Mutex
+	waits []time.Duration
+}
+
+func (r *rateLimitRecorder) OnNodeRetry(context.Context, string, int, error) {}
+func (r *rateLimitRecorder) OnCircuitBreakerOpen(context.Context, string)    {}
+
+func (r *rateLimitRecorder) OnRateLimited(ctx context.Context, nodeName string, wait time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.waits = append(r.waits, wait)
+}
+
+func TestMemoryLimiterStore_TokenBucket(t *testing.T) {
+	store := NewMemoryLimiterStore()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		wait, err := store.TakeToken(ctx, "llm", 2, time.Second)
+		assert.NoError(t, err)
+		assert.Zero(t, wait)
+	}
+
+	// The bucket is empty and refills one token every 500ms
+	wait, err := store.TakeToken(ctx, "llm", 2, time.Second)
+	assert.NoError(t, err)
+	assert.Greater(t, wait, 400*time.Millisecond)
+	assert.LessOrEqual(t, wait, 500*time.Millisecond)
+
+	// Buckets are independent per key
+	wait, err = store.TakeToken(ctx, "search", 2, time.Second)
+	assert.NoError(t, err)
+	assert.Zero(t, wait)
+}
+
+func TestRateLimit_SharedStore(t *testing.T) {
+	store := NewMemoryLimiterStore()
+	var calls int32
+	newReplica := func() *StateRunnable {
+		g := NewStateGraph()
+		g.AddNode("llm", "llm", func(ctx context.Context, state interface{}) (interface{}, error) {
+			atomic.AddInt32(&calls, 1)
+			return state, nil
+		}, WithRateLimit(RateLimitConfig{MaxCalls: 2, Window: time.Minute, Store: store, Key: "upstream"}))
+		g.AddEdge("llm", END)
+		g.SetEntryPoint("llm")
+		runnable, err := g.Compile()
+		assert.NoError(t, err)
+		return runnable
+	}
+
+	// Both replicas draw from the same budget
+	first, second := newReplica(), newReplica()
+	_, err := first.Invoke(context.Background(), "a")
+	assert.NoError(t, err)
+	_, err = second.Invoke(context.Background(), "b")
+	assert.NoError(t, err)
+	_, err = first.Invoke(context.Background(), "c")
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestRateLimit_Wait(t *testing.T) {
+	g := NewStateGraph()
+	g.AddNode("llm", "llm", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return state, nil
+	}, WithRateLimit(RateLimitConfig{MaxCalls: 1, Window: 30 * time.Millisecond, Wait: true}))
+	g.AddEdge("llm", END)
+	g.SetEntryPoint("llm")
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	recorder := &rateLimitRecorder{}
+	config := &Config{Callbacks: []CallbackHandler{recorder}}
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		result, err := runnable.InvokeWithConfig(context.Background(), "input", config)
+		assert.NoError(t, err)
+		assert.Equal(t, "input", result)
+	}
+	// The second and third calls wait for a token
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	assert.GreaterOrEqual(t, len(recorder.waits), 2)
+
+	// A cancelled wait fails with the context error
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	_, err = runnable.Invoke(ctx, "input")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRateLimit_MaxWait(t *testing.T) {
+	var calls int32
+	rl := NewRateLimiterWithConfig(Node{Name: "llm", Function: func(ctx context.Context, state interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return state, nil
+	}}, RateLimitConfig{MaxCalls: 1, Window: time.Hour, Wait: true, MaxWait: time.Second})
+
+	_, err := rl.Execute(context.Background(), "input")
+	assert.NoError(t, err)
+	_, err = rl.Execute(context.Background(), "input")
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestRateLimit_InvalidConfig(t *testing.T) {
+	noop := func(ctx context.Context, state interface{}) (interface{}, error) {
+		return state, nil
+	}
+
+	// Graphs with a rate limit that allows no calls do not compile
+	for _, config := range []RateLimitConfig{{MaxCalls: 0, Window: time.Second}, {MaxCalls: 1}} {
+		g := NewMessageGraph()
+		g.AddNode("llm", "llm", noop, WithRateLimit(config))
+		g.SetEntryPoint("llm")
+		_, err := g.Compile()
+		assert.ErrorContains(t, err, "node llm: invalid rate limit")
+
+		sg := NewStateGraph()
+		sg.AddNode("llm", "llm", noop, WithRateLimit(config))
+		sg.SetEntryPoint("llm")
+		_, err = sg.Compile()
+		assert.ErrorContains(t, err, "invalid rate limit")
+
+		// Limiters created directly reject every call instead of allowing them all
+		rl := NewRateLimiterWithConfig(Node{Name: "llm", Function: noop}, config)
+		_, err = rl.Execute(context.Background(), "input")
+		assert.ErrorContains(t, err, "rate limiter for llm: invalid rate limit")
+	}
+
+	_, err := NewMemoryLimiterStore().TakeToken(context.Background(), "llm", 0, time.Second)
+	assert.Error(t, err)
+	_, err = NewMemoryLimiterStore().TakeToken(context.Background(), "llm", 1, 0)
+	assert.Error(t, err)
+}
+
+func TestCircuitBreaker_SharedStore(t *testing.T) {
+	store := NewMemoryLimiterStore()
+	config := CircuitBreakerConfig{FailureThreshold: 2, SuccessThreshold: 1, Timeout: time.Hour, HalfOpenMaxCalls: 1, Store: store, Key: "upstream"}
+	fail := func(ctx context.Context, state interface{}) (interface{}, error) {
+		return nil, errors.New("unavailable")
+	}
+	first := NewCircuitBreaker(Node{Name: "a", Function: fail}, config)
+	second := NewCircuitBreaker(Node{Name: "b", Function: fail}, config)
+
+	// One failure in each process trips the shared circuit
+	_, err := first.Execute(context.Background(), nil)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrCircuitOpen)
+	_, err = second.Execute(context.Background(), nil)
+	assert.NotErrorIs(t, err, ErrCircuitOpen)
+
+	_, err = first.Execute(context.Background(), nil)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	_, err = second.Execute(context.Background(), nil)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+}
@@ -2,6 +2,7 @@ package graph
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -46,6 +47,9 @@ const (
 
 	// EventCustom indicates a custom user-defined event
 	EventCustom NodeEvent = "custom"
+
+	// NodeEventCacheHit indicates a node result was served from the cache
+	NodeEventCacheHit NodeEvent = "cache_hit"
 )
 
 // NodeListener defines the interface for node event listeners
@@ -199,17 +203,13 @@ func NewListenableMessageGraph() *ListenableMessageGraph {
 }
 
 // AddNode adds a node with listener capabilities
-func (g *ListenableMessageGraph) AddNode(name string, description string, fn func(ctx context.Context, state interface{}) (interface{}, error)) *ListenableNode {
-	node := Node{
-		Name:        name,
-		Description: description,
-		Function:    fn,
-	}
-
+func (g *ListenableMessageGraph) AddNode(name string, description string, fn func(ctx context.Context, state interface{}) (interface{}, error), opts ...NodeOption) *ListenableNode {
+	node := newNode(name, description, fn, opts)
 	listenableNode := NewListenableNode(node)
 
-	// Add to both the base graph and our listenable nodes map
-	g.MessageGraph.AddNode(name, description, fn)
+	// Add to both the base graph and our listenable nodes map, sharing the wrapped
+	// function so resilience state such as a circuit breaker is not duplicated
+	g.nodes[name] = node
 	g.listenableNodes[name] = listenableNode
 
 	return listenableNode
@@ -245,6 +245,12 @@ func (g *ListenableMessageGraph) CompileListenable() (*ListenableRunnable, error
 	if g.entryPoint == "" {
 		return nil, ErrEntryPointNotSet
 	}
+	if err := validateNodes(g.nodes); err != nil {
+		return nil, err
+	}
+	if err := validateErrorHandlers(g.errorEdges, g.nodes); err != nil {
+		return nil, err
+	}
 
 	return &ListenableRunnable{
 		graph:           g,
@@ -259,13 +265,35 @@ func (lr *ListenableRunnable) Invoke(ctx context.Context, initialState interface
 
 // InvokeWithConfig executes the graph with listener notifications and config
 func (lr *ListenableRunnable) InvokeWithConfig(ctx context.Context, initialState interface{}, config *Config) (interface{}, error) {
+	run, ctx, err := startRun(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer run.end()
+
 	if config != nil {
 		ctx = WithConfig(ctx, config)
 	}
+	ctx = withRunID(ctx, generateRunID())
+
+	// Limit the members of parallel groups running at once, as nodes run one at a time
+	ctx, reacquireSlot := withRunScheduler(ctx, config)
+	defer reacquireSlot()
+	ctx = withStateCloner(ctx, lr.graph.isolation.cloner(lr.graph.stateCloner))
 
 	state := initialState
 	currentNode := lr.graph.entryPoint
 
+	// Handle ResumeFrom
+	if config != nil && len(config.ResumeFrom) > 0 {
+		currentNode = config.ResumeFrom[0]
+
+		// Inject ResumeValue
+		if config.ResumeValue != nil {
+			ctx = WithResumeValue(ctx, config.ResumeValue)
+		}
+	}
+
 	for {
 		if currentNode == END {
 			break
@@ -276,11 +304,39 @@ func (lr *ListenableRunnable) InvokeWithConfig(ctx context.Context, initialState
 			return nil, ErrNodeNotFound
 		}
 
-		// Execute the node function
-		result, err := listenableNode.Execute(ctx, state)
+		// Execute the node function, serving from cache when possible
+		result, cacheHit, err := executeWithCache(withNodeName(ctx, currentNode), lr.graph.cache, listenableNode.Node, state, listenableNode.Execute)
 		if err != nil {
+			// Route the error to the handler of the node, if any, and continue from it
+			if handler := lr.graph.errorHandler(currentNode); handler != "" && handleableError(ctx, err) {
+				var handlerFn func(context.Context, interface{}) (interface{}, error)
+				if handlerNode, ok := lr.listenableNodes[handler]; ok {
+					handlerFn = handlerNode.Execute
+				}
+				result, err = runErrorHandler(ctx, handler, handlerFn, currentNode, state, err)
+				currentNode = handler
+			}
+		}
+		if err != nil {
+			// Run the node again on resume when Shutdown cancelled it
+			if interrupt := run.cancelled(ctx, state, []string{currentNode}); interrupt != nil {
+				return state, interrupt
+			}
+			var nodeInterrupt *NodeInterrupt
+			if errors.As(err, &nodeInterrupt) {
+				return state, &GraphInterrupt{
+					Node:           currentNode,
+					State:          state,
+					NextNodes:      []string{currentNode},
+					InterruptValue: nodeInterrupt.Value,
+					Subgraph:       nodeInterrupt.Subgraph,
+				}
+			}
 			return nil, err
 		}
+		if cacheHit {
+			listenableNode.NotifyListeners(ctx, NodeEventCacheHit, result, nil)
+		}
 
 		// Update state using Schema if available
 		if lr.graph.Schema != nil {
@@ -323,6 +379,16 @@ func (lr *ListenableRunnable) InvokeWithConfig(ctx context.Context, initialState
 			}
 		}
 
+		// Stop the run if a callback guard (e.g. a usage budget) says so
+		if err := checkRunGuards(ctx); err != nil {
+			return state, err
+		}
+
+		// Stop between steps when the runtime is shutting down
+		if err := run.interrupt(state, []string{nextNode}); err != nil {
+			return state, err
+		}
+
 		currentNode = nextNode
 	}
 
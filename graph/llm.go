@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/tools"
+)
+
+// GenerateContent calls model.GenerateContent and reports the call to the callbacks
+// of the config in ctx through OnLLMStart and OnLLMEnd/OnLLMError, so that tracers and
+// usage trackers see the LLM calls made inside nodes. The call is reported as a child
+// of the run in ctx, usually the node calling it.
+func GenerateContent(ctx context.Context, model llms.Model, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	config := GetConfig(ctx)
+	if config == nil || len(config.Callbacks) == 0 {
+		return model.GenerateContent(ctx, messages, options...)
+	}
+
+	var callOpts llms.CallOptions
+	for _, opt := range options {
+		opt(&callOpts)
+	}
+	serialized := map[string]interface{}{
+		"name": fmt.Sprintf("%T", model),
+		"type": "llm",
+	}
+	if callOpts.Model != "" {
+		serialized["model"] = callOpts.Model
+	}
+
+	var prompts []string
+	for _, msg := range messages {
+		for _, part := range msg.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				prompts = append(prompts, text.Text)
+			}
+		}
+	}
+
+	runID := generateRunID()
+	for _, cb := range config.Callbacks {
+		cb.OnLLMStart(ctx, serialized, prompts, runID, ParentRunIDFromContext(ctx), config.Tags, config.Metadata)
+	}
+
+	resp, err := model.GenerateContent(ctx, messages, options...)
+	for _, cb := range config.Callbacks {
+		if err != nil {
+			cb.OnLLMError(ctx, err, runID)
+		} else {
+			cb.OnLLMEnd(ctx, resp, runID)
+		}
+	}
+	return resp, err
+}
+
+// CallTool calls tool.Call and reports the call to the callbacks of the config in ctx
+// through OnToolStart and OnToolEnd/OnToolError, as a child of the run in ctx. Calls
+// made by the tool itself are reported under the tool run.
+func CallTool(ctx context.Context, tool tools.Tool, input string) (string, error) {
+	config := GetConfig(ctx)
+	if config == nil || len(config.Callbacks) == 0 {
+		return tool.Call(ctx, input)
+	}
+
+	serialized := map[string]interface{}{
+		"name":        tool.Name(),
+		"description": tool.Description(),
+		"type":        "tool",
+	}
+	runID := generateRunID()
+	for _, cb := range config.Callbacks {
+		cb.OnToolStart(ctx, serialized, input, runID, ParentRunIDFromContext(ctx), config.Tags, config.Metadata)
+	}
+
+	output, err := tool.Call(WithParentRunID(ctx, runID), input)
+	for _, cb := range config.Callbacks {
+		if err != nil {
+			cb.OnToolError(ctx, err, runID)
+		} else {
+			cb.OnToolEnd(ctx, output, runID)
+		}
+	}
+	return output, err
+}
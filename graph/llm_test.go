@@ -0,0 +1,209 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// treeRun is a callback run as seen by treeHandler
+type treeRun struct {
+	kind   string
+	name   string
+	input  string
+	parent string
+	ended  bool
+	err    error
+}
+
+// treeHandler records the callback runs of a graph run with their parents
+type treeHandler struct {
+	NoOpCallbackHandler
+	mu    sync.Mutex
+	runs  map[string]*treeRun
+	order []string
+}
+
+func newTreeHandler() *treeHandler {
+	return &treeHandler{runs: make(map[string]*treeRun)}
+}
+
+func (h *treeHandler) start(kind, name, input, runID string, parentRunID *string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r := &treeRun{kind: kind, name: name, input: input}
+	if parentRunID != nil {
+		r.parent = *parentRunID
+	}
+	h.runs[runID] = r
+	h.order = append(h.order, runID)
+}
+
+func (h *treeHandler) end(runID string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.runs[runID].ended = true
+	h.runs[runID].err = err
+}
+
+func (h *treeHandler) OnChainStart(ctx context.Context, serialized map[string]interface{}, inputs map[string]interface{}, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	h.start("chain", serialized["name"].(string), "", runID, parentRunID)
+}
+
+func (h *treeHandler) OnChainEnd(ctx context.Context, outputs map[string]interface{}, runID string) {
+	h.end(runID, nil)
+}
+
+func (h *treeHandler) OnChainError(ctx context.Context, err error, runID string) {
+	h.end(runID, err)
+}
+
+func (h *treeHandler) OnLLMStart(ctx context.Context, serialized map[string]interface{}, prompts []string, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	h.start("llm", "llm", prompts[0], runID, parentRunID)
+}
+
+func (h *treeHandler) OnLLMEnd(ctx context.Context, response interface{}, runID string) {
+	h.end(runID, nil)
+}
+
+func (h *treeHandler) OnToolStart(ctx context.Context, serialized map[string]interface{}, inputStr string, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	h.start("tool", serialized["name"].(string), inputStr, runID, parentRunID)
+}
+
+func (h *treeHandler) OnToolEnd(ctx context.Context, output string, runID string) {
+	h.end(runID, nil)
+}
+
+func (h *treeHandler) OnToolError(ctx context.Context, err error, runID string) {
+	h.end(runID, err)
+}
+
+// path returns the names from the root run down to the named run
+func (h *treeHandler) path(name string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, id := range h.order {
+		if h.runs[id].name != name {
+			continue
+		}
+		var path []string
+		for r := h.runs[id]; r != nil; r = h.runs[r.parent] {
+			path = append([]string{r.name}, path...)
+		}
+		return path
+	}
+	return nil
+}
+
+func (h *treeHandler) run(name string) *treeRun {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, id := range h.order {
+		if h.runs[id].name == name {
+			return h.runs[id]
+		}
+	}
+	return nil
+}
+
+// echoTool returns its input
+type echoTool struct{}
+
+func (echoTool) Name() string        { return "echo" }
+func (echoTool) Description() string { return "echoes its input" }
+func (echoTool) Call(ctx context.Context, input string) (string, error) {
+	return input, nil
+}
+
+// runTreeNode calls an LLM and a tool, checking that the node was reported before it ran
+func runTreeNode(t *testing.T, handler *treeHandler) func(context.Context, interface{}) (interface{}, error) {
+	return func(ctx context.Context, state interface{}) (interface{}, error) {
+		node := handler.run("agent")
+		assert.NotNil(t, node)
+		assert.False(t, node.ended)
+		assert.Equal(t, `"question"`, node.input)
+		assert.NotNil(t, ParentRunIDFromContext(ctx))
+
+		if _, err := GenerateContent(ctx, &usageLLM{}, []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "hi")}); err != nil {
+			return nil, err
+		}
+		return CallTool(ctx, echoTool{}, "answer")
+	}
+}
+
+func TestRunTree(t *testing.T) {
+	tests := []struct {
+		name   string
+		invoke func(t *testing.T, handler *treeHandler) (interface{}, error)
+	}{
+		{
+			name: "MessageGraph",
+			invoke: func(t *testing.T, handler *treeHandler) (interface{}, error) {
+				g := NewMessageGraph()
+				g.AddNode("agent", "agent", runTreeNode(t, handler))
+				g.AddEdge("agent", END)
+				g.SetEntryPoint("agent")
+				runnable, err := g.Compile()
+				assert.NoError(t, err)
+				return runnable.InvokeWithConfig(context.Background(), "question", &Config{Callbacks: []CallbackHandler{handler}})
+			},
+		},
+		{
+			name: "StateGraph",
+			invoke: func(t *testing.T, handler *treeHandler) (interface{}, error) {
+				g := NewStateGraph()
+				g.AddNode("agent", "agent", runTreeNode(t, handler))
+				g.AddEdge("agent", END)
+				g.SetEntryPoint("agent")
+				runnable, err := g.Compile()
+				assert.NoError(t, err)
+				return runnable.InvokeWithConfig(context.Background(), "question", &Config{Callbacks: []CallbackHandler{handler}})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newTreeHandler()
+			result, err := tt.invoke(t, handler)
+			assert.NoError(t, err)
+			assert.Equal(t, "answer", result)
+
+			assert.Equal(t, []string{"graph", "agent", "llm"}, handler.path("llm"))
+			assert.Equal(t, []string{"graph", "agent", "echo"}, handler.path("echo"))
+			assert.Equal(t, "answer", handler.run("echo").input)
+			for _, name := range []string{"graph", "agent", "llm", "echo"} {
+				assert.True(t, handler.run(name).ended, name)
+			}
+		})
+	}
+}
+
+func TestRunTree_NodeError(t *testing.T) {
+	boom := errors.New("boom")
+	g := NewStateGraph()
+	g.AddNode("agent", "agent", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return nil, boom
+	})
+	g.AddEdge("agent", END)
+	g.SetEntryPoint("agent")
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	handler := newTreeHandler()
+	_, err = runnable.InvokeWithConfig(context.Background(), "question", &Config{Callbacks: []CallbackHandler{handler}})
+	assert.ErrorIs(t, err, boom)
+	assert.ErrorIs(t, handler.run("agent").err, boom)
+	assert.ErrorIs(t, handler.run("graph").err, boom)
+}
+
+func TestCallTool_WithoutCallbacks(t *testing.T) {
+	out, err := CallTool(context.Background(), echoTool{}, "hi")
+	assert.NoError(t, err)
+	assert.Equal(t, "hi", out)
+	assert.Nil(t, ParentRunIDFromContext(context.Background()))
+}
@@ -0,0 +1,69 @@
+package graph
+
+import (
+	"context"
+	"sort"
+)
+
+// NodeOrder controls the order of the nodes of a superstep, which is the order in
+// which their results are passed to Schema.Update or the StateMerger
+type NodeOrder int
+
+const (
+	// NodeOrderDeclaration orders the next nodes by the nodes of the current superstep,
+	// then by the declaration order of their edges. This is the default.
+	NodeOrderDeclaration NodeOrder = iota
+	// NodeOrderName orders the next nodes by name, so that the order does not depend
+	// on how the graph was built
+	NodeOrderName
+)
+
+// SetNodeOrder sets the order of the nodes of each superstep
+func (g *MessageGraph) SetNodeOrder(order NodeOrder) {
+	g.nodeOrder = order
+}
+
+// SetNodeOrder sets the order of the nodes of each superstep
+func (g *StateGraph) SetNodeOrder(order NodeOrder) {
+	g.nodeOrder = order
+}
+
+// nodeSet collects the next nodes of a superstep without duplicates, keeping the
+// order in which they were added
+type nodeSet struct {
+	seen  map[string]bool
+	nodes []string
+}
+
+func (s *nodeSet) add(name string) {
+	if s.seen == nil {
+		s.seen = make(map[string]bool)
+	}
+	if !s.seen[name] {
+		s.seen[name] = true
+		s.nodes = append(s.nodes, name)
+	}
+}
+
+// list returns the nodes in the given order
+func (s *nodeSet) list(order NodeOrder) []string {
+	if order == NodeOrderName {
+		sort.Strings(s.nodes)
+	}
+	return s.nodes
+}
+
+// sortedNodes returns the functions of a group node as nodes ordered by name
+func sortedNodes(functions map[string]func(ctx context.Context, state interface{}) (interface{}, error)) []Node {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	nodes := make([]Node, 0, len(names))
+	for _, name := range names {
+		nodes = append(nodes, Node{Name: name, Function: functions[name]})
+	}
+	return nodes
+}
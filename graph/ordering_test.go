@@ -0,0 +1,112 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// assertReproducible runs invoke repeatedly and asserts that every run returns the
+// same final state
+func assertReproducible(t *testing.T, runs int, invoke func() (interface{}, error)) interface{} {
+	t.Helper()
+	first, err := invoke()
+	assert.NoError(t, err)
+	for i := 1; i < runs; i++ {
+		state, err := invoke()
+		assert.NoError(t, err)
+		if !assert.Equal(t, first, state, "run %d", i) {
+			break
+		}
+	}
+	return first
+}
+
+// messageNode appends its name to the messages of the state
+func messageNode(name string) func(context.Context, interface{}) (interface{}, error) {
+	return func(ctx context.Context, state interface{}) (interface{}, error) {
+		return map[string]interface{}{"messages": []string{name}}, nil
+	}
+}
+
+func newMessagesSchema() *MapSchema {
+	schema := NewMapSchema()
+	schema.RegisterReducer("messages", AppendReducer)
+	return schema
+}
+
+func TestNodeOrder_Declaration(t *testing.T) {
+	g := NewStateGraph()
+	g.SetSchema(newMessagesSchema())
+	g.AddNode("start", "start", messageNode("start"))
+	g.SetEntryPoint("start")
+	workers := []string{"zulu", "alpha", "mike", "bravo", "yankee", "charlie"}
+	for _, name := range workers {
+		g.AddNode(name, name, messageNode(name))
+		g.AddEdge("start", name)
+		g.AddEdge(name, "join")
+	}
+	g.AddNode("join", "join", messageNode("join"))
+	g.AddEdge("join", END)
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	state := assertReproducible(t, 50, func() (interface{}, error) {
+		return runnable.Invoke(context.Background(), map[string]interface{}{})
+	})
+	expected := append(append([]string{"start"}, workers...), "join")
+	assert.Equal(t, expected, state.(map[string]interface{})["messages"])
+}
+
+func TestNodeOrder_Name(t *testing.T) {
+	g := NewMessageGraph()
+	g.SetNodeOrder(NodeOrderName)
+	g.SetStateMerger(func(ctx context.Context, current interface{}, states []interface{}) (interface{}, error) {
+		merged := current.(string)
+		for _, s := range states {
+			merged += "," + s.(string)
+		}
+		return merged, nil
+	})
+	g.AddNode("start", "start", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return &Command{Update: "start", Goto: []string{"c", "a", "b"}}, nil
+	})
+	g.SetEntryPoint("start")
+	for _, name := range []string{"c", "a", "b"} {
+		g.AddNode(name, name, func(ctx context.Context, state interface{}) (interface{}, error) {
+			return name, nil
+		})
+		g.AddEdge(name, END)
+	}
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	state := assertReproducible(t, 50, func() (interface{}, error) {
+		return runnable.Invoke(context.Background(), "input")
+	})
+	assert.Equal(t, "input,start,a,b,c", state)
+}
+
+func TestNodeOrder_ParallelGroup(t *testing.T) {
+	nodes := make(map[string]func(context.Context, interface{}) (interface{}, error))
+	for i := 0; i < 8; i++ {
+		name := fmt.Sprintf("doc_%d", i)
+		nodes[name] = func(ctx context.Context, state interface{}) (interface{}, error) {
+			return name, nil
+		}
+	}
+
+	g := NewMessageGraph()
+	g.AddParallelNodes("docs", nodes)
+	g.SetEntryPoint("docs")
+	g.AddEdge("docs", END)
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	state := assertReproducible(t, 20, func() (interface{}, error) {
+		return runnable.Invoke(context.Background(), "input")
+	})
+	assert.Equal(t, []interface{}{"doc_0", "doc_1", "doc_2", "doc_3", "doc_4", "doc_5", "doc_6", "doc_7"}, state)
+}
@@ -8,8 +8,9 @@ import (
 
 // ParallelNode represents a set of nodes that can execute in parallel
 type ParallelNode struct {
-	nodes []Node
-	name  string
+	nodes          []Node
+	name           string
+	maxConcurrency int
 }
 
 // NewParallelNode creates a new parallel node
@@ -20,8 +21,19 @@ func NewParallelNode(name string, nodes ...Node) *ParallelNode {
 	}
 }
 
-// Execute runs all nodes in parallel and collects results
+// SetMaxConcurrency limits how many nodes of the group run at once; zero means no limit
+func (pn *ParallelNode) SetMaxConcurrency(limit int) {
+	pn.maxConcurrency = limit
+}
+
+// Execute runs all nodes in parallel and collects results. Nodes wait for a slot of
+// the group limit and of the run's Config.MaxConcurrency, highest priority first.
 func (pn *ParallelNode) Execute(ctx context.Context, state interface{}) (interface{}, error) {
+	// Let the members use the slot of the group node while it waits for them
+	reacquire := yieldSlot(ctx)
+	defer reacquire()
+	group := newScheduler(pn.maxConcurrency)
+
 	// Create channels for results and errors
 	type result struct {
 		index int
@@ -48,7 +60,26 @@ func (pn *ParallelNode) Execute(ctx context.Context, state interface{}) (interfa
 				}
 			}()
 
-			value, err := n.Function(ctx, state)
+			if err := group.acquire(ctx, n.Priority); err != nil {
+				results <- result{index: idx, err: err}
+				return
+			}
+			defer group.release()
+			nodeCtx, slot, err := acquireSlot(ctx, n.Priority)
+			if err != nil {
+				results <- result{index: idx, err: err}
+				return
+			}
+			defer slot.release()
+
+			// Give each member its own copy of the state when the run isolates it
+			memberState, err := isolateState(ctx, state)
+			if err != nil {
+				results <- result{index: idx, err: err}
+				return
+			}
+
+			value, err := n.Function(nodeCtx, memberState)
 			results <- result{
 				index: idx,
 				value: value,
@@ -82,27 +113,24 @@ func (pn *ParallelNode) Execute(ctx context.Context, state interface{}) (interfa
 	return outputs, nil
 }
 
-// AddParallelNodes adds a set of nodes that execute in parallel
-func (g *MessageGraph) AddParallelNodes(groupName string, nodes map[string]func(context.Context, interface{}) (interface{}, error)) {
-	// Create parallel node group
-	parallelNodes := make([]Node, 0, len(nodes))
-	for name, fn := range nodes {
-		parallelNodes = append(parallelNodes, Node{
-			Name:     name,
-			Function: fn,
-		})
-	}
+// AddParallelNodes adds a set of nodes that execute in parallel. Use WithMaxConcurrency
+// to limit how many of them run at once.
+func (g *MessageGraph) AddParallelNodes(groupName string, nodes map[string]func(context.Context, interface{}) (interface{}, error), opts ...NodeOption) {
+	// Create parallel node group, ordered by name so that results are reproducible
+	parallelNodes := sortedNodes(nodes)
 
 	// Add as a single parallel node
 	parallelNode := NewParallelNode(groupName, parallelNodes...)
-	g.AddNode(groupName, "Parallel execution group: "+groupName, parallelNode.Execute)
+	parallelNode.SetMaxConcurrency(groupConcurrency(opts))
+	g.AddNode(groupName, "Parallel execution group: "+groupName, parallelNode.Execute, opts...)
 }
 
 // MapReduceNode executes nodes in parallel and reduces results
 type MapReduceNode struct {
-	name     string
-	mapNodes []Node
-	reducer  func([]interface{}) (interface{}, error)
+	name           string
+	mapNodes       []Node
+	reducer        func([]interface{}) (interface{}, error)
+	maxConcurrency int
 }
 
 // NewMapReduceNode creates a new map-reduce node
@@ -114,10 +142,16 @@ func NewMapReduceNode(name string, reducer func([]interface{}) (interface{}, err
 	}
 }
 
+// SetMaxConcurrency limits how many map nodes run at once; zero means no limit
+func (mr *MapReduceNode) SetMaxConcurrency(limit int) {
+	mr.maxConcurrency = limit
+}
+
 // Execute runs map nodes in parallel and reduces results
 func (mr *MapReduceNode) Execute(ctx context.Context, state interface{}) (interface{}, error) {
 	// Execute map phase in parallel
 	pn := NewParallelNode(mr.name+"_map", mr.mapNodes...)
+	pn.SetMaxConcurrency(mr.maxConcurrency)
 	results, err := pn.Execute(ctx, state)
 	if err != nil {
 		return nil, fmt.Errorf("map phase failed: %w", err)
@@ -131,24 +165,30 @@ func (mr *MapReduceNode) Execute(ctx context.Context, state interface{}) (interf
 	return results, nil
 }
 
-// AddMapReduceNode adds a map-reduce pattern node
+// AddMapReduceNode adds a map-reduce pattern node. Use WithMaxConcurrency to limit how
+// many map functions run at once.
 func (g *MessageGraph) AddMapReduceNode(
 	name string,
 	mapFunctions map[string]func(context.Context, interface{}) (interface{}, error),
 	reducer func([]interface{}) (interface{}, error),
+	opts ...NodeOption,
 ) {
-	// Create map nodes
-	mapNodes := make([]Node, 0, len(mapFunctions))
-	for nodeName, fn := range mapFunctions {
-		mapNodes = append(mapNodes, Node{
-			Name:     nodeName,
-			Function: fn,
-		})
-	}
+	// Create map nodes, ordered by name so that results are reproducible
+	mapNodes := sortedNodes(mapFunctions)
 
 	// Create and add map-reduce node
 	mrNode := NewMapReduceNode(name, reducer, mapNodes...)
-	g.AddNode(name, "Map-reduce node: "+name, mrNode.Execute)
+	mrNode.SetMaxConcurrency(groupConcurrency(opts))
+	g.AddNode(name, "Map-reduce node: "+name, mrNode.Execute, opts...)
+}
+
+// groupConcurrency returns the member limit set by the options of a group node
+func groupConcurrency(opts []NodeOption) int {
+	var node Node
+	for _, opt := range opts {
+		opt(&node)
+	}
+	return node.MaxConcurrency
 }
 
 // FanOutFanIn creates a fan-out/fan-in pattern
@@ -158,9 +198,10 @@ func (g *MessageGraph) FanOutFanIn(
 	collector string,
 	workerFuncs map[string]func(context.Context, interface{}) (interface{}, error),
 	collectFunc func([]interface{}) (interface{}, error),
+	opts ...NodeOption,
 ) {
 	// Add parallel worker nodes
-	g.AddParallelNodes(source+"_workers", workerFuncs)
+	g.AddParallelNodes(source+"_workers", workerFuncs, opts...)
 
 	// Add collector node
 	g.AddNode(collector, "Collector node: "+collector, func(_ context.Context, state interface{}) (interface{}, error) {
@@ -0,0 +1,539 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NodeSpan is the execution of a node within a superstep. Offsets are relative to the
+// start of the run.
+type NodeSpan struct {
+	Node string `json:"node"`
+	// Queued is when the node was scheduled, i.e. the start of its superstep
+	Queued time.Duration `json:"queued"`
+	// Start and End bound the execution of the node, including retries
+	Start time.Duration `json:"start"`
+	End   time.Duration `json:"end"`
+	// Attempts is the number of times the node was executed
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error,omitempty"`
+	CacheHit bool   `json:"cache_hit,omitempty"`
+}
+
+// QueueTime returns how long the node waited between being scheduled and starting
+func (s NodeSpan) QueueTime() time.Duration {
+	return s.Start - s.Queued
+}
+
+// RunTime returns how long the node ran
+func (s NodeSpan) RunTime() time.Duration {
+	return s.End - s.Start
+}
+
+// Superstep is a step of a run, whose nodes execute in parallel
+type Superstep struct {
+	Index int           `json:"index"`
+	Start time.Duration `json:"start"`
+	End   time.Duration `json:"end"`
+	Nodes []NodeSpan    `json:"nodes"`
+}
+
+// Duration returns the wall time of the superstep
+func (s Superstep) Duration() time.Duration {
+	return s.End - s.Start
+}
+
+// RunProfile is the execution timeline of a graph run
+type RunProfile struct {
+	RunID     string        `json:"run_id"`
+	StartTime time.Time     `json:"start_time"`
+	Duration  time.Duration `json:"duration"`
+	Steps     []Superstep   `json:"steps"`
+}
+
+// runTimeline is the timeline of a run being profiled
+type runTimeline struct {
+	profile RunProfile
+	// step is the superstep in progress, nil between steps
+	step *Superstep
+	// stepStart is when the next superstep is scheduled
+	stepStart time.Duration
+	// open maps the nodes of the current step to their index in step.Nodes
+	open map[string]int
+}
+
+// Profiler records the execution timeline of graph runs: each superstep, the nodes
+// inside it with their start and end offsets, queueing and running time and retry
+// attempts. It is used as a callback handler in Config.Callbacks and can also be added
+// as a NodeListener to the nodes of a ListenableMessageGraph.
+type Profiler struct {
+	NoOpCallbackHandler
+
+	mu       sync.Mutex
+	runs     map[string]*runTimeline
+	order    []string
+	nodeRuns map[string]string
+	now      func() time.Time
+}
+
+// NewProfiler creates a new profiler
+func NewProfiler() *Profiler {
+	return &Profiler{
+		runs:     make(map[string]*runTimeline),
+		nodeRuns: make(map[string]string),
+		now:      time.Now,
+	}
+}
+
+// timeline returns the timeline of a run, starting it if needed. Must be called with mu held.
+func (p *Profiler) timeline(runID string) *runTimeline {
+	tl, ok := p.runs[runID]
+	if !ok {
+		tl = &runTimeline{
+			profile: RunProfile{RunID: runID, StartTime: p.now()},
+			open:    make(map[string]int),
+		}
+		p.runs[runID] = tl
+		p.order = append(p.order, runID)
+	}
+	return tl
+}
+
+// offset returns the time elapsed since the start of the run
+func (p *Profiler) offset(tl *runTimeline) time.Duration {
+	return p.now().Sub(tl.profile.StartTime)
+}
+
+// nodeStarted records the start of a node in the current superstep
+func (p *Profiler) nodeStarted(ctx context.Context, node string) {
+	runID := RunIDFromContext(ctx)
+	if runID == "" {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	tl := p.timeline(runID)
+	if tl.step == nil {
+		tl.step = &Superstep{Index: len(tl.profile.Steps) + 1, Start: tl.stepStart}
+	}
+	if _, ok := tl.open[node]; ok {
+		return
+	}
+	now := p.offset(tl)
+	tl.step.Nodes = append(tl.step.Nodes, NodeSpan{
+		Node:     node,
+		Queued:   tl.step.Start,
+		Start:    now,
+		End:      now,
+		Attempts: 1,
+	})
+	tl.open[node] = len(tl.step.Nodes) - 1
+}
+
+// span applies update to the span of a node of the current superstep
+func (p *Profiler) span(ctx context.Context, node string, update func(tl *runTimeline, span *NodeSpan)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	tl, ok := p.runs[RunIDFromContext(ctx)]
+	if !ok || tl.step == nil {
+		return
+	}
+	if i, ok := tl.open[node]; ok {
+		update(tl, &tl.step.Nodes[i])
+	}
+}
+
+// nodeEnded records the end of a node
+func (p *Profiler) nodeEnded(ctx context.Context, node string, err error) {
+	p.span(ctx, node, func(tl *runTimeline, span *NodeSpan) {
+		span.End = p.offset(tl)
+		if err != nil {
+			span.Error = err.Error()
+		}
+	})
+}
+
+// OnChainStart implements CallbackHandler, starting the timeline of a graph run
+func (p *Profiler) OnChainStart(ctx context.Context, serialized map[string]interface{}, inputs map[string]interface{}, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	if runID != RunIDFromContext(ctx) {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.timeline(runID)
+}
+
+// OnChainEnd implements CallbackHandler, finishing the timeline of a graph run
+func (p *Profiler) OnChainEnd(ctx context.Context, outputs map[string]interface{}, runID string) {
+	p.finishRun(runID)
+}
+
+// OnChainError implements CallbackHandler, finishing the timeline of a failed graph run
+func (p *Profiler) OnChainError(ctx context.Context, err error, runID string) {
+	p.finishRun(runID)
+}
+
+func (p *Profiler) finishRun(runID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	tl, ok := p.runs[runID]
+	if !ok {
+		return
+	}
+	now := p.offset(tl)
+	if tl.step != nil {
+		// The run stopped in the middle of a superstep, e.g. because a node failed
+		tl.step.End = now
+		tl.profile.Steps = append(tl.profile.Steps, *tl.step)
+		tl.step = nil
+	}
+	tl.profile.Duration = now
+}
+
+// OnToolStart implements CallbackHandler. Tool runs directly under the graph run are
+// node executions.
+func (p *Profiler) OnToolStart(ctx context.Context, serialized map[string]interface{}, inputStr string, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	if parentRunID == nil || *parentRunID != RunIDFromContext(ctx) {
+		return
+	}
+	node, _ := serialized["name"].(string)
+	p.mu.Lock()
+	p.nodeRuns[runID] = node
+	p.mu.Unlock()
+	p.nodeStarted(ctx, node)
+}
+
+// OnToolEnd implements CallbackHandler
+func (p *Profiler) OnToolEnd(ctx context.Context, output string, runID string) {
+	p.endNodeRun(ctx, runID, nil)
+}
+
+// OnToolError implements CallbackHandler
+func (p *Profiler) OnToolError(ctx context.Context, err error, runID string) {
+	p.endNodeRun(ctx, runID, err)
+}
+
+func (p *Profiler) endNodeRun(ctx context.Context, runID string, err error) {
+	p.mu.Lock()
+	node, ok := p.nodeRuns[runID]
+	delete(p.nodeRuns, runID)
+	p.mu.Unlock()
+	if ok {
+		p.nodeEnded(ctx, node, err)
+	}
+}
+
+// OnGraphStep implements GraphCallbackHandler, closing the current superstep
+func (p *Profiler) OnGraphStep(ctx context.Context, stepNode string, state interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	tl, ok := p.runs[RunIDFromContext(ctx)]
+	if !ok || tl.step == nil {
+		return
+	}
+	now := p.offset(tl)
+	tl.step.End = now
+	tl.profile.Steps = append(tl.profile.Steps, *tl.step)
+	tl.profile.Duration = now
+	tl.step = nil
+	tl.stepStart = now
+	tl.open = make(map[string]int)
+}
+
+// OnNodeEvent implements NodeListener for the nodes of a ListenableMessageGraph
+func (p *Profiler) OnNodeEvent(ctx context.Context, event NodeEvent, nodeName string, state interface{}, err error) {
+	switch event {
+	case NodeEventStart:
+		p.nodeStarted(ctx, nodeName)
+	case NodeEventComplete, NodeEventError:
+		p.nodeEnded(ctx, nodeName, err)
+	}
+}
+
+// OnNodeRetry implements ResilienceCallbackHandler, counting the attempts of a node
+func (p *Profiler) OnNodeRetry(ctx context.Context, nodeName string, attempt int, err error) {
+	p.span(ctx, nodeName, func(tl *runTimeline, span *NodeSpan) {
+		if attempt > span.Attempts {
+			span.Attempts = attempt
+		}
+	})
+}
+
+// OnCircuitBreakerOpen implements ResilienceCallbackHandler
+func (p *Profiler) OnCircuitBreakerOpen(ctx context.Context, nodeName string) {}
+
+// OnRateLimited implements ResilienceCallbackHandler
+func (p *Profiler) OnRateLimited(ctx context.Context, nodeName string, wait time.Duration) {}
+
+// OnCacheHit implements CacheCallbackHandler
+func (p *Profiler) OnCacheHit(ctx context.Context, nodeName string, key string) {
+	p.span(ctx, nodeName, func(tl *runTimeline, span *NodeSpan) {
+		span.CacheHit = true
+	})
+}
+
+// snapshot copies the profile of a run, including the superstep in progress
+func (tl *runTimeline) snapshot() RunProfile {
+	profile := tl.profile
+	profile.Steps = make([]Superstep, 0, len(tl.profile.Steps)+1)
+	for _, step := range tl.profile.Steps {
+		step.Nodes = append([]NodeSpan(nil), step.Nodes...)
+		profile.Steps = append(profile.Steps, step)
+	}
+	if tl.step != nil {
+		step := *tl.step
+		step.Nodes = append([]NodeSpan(nil), step.Nodes...)
+		for _, span := range step.Nodes {
+			if span.End > step.End {
+				step.End = span.End
+			}
+		}
+		profile.Steps = append(profile.Steps, step)
+		if step.End > profile.Duration {
+			profile.Duration = step.End
+		}
+	}
+	return profile
+}
+
+// Profiles returns the profiles of the runs recorded so far, in start order
+func (p *Profiler) Profiles() []RunProfile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	profiles := make([]RunProfile, 0, len(p.order))
+	for _, runID := range p.order {
+		profiles = append(profiles, p.runs[runID].snapshot())
+	}
+	return profiles
+}
+
+// Profile returns the profile of a run
+func (p *Profiler) Profile(runID string) (RunProfile, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	tl, ok := p.runs[runID]
+	if !ok {
+		return RunProfile{}, false
+	}
+	return tl.snapshot(), true
+}
+
+// LastProfile returns the profile of the most recently started run
+func (p *Profiler) LastProfile() (RunProfile, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.order) == 0 {
+		return RunProfile{}, false
+	}
+	return p.runs[p.order[len(p.order)-1]].snapshot(), true
+}
+
+// Reset discards all recorded runs
+func (p *Profiler) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.runs = make(map[string]*runTimeline)
+	p.order = nil
+	p.nodeRuns = make(map[string]string)
+}
+
+// traceEvent is an event of the Chrome trace event format
+type traceEvent struct {
+	Name      string                 `json:"name"`
+	Category  string                 `json:"cat,omitempty"`
+	Phase     string                 `json:"ph"`
+	Timestamp int64                  `json:"ts"`
+	Duration  int64                  `json:"dur,omitempty"`
+	PID       int                    `json:"pid"`
+	TID       int                    `json:"tid"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+}
+
+// WriteChromeTrace writes the profile in the Chrome trace event format, which can be
+// opened in Perfetto or chrome://tracing. Supersteps are shown on the first track and
+// the nodes of each superstep on one track per parallel branch, with their queueing
+// time as a separate slice before their execution.
+func (rp RunProfile) WriteChromeTrace(w io.Writer) error {
+	base := rp.StartTime.UnixMicro()
+	events := []traceEvent{
+		{Name: "process_name", Phase: "M", PID: 1, Args: map[string]interface{}{"name": "run " + rp.RunID}},
+		{Name: "thread_name", Phase: "M", PID: 1, TID: 0, Args: map[string]interface{}{"name": "supersteps"}},
+	}
+
+	lanes := 0
+	for _, step := range rp.Steps {
+		events = append(events, traceEvent{
+			Name:      fmt.Sprintf("step %d", step.Index),
+			Category:  "superstep",
+			Phase:     "X",
+			Timestamp: base + step.Start.Microseconds(),
+			Duration:  step.Duration().Microseconds(),
+			PID:       1,
+			TID:       0,
+			Args:      map[string]interface{}{"nodes": len(step.Nodes)},
+		})
+
+		for i, span := range step.Nodes {
+			lane := i + 1
+			for lanes < lane {
+				lanes++
+				events = append(events, traceEvent{Name: "thread_name", Phase: "M", PID: 1, TID: lanes, Args: map[string]interface{}{"name": fmt.Sprintf("branch %d", lanes)}})
+			}
+
+			if queue := span.QueueTime(); queue > 0 {
+				events = append(events, traceEvent{
+					Name:      span.Node + " (queued)",
+					Category:  "queue",
+					Phase:     "X",
+					Timestamp: base + span.Queued.Microseconds(),
+					Duration:  queue.Microseconds(),
+					PID:       1,
+					TID:       lane,
+				})
+			}
+
+			args := map[string]interface{}{"step": step.Index, "attempts": span.Attempts}
+			if span.Error != "" {
+				args["error"] = span.Error
+			}
+			if span.CacheHit {
+				args["cache_hit"] = true
+			}
+			events = append(events, traceEvent{
+				Name:      span.Node,
+				Category:  "node",
+				Phase:     "X",
+				Timestamp: base + span.Start.Microseconds(),
+				Duration:  span.RunTime().Microseconds(),
+				PID:       1,
+				TID:       lane,
+				Args:      args,
+			})
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]interface{}{
+		"traceEvents":     events,
+		"displayTimeUnit": "ms",
+	})
+}
+
+// summaryWidth is the width of the timeline bars of WriteSummary
+const summaryWidth = 40
+
+// WriteSummary writes a text flamegraph of the profile: one bar per superstep and per
+// node, positioned on the timeline of the run, with queueing shown as ░ and running
+// as █, followed by the slowest nodes overall
+func (rp RunProfile) WriteSummary(w io.Writer) {
+	nodes := 0
+	nameWidth := len("step 00")
+	for _, step := range rp.Steps {
+		nodes += len(step.Nodes)
+		for _, span := range step.Nodes {
+			if len(span.Node)+2 > nameWidth {
+				nameWidth = len(span.Node) + 2
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "Run %s: %v, %d supersteps, %d node executions\n", rp.RunID, rp.Duration, len(rp.Steps), nodes)
+
+	var all []NodeSpan
+	for _, step := range rp.Steps {
+		label := fmt.Sprintf("step %d", step.Index)
+		fmt.Fprintf(w, "%-*s |%s| %v\n", nameWidth, label, rp.bar(step.Start, step.Start, step.End), step.Duration())
+		for _, span := range step.Nodes {
+			all = append(all, span)
+			details := fmt.Sprintf("run %v", span.RunTime())
+			if queue := span.QueueTime(); queue > 0 {
+				details += fmt.Sprintf(", queued %v", queue)
+			}
+			if span.Attempts > 1 {
+				details += fmt.Sprintf(", %d attempts", span.Attempts)
+			}
+			if span.CacheHit {
+				details += ", cached"
+			}
+			if span.Error != "" {
+				details += ", error: " + span.Error
+			}
+			fmt.Fprintf(w, "  %-*s |%s| %s\n", nameWidth-2, span.Node, rp.bar(span.Queued, span.Start, span.End), details)
+		}
+	}
+
+	if len(all) == 0 {
+		return
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].RunTime() > all[j].RunTime()
+	})
+	if len(all) > 5 {
+		all = all[:5]
+	}
+	fmt.Fprintln(w, "Slowest nodes:")
+	for _, span := range all {
+		share := 0.0
+		if rp.Duration > 0 {
+			share = 100 * float64(span.RunTime()) / float64(rp.Duration)
+		}
+		fmt.Fprintf(w, "  %-*s %v (%.1f%%)\n", nameWidth-2, span.Node, span.RunTime(), share)
+	}
+}
+
+// bar renders an interval of the run as a timeline bar, queueing from queued to start
+// and running from start to end
+func (rp RunProfile) bar(queued, start, end time.Duration) string {
+	column := func(d time.Duration) int {
+		if rp.Duration <= 0 {
+			return 0
+		}
+		c := int(int64(d) * summaryWidth / int64(rp.Duration))
+		if c > summaryWidth {
+			c = summaryWidth
+		}
+		return c
+	}
+
+	q, s, e := column(queued), column(start), column(end)
+	if e == s && end > start {
+		// Keep short executions visible
+		if e < summaryWidth {
+			e++
+		} else {
+			s--
+		}
+	}
+	if q > s {
+		q = s
+	}
+	var sb strings.Builder
+	sb.WriteString(strings.Repeat(" ", q))
+	sb.WriteString(strings.Repeat("░", s-q))
+	sb.WriteString(strings.Repeat("█", e-s))
+	sb.WriteString(strings.Repeat(" ", summaryWidth-e))
+	return sb.String()
+}
+
+var (
+	_ GraphCallbackHandler      = (*Profiler)(nil)
+	_ ResilienceCallbackHandler = (*Profiler)(nil)
+	_ CacheCallbackHandler      = (*Profiler)(nil)
+	_ NodeListener              = (*Profiler)(nil)
+)
@@ -0,0 +1,154 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sleepNode(d time.Duration, out string) func(context.Context, interface{}) (interface{}, error) {
+	return func(ctx context.Context, state interface{}) (interface{}, error) {
+		time.Sleep(d)
+		return out, nil
+	}
+}
+
+func TestProfiler_ParallelSupersteps(t *testing.T) {
+	g := NewMessageGraph()
+	g.AddNode("start", "start", sleepNode(5*time.Millisecond, "start"))
+	g.AddNode("fast", "fast", sleepNode(10*time.Millisecond, "fast"))
+	var calls int32
+	g.AddNodeWithRetry("slow", "slow", func(ctx context.Context, state interface{}) (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return nil, errors.New("flaky")
+		}
+		time.Sleep(30 * time.Millisecond)
+		return "slow", nil
+	}, &RetryConfig{MaxAttempts: 3, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond, BackoffFactor: 1})
+	g.SetEntryPoint("start")
+	g.AddEdge("start", "fast")
+	g.AddEdge("start", "slow")
+	g.AddEdge("fast", END)
+	g.AddEdge("slow", END)
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	profiler := NewProfiler()
+	_, err = runnable.InvokeWithConfig(context.Background(), "input", &Config{Callbacks: []CallbackHandler{profiler}})
+	assert.NoError(t, err)
+
+	profile, ok := profiler.LastProfile()
+	assert.True(t, ok)
+	assert.Len(t, profiler.Profiles(), 1)
+	assert.Len(t, profile.Steps, 2)
+
+	first := profile.Steps[0]
+	assert.Equal(t, 1, first.Index)
+	assert.Len(t, first.Nodes, 1)
+	assert.Equal(t, "start", first.Nodes[0].Node)
+	assert.GreaterOrEqual(t, first.Nodes[0].RunTime(), 5*time.Millisecond)
+
+	second := profile.Steps[1]
+	assert.Len(t, second.Nodes, 2)
+	assert.GreaterOrEqual(t, second.Start, first.End)
+	spans := make(map[string]NodeSpan)
+	for _, span := range second.Nodes {
+		spans[span.Node] = span
+		assert.Equal(t, second.Start, span.Queued)
+		assert.GreaterOrEqual(t, span.QueueTime(), time.Duration(0))
+		assert.LessOrEqual(t, span.End, second.End)
+	}
+	assert.Equal(t, 1, spans["fast"].Attempts)
+	assert.Equal(t, 3, spans["slow"].Attempts)
+	assert.GreaterOrEqual(t, spans["slow"].RunTime(), 30*time.Millisecond)
+	// The superstep lasts as long as its slowest node
+	assert.GreaterOrEqual(t, second.Duration(), spans["slow"].RunTime())
+	assert.GreaterOrEqual(t, profile.Duration, second.End)
+
+	// Chrome trace
+	var buf bytes.Buffer
+	assert.NoError(t, profile.WriteChromeTrace(&buf))
+	var trace struct {
+		TraceEvents []traceEvent `json:"traceEvents"`
+	}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &trace))
+	var names []string
+	for _, event := range trace.TraceEvents {
+		if event.Phase == "X" && event.Category != "queue" {
+			names = append(names, event.Name)
+			assert.GreaterOrEqual(t, event.Timestamp, profile.StartTime.UnixMicro())
+		}
+		if event.Name == "slow" {
+			assert.Equal(t, float64(3), event.Args["attempts"])
+		}
+	}
+	assert.ElementsMatch(t, []string{"step 1", "start", "step 2", "fast", "slow"}, names)
+
+	// Text summary
+	buf.Reset()
+	profile.WriteSummary(&buf)
+	summary := buf.String()
+	assert.Contains(t, summary, "2 supersteps, 3 node executions")
+	assert.Contains(t, summary, "3 attempts")
+	assert.Contains(t, summary, "█")
+	assert.Contains(t, summary, "Slowest nodes:\n  slow")
+	for _, line := range strings.Split(strings.TrimSpace(summary), "\n") {
+		if strings.Contains(line, "|") {
+			bar := strings.Split(line, "|")[1]
+			assert.Equal(t, summaryWidth, len([]rune(bar)), line)
+		}
+	}
+}
+
+func TestProfiler_NodeError(t *testing.T) {
+	g := NewStateGraph()
+	g.AddNode("fail", "fail", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	g.AddEdge("fail", END)
+	g.SetEntryPoint("fail")
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	profiler := NewProfiler()
+	_, err = runnable.InvokeWithConfig(context.Background(), "input", &Config{Callbacks: []CallbackHandler{profiler}})
+	assert.Error(t, err)
+
+	profile, ok := profiler.LastProfile()
+	assert.True(t, ok)
+	assert.Len(t, profile.Steps, 1)
+	assert.Equal(t, "boom", profile.Steps[0].Nodes[0].Error)
+
+	profiler.Reset()
+	_, ok = profiler.LastProfile()
+	assert.False(t, ok)
+}
+
+func TestProfiler_NodeListener(t *testing.T) {
+	profiler := NewProfiler()
+	g := NewListenableMessageGraph()
+	g.AddNode("a", "a", sleepNode(time.Millisecond, "a")).AddListener(profiler)
+	g.AddNode("b", "b", sleepNode(time.Millisecond, "b")).AddListener(profiler)
+	g.SetEntryPoint("a")
+	g.AddEdge("a", "b")
+	g.AddEdge("b", END)
+	runnable, err := g.CompileListenable()
+	assert.NoError(t, err)
+
+	_, err = runnable.InvokeWithConfig(context.Background(), "input", &Config{Callbacks: []CallbackHandler{profiler}})
+	assert.NoError(t, err)
+
+	profile, ok := profiler.LastProfile()
+	assert.True(t, ok)
+	assert.Len(t, profile.Steps, 2)
+	assert.Equal(t, "a", profile.Steps[0].Nodes[0].Node)
+	assert.Equal(t, "b", profile.Steps[1].Nodes[0].Node)
+	assert.GreaterOrEqual(t, profile.Steps[1].Nodes[0].RunTime(), time.Millisecond)
+}
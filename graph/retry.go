@@ -2,21 +2,121 @@ package graph
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"math"
 	"math/rand"
+	"sort"
 	"time"
+
+	"github.com/smallnest/langgraphgo/log"
+)
+
+var (
+	// ErrNodeTimeout is matched by the errors of nodes that exceeded their timeout
+	ErrNodeTimeout = errors.New("node timed out")
+
+	// ErrCircuitOpen is matched by the errors of calls rejected by an open circuit breaker
+	ErrCircuitOpen = errors.New("circuit breaker open")
+
+	// ErrRateLimited is matched by the errors of calls rejected by a rate limiter
+	ErrRateLimited = errors.New("rate limit exceeded")
 )
 
+// NodeTimeoutError is returned when a node exceeds its timeout.
+// It matches ErrNodeTimeout and context.DeadlineExceeded.
+type NodeTimeoutError struct {
+	Node    string
+	Timeout time.Duration
+}
+
+func (e *NodeTimeoutError) Error() string {
+	return fmt.Sprintf("node %s timed out after %v", e.Node, e.Timeout)
+}
+
+// Is reports whether target is ErrNodeTimeout or context.DeadlineExceeded
+func (e *NodeTimeoutError) Is(target error) bool {
+	return target == ErrNodeTimeout || target == context.DeadlineExceeded
+}
+
+// RetryExhaustedError is returned when a node still fails after its last attempt
+type RetryExhaustedError struct {
+	Node     string
+	Attempts int
+	Err      error
+}
+
+func (e *RetryExhaustedError) Error() string {
+	return fmt.Sprintf("max retries (%d) exceeded for %s: %v", e.Attempts, e.Node, e.Err)
+}
+
+func (e *RetryExhaustedError) Unwrap() error {
+	return e.Err
+}
+
 // RetryConfig configures retry behavior for nodes
 type RetryConfig struct {
 	MaxAttempts     int
 	InitialDelay    time.Duration
-	MaxDelay        time.Duration
+	MaxDelay        time.Duration // Caps the backoff delay; zero means no cap
 	BackoffFactor   float64
+	Jitter          float64          // Randomizes each delay by up to this fraction, e.g. 0.2 for ±20%
 	RetryableErrors func(error) bool // Determines if an error should trigger retry
 }
 
+// RetryOnErrors returns a RetryableErrors predicate matching errors that wrap any of
+// the targets, as reported by errors.Is
+func RetryOnErrors(targets ...error) func(error) bool {
+	return func(err error) bool {
+		for _, target := range targets {
+			if errors.Is(err, target) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RetryOnErrorType returns a RetryableErrors predicate matching errors that wrap an
+// error of type T, as reported by errors.As
+func RetryOnErrorType[T error]() func(error) bool {
+	return func(err error) bool {
+		var target T
+		return errors.As(err, &target)
+	}
+}
+
+type attemptKey struct{}
+
+// withAttempt marks the context as belonging to the given attempt of a node execution,
+// which is also attached to records logged with the context
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	ctx = context.WithValue(ctx, attemptKey{}, attempt)
+	return log.ContextWithAttrs(ctx, slog.Int(log.KeyAttempt, attempt))
+}
+
+// AttemptFromContext returns the attempt number, starting at 1, of the node execution
+// running with the context. It is 1 for nodes that are not retried.
+func AttemptFromContext(ctx context.Context) int {
+	if attempt, ok := ctx.Value(attemptKey{}).(int); ok {
+		return attempt
+	}
+	return 1
+}
+
+// jitterDelay randomizes a delay by up to the given fraction in either direction
+func jitterDelay(delay time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 || delay <= 0 {
+		return delay
+	}
+	if jitter > 1 {
+		jitter = 1
+	}
+	factor := 1 + jitter*(2*rand.Float64()-1)
+	return time.Duration(float64(delay) * factor)
+}
+
 // DefaultRetryConfig returns a default retry configuration
 func DefaultRetryConfig() *RetryConfig {
 	return &RetryConfig{
@@ -31,6 +131,32 @@ func DefaultRetryConfig() *RetryConfig {
 	}
 }
 
+// ResilienceCallbackHandler receives events from the retry, circuit breaker and rate
+// limiting wrappers. Callbacks in the graph config that implement it are notified.
+type ResilienceCallbackHandler interface {
+	// OnNodeRetry is called before a failed node is retried; attempt is the upcoming attempt number
+	OnNodeRetry(ctx context.Context, nodeName string, attempt int, err error)
+
+	// OnCircuitBreakerOpen is called when a circuit breaker trips open
+	OnCircuitBreakerOpen(ctx context.Context, nodeName string)
+
+	// OnRateLimited is called when a rate limiter rejects a call; wait is the time until a call is allowed
+	OnRateLimited(ctx context.Context, nodeName string, wait time.Duration)
+}
+
+// notifyResilience reports an event to callbacks in the config that implement ResilienceCallbackHandler
+func notifyResilience(ctx context.Context, notify func(ResilienceCallbackHandler)) {
+	config := GetConfig(ctx)
+	if config == nil {
+		return
+	}
+	for _, cb := range config.Callbacks {
+		if rcb, ok := cb.(ResilienceCallbackHandler); ok {
+			notify(rcb)
+		}
+	}
+}
+
 // RetryNode wraps a node with retry logic
 type RetryNode struct {
 	node   Node
@@ -48,7 +174,8 @@ func NewRetryNode(node Node, config *RetryConfig) *RetryNode {
 	}
 }
 
-// Execute runs the node with retry logic
+// Execute runs the node with retry logic. Each attempt runs with its number in the
+// context (see AttemptFromContext) and is recorded on the trace span of the node.
 func (rn *RetryNode) Execute(ctx context.Context, state interface{}) (interface{}, error) {
 	var lastErr error
 	delay := rn.config.InitialDelay
@@ -61,8 +188,12 @@ func (rn *RetryNode) Execute(ctx context.Context, state interface{}) (interface{
 		default:
 		}
 
+		if span := SpanFromContext(ctx); span != nil && span.NodeName == rn.node.Name && span.Metadata != nil {
+			span.Metadata["attempts"] = attempt
+		}
+
 		// Execute the node
-		result, err := rn.node.Function(ctx, state)
+		result, err := rn.node.Function(withAttempt(ctx, attempt), state)
 		if err == nil {
 			return result, nil
 		}
@@ -76,12 +207,16 @@ func (rn *RetryNode) Execute(ctx context.Context, state interface{}) (interface{
 
 		// Don't sleep after the last attempt
 		if attempt < rn.config.MaxAttempts {
+			notifyResilience(ctx, func(h ResilienceCallbackHandler) {
+				h.OnNodeRetry(ctx, rn.node.Name, attempt+1, err)
+			})
+
 			// Sleep with exponential backoff
 			select {
-			case <-time.After(delay):
+			case <-time.After(jitterDelay(delay, rn.config.Jitter)):
 				// Calculate next delay with backoff
 				delay = time.Duration(float64(delay) * rn.config.BackoffFactor)
-				if delay > rn.config.MaxDelay {
+				if rn.config.MaxDelay > 0 && delay > rn.config.MaxDelay {
 					delay = rn.config.MaxDelay
 				}
 			case <-ctx.Done():
@@ -90,8 +225,7 @@ func (rn *RetryNode) Execute(ctx context.Context, state interface{}) (interface{
 		}
 	}
 
-	return nil, fmt.Errorf("max retries (%d) exceeded for %s: %w",
-		rn.config.MaxAttempts, rn.node.Name, lastErr)
+	return nil, &RetryExhaustedError{Node: rn.node.Name, Attempts: rn.config.MaxAttempts, Err: lastErr}
 }
 
 // AddNodeWithRetry adds a node with retry logic
@@ -148,7 +282,11 @@ func (tn *TimeoutNode) Execute(ctx context.Context, state interface{}) (interfac
 	case res := <-resultChan:
 		return res.value, res.err
 	case <-timeoutCtx.Done():
-		return nil, fmt.Errorf("node %s timed out after %v", tn.node.Name, tn.timeout)
+		if err := ctx.Err(); err != nil {
+			// The caller gave up, not the node
+			return nil, err
+		}
+		return nil, &NodeTimeoutError{Node: tn.node.Name, Timeout: tn.timeout}
 	}
 }
 
@@ -174,6 +312,8 @@ type CircuitBreakerConfig struct {
 	SuccessThreshold int           // Number of successes before closing
 	Timeout          time.Duration // Time before attempting to close
 	HalfOpenMaxCalls int           // Max calls in half-open state
+	Store            LimiterStore  // Holds the circuit state; nil keeps it in process memory
+	Key              string        // Key of the circuit in Store; defaults to the node name
 }
 
 // CircuitBreakerState represents the state of a circuit breaker
@@ -187,45 +327,38 @@ const (
 
 // CircuitBreaker implements the circuit breaker pattern
 type CircuitBreaker struct {
-	node            Node
-	config          CircuitBreakerConfig
-	state           CircuitBreakerState
-	failures        int
-	successes       int
-	lastFailureTime time.Time
-	halfOpenCalls   int
+	node   Node
+	config CircuitBreakerConfig
+	store  LimiterStore
+	key    string
 }
 
 // NewCircuitBreaker creates a new circuit breaker
 func NewCircuitBreaker(node Node, config CircuitBreakerConfig) *CircuitBreaker {
-	return &CircuitBreaker{
+	cb := &CircuitBreaker{
 		node:   node,
 		config: config,
-		state:  CircuitClosed,
+		store:  config.Store,
+		key:    config.Key,
 	}
+	if cb.store == nil {
+		cb.store = NewMemoryLimiterStore()
+	}
+	if cb.key == "" {
+		cb.key = node.Name
+	}
+	return cb
 }
 
-// Execute runs the node with circuit breaker logic
+// Execute runs the node with circuit breaker logic. Calls rejected while the circuit
+// is open return an error matching ErrCircuitOpen.
 func (cb *CircuitBreaker) Execute(ctx context.Context, state interface{}) (interface{}, error) {
-	// Check circuit state
-	switch cb.state {
-	case CircuitClosed:
-		// Circuit is closed, proceed normally
-	case CircuitOpen:
-		// Check if enough time has passed to try again
-		if time.Since(cb.lastFailureTime) > cb.config.Timeout {
-			cb.state = CircuitHalfOpen
-			cb.halfOpenCalls = 0
-		} else {
-			return nil, fmt.Errorf("circuit breaker open for %s", cb.node.Name)
-		}
-	case CircuitHalfOpen:
-		// Check if we've made too many calls in half-open state
-		if cb.halfOpenCalls >= cb.config.HalfOpenMaxCalls {
-			cb.state = CircuitOpen
-			return nil, fmt.Errorf("circuit breaker half-open limit reached for %s", cb.node.Name)
-		}
-		cb.halfOpenCalls++
+	allowed, err := cb.store.AllowCall(ctx, cb.key, cb.config)
+	if err != nil {
+		return nil, fmt.Errorf("circuit breaker store error for %s: %w", cb.node.Name, err)
+	}
+	if !allowed {
+		return nil, fmt.Errorf("%w for %s", ErrCircuitOpen, cb.node.Name)
 	}
 
 	// Execute the node
@@ -233,25 +366,21 @@ func (cb *CircuitBreaker) Execute(ctx context.Context, state interface{}) (inter
 
 	// Update circuit breaker state based on result
 	if err != nil {
-		cb.failures++
-		cb.successes = 0
-		cb.lastFailureTime = time.Now()
-
-		if cb.failures >= cb.config.FailureThreshold {
-			cb.state = CircuitOpen
+		opened, storeErr := cb.store.RecordFailure(ctx, cb.key, cb.config)
+		if storeErr != nil {
+			return nil, fmt.Errorf("circuit breaker store error for %s: %w", cb.node.Name, storeErr)
+		}
+		if opened {
+			notifyResilience(ctx, func(h ResilienceCallbackHandler) {
+				h.OnCircuitBreakerOpen(ctx, cb.node.Name)
+			})
 		}
-
 		return nil, fmt.Errorf("circuit breaker error in %s: %w", cb.node.Name, err)
 	}
 
-	// Success
-	cb.successes++
-	cb.failures = 0
-
-	if cb.state == CircuitHalfOpen && cb.successes >= cb.config.SuccessThreshold {
-		cb.state = CircuitClosed
+	if err := cb.store.RecordSuccess(ctx, cb.key, cb.config); err != nil {
+		return nil, fmt.Errorf("circuit breaker store error for %s: %w", cb.node.Name, err)
 	}
-
 	return result, nil
 }
 
@@ -271,48 +400,93 @@ func (g *MessageGraph) AddNodeWithCircuitBreaker(
 	g.AddNode(name, description, cb.Execute)
 }
 
+// RateLimitConfig configures rate limiting for nodes. Calls are limited with a token
+// bucket holding MaxCalls tokens, refilled at MaxCalls per Window.
+type RateLimitConfig struct {
+	MaxCalls int           // Number of calls allowed per window
+	Window   time.Duration // Time to refill the bucket
+	Wait     bool          // Wait for a call to be allowed instead of failing
+	MaxWait  time.Duration // Fails calls that would wait longer; zero means no limit
+	Store    LimiterStore  // Holds the limiter state; nil keeps it in process memory
+	Key      string        // Key of the limiter in Store; defaults to the node name
+}
+
+// validate checks that the config allows calls at a finite rate
+func (c RateLimitConfig) validate() error {
+	if c.MaxCalls <= 0 {
+		return fmt.Errorf("invalid rate limit: max calls must be positive, got %d", c.MaxCalls)
+	}
+	if c.Window <= 0 {
+		return fmt.Errorf("invalid rate limit: window must be positive, got %v", c.Window)
+	}
+	return nil
+}
+
 // RateLimiter implements rate limiting for nodes
 type RateLimiter struct {
-	node     Node
-	maxCalls int
-	window   time.Duration
-	calls    []time.Time
+	node   Node
+	config RateLimitConfig
+	store  LimiterStore
+	key    string
+	err    error
 }
 
 // NewRateLimiter creates a new rate limiter
 func NewRateLimiter(node Node, maxCalls int, window time.Duration) *RateLimiter {
-	return &RateLimiter{
-		node:     node,
-		maxCalls: maxCalls,
-		window:   window,
-		calls:    make([]time.Time, 0, maxCalls),
+	return NewRateLimiterWithConfig(node, RateLimitConfig{MaxCalls: maxCalls, Window: window})
+}
+
+// NewRateLimiterWithConfig creates a new rate limiter from config. A config without
+// positive MaxCalls and Window is rejected: all calls of the limiter fail.
+func NewRateLimiterWithConfig(node Node, config RateLimitConfig) *RateLimiter {
+	rl := &RateLimiter{
+		node:   node,
+		config: config,
+		store:  config.Store,
+		key:    config.Key,
+		err:    config.validate(),
 	}
+	if rl.store == nil {
+		rl.store = NewMemoryLimiterStore()
+	}
+	if rl.key == "" {
+		rl.key = node.Name
+	}
+	return rl
 }
 
-// Execute runs the node with rate limiting
+// Execute runs the node with rate limiting. Calls rejected by the limiter return an
+// error matching ErrRateLimited; in wait mode they wait for a token instead.
 func (rl *RateLimiter) Execute(ctx context.Context, state interface{}) (interface{}, error) {
-	now := time.Now()
+	if rl.err != nil {
+		return nil, fmt.Errorf("rate limiter for %s: %w", rl.node.Name, rl.err)
+	}
+	for {
+		waitTime, err := rl.store.TakeToken(ctx, rl.key, rl.config.MaxCalls, rl.config.Window)
+		if err != nil {
+			return nil, fmt.Errorf("rate limiter store error for %s: %w", rl.node.Name, err)
+		}
+		if waitTime <= 0 {
+			break
+		}
 
-	// Remove old calls outside the window
-	validCalls := make([]time.Time, 0, rl.maxCalls)
-	for _, callTime := range rl.calls {
-		if now.Sub(callTime) < rl.window {
-			validCalls = append(validCalls, callTime)
+		notifyResilience(ctx, func(h ResilienceCallbackHandler) {
+			h.OnRateLimited(ctx, rl.node.Name, waitTime)
+		})
+		if !rl.config.Wait || (rl.config.MaxWait > 0 && waitTime > rl.config.MaxWait) {
+			return nil, fmt.Errorf("%w for %s, retry after %v", ErrRateLimited, rl.node.Name, waitTime)
 		}
-	}
-	rl.calls = validCalls
 
-	// Check if we're at the limit
-	if len(rl.calls) >= rl.maxCalls {
-		// Calculate when we can make the next call
-		oldestCall := rl.calls[0]
-		waitTime := rl.window - now.Sub(oldestCall)
-		return nil, fmt.Errorf("rate limit exceeded for %s, retry after %v", rl.node.Name, waitTime)
+		// Wait for the next token; other callers may take it first, so try again
+		timer := time.NewTimer(waitTime)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
 	}
 
-	// Record this call
-	rl.calls = append(rl.calls, now)
-
 	// Execute the node
 	return rl.node.Function(ctx, state)
 }
@@ -369,3 +543,98 @@ func ExponentialBackoffRetry(
 
 	return nil, fmt.Errorf("max attempts reached")
 }
+
+// WithRetry retries the node according to config. Unset MaxAttempts and BackoffFactor
+// default to 3 attempts and a factor of 2; use RetryOnErrors or RetryOnErrorType to
+// retry only some errors. When the attempts are exhausted the node fails with a
+// *RetryExhaustedError wrapping the last error.
+func WithRetry(config RetryConfig) NodeOption {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 3
+	}
+	if config.BackoffFactor <= 0 {
+		config.BackoffFactor = 2
+	}
+	return func(n *Node) {
+		c := config
+		n.Retry = &c
+	}
+}
+
+// WithTimeout fails each attempt of the node that runs longer than timeout with a
+// *NodeTimeoutError, which matches ErrNodeTimeout
+func WithTimeout(timeout time.Duration) NodeOption {
+	return func(n *Node) {
+		n.Timeout = timeout
+	}
+}
+
+// WithCircuitBreaker guards the node with a circuit breaker shared by all runs of the
+// graph. Unset fields default to 5 failures, 1 success, a 30s timeout and 1 half-open call.
+func WithCircuitBreaker(config CircuitBreakerConfig) NodeOption {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.SuccessThreshold <= 0 {
+		config.SuccessThreshold = 1
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	if config.HalfOpenMaxCalls <= 0 {
+		config.HalfOpenMaxCalls = 1
+	}
+	return func(n *Node) {
+		c := config
+		n.CircuitBreaker = &c
+	}
+}
+
+// WithRateLimit limits the calls of the node according to config, counting each retry
+// attempt as a call. Use a shared Store, e.g. Redis, to limit the calls of all the
+// processes running the graph. MaxCalls and Window must be positive, otherwise
+// Compile fails.
+func WithRateLimit(config RateLimitConfig) NodeOption {
+	return func(n *Node) {
+		c := config
+		n.RateLimit = &c
+	}
+}
+
+// withResilience wraps the function of a node with its resilience policies. The
+// timeout and rate limit apply to each attempt and the circuit breaker to the node as a
+// whole, so calls rejected by an open circuit are not retried.
+func withResilience(node Node) func(ctx context.Context, state interface{}) (interface{}, error) {
+	fn := node.Function
+	if node.Timeout > 0 {
+		fn = NewTimeoutNode(Node{Name: node.Name, Function: fn}, node.Timeout).Execute
+	}
+	if node.RateLimit != nil {
+		fn = NewRateLimiterWithConfig(Node{Name: node.Name, Function: fn}, *node.RateLimit).Execute
+	}
+	if node.Retry != nil {
+		fn = NewRetryNode(Node{Name: node.Name, Function: fn}, node.Retry).Execute
+	}
+	if node.CircuitBreaker != nil {
+		fn = NewCircuitBreaker(Node{Name: node.Name, Function: fn}, *node.CircuitBreaker).Execute
+	}
+	return fn
+}
+
+// validateNodes checks the resilience policies of the nodes of a graph
+func validateNodes(nodes map[string]Node) error {
+	names := make([]string, 0, len(nodes))
+	for name := range nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if rateLimit := nodes[name].RateLimit; rateLimit != nil {
+			if err := rateLimit.validate(); err != nil {
+				return fmt.Errorf("node %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -3,6 +3,8 @@ package graph_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -551,3 +553,259 @@ func TestExponentialBackoffRetry(t *testing.T) {
 		}
 	})
 }
+
+// retryRecorder records the retries reported to the graph callbacks
+type retryRecorder struct {
+	graph.NoOpCallbackHandler
+	attempts []int
+}
+
+func (r *retryRecorder) OnNodeRetry(_ context.Context, _ string, attempt int, _ error) {
+	r.attempts = append(r.attempts, attempt)
+}
+
+func (r *retryRecorder) OnCircuitBreakerOpen(context.Context, string) {}
+
+func (r *retryRecorder) OnRateLimited(context.Context, string, time.Duration) {}
+
+type temporaryError struct{ msg string }
+
+func (e *temporaryError) Error() string { return e.msg }
+
+func compileSingleNode(t *testing.T, fn func(context.Context, interface{}) (interface{}, error), opts ...graph.NodeOption) *graph.StateRunnable {
+	t.Helper()
+	g := graph.NewStateGraph()
+	g.AddNode("node", "node", fn, opts...)
+	g.AddEdge("node", graph.END)
+	g.SetEntryPoint("node")
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	return runnable
+}
+
+func TestStateGraphNodeRetry(t *testing.T) {
+	t.Parallel()
+
+	errTransient := errors.New("transient")
+
+	t.Run("retries matching errors with attempt numbers", func(t *testing.T) {
+		t.Parallel()
+
+		var seen []int
+		runnable := compileSingleNode(t, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			attempt := graph.AttemptFromContext(ctx)
+			seen = append(seen, attempt)
+			if attempt < 3 {
+				return nil, fmt.Errorf("call failed: %w", errTransient)
+			}
+			return successResult, nil
+		}, graph.WithRetry(graph.RetryConfig{
+			MaxAttempts:     3,
+			InitialDelay:    time.Millisecond,
+			MaxDelay:        2 * time.Millisecond,
+			Jitter:          0.5,
+			RetryableErrors: graph.RetryOnErrors(errTransient),
+		}))
+
+		recorder := &retryRecorder{}
+		result, err := runnable.InvokeWithConfig(context.Background(), "input", &graph.Config{
+			Callbacks: []graph.CallbackHandler{recorder},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != successResult {
+			t.Errorf("expected %q, got %v", successResult, result)
+		}
+		if fmt.Sprint(seen) != "[1 2 3]" {
+			t.Errorf("expected attempts [1 2 3], got %v", seen)
+		}
+		if fmt.Sprint(recorder.attempts) != "[2 3]" {
+			t.Errorf("expected retry callbacks for attempts [2 3], got %v", recorder.attempts)
+		}
+	})
+
+	t.Run("matches error types", func(t *testing.T) {
+		t.Parallel()
+
+		var calls int32
+		runnable := compileSingleNode(t, func(context.Context, interface{}) (interface{}, error) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				return nil, fmt.Errorf("wrapped: %w", &temporaryError{"busy"})
+			}
+			return nil, errors.New("permanent")
+		}, graph.WithRetry(graph.RetryConfig{
+			MaxAttempts:     5,
+			RetryableErrors: graph.RetryOnErrorType[*temporaryError](),
+		}))
+
+		_, err := runnable.Invoke(context.Background(), "input")
+		if err == nil || !strings.Contains(err.Error(), "non-retryable error in node: permanent") {
+			t.Errorf("expected non-retryable error, got %v", err)
+		}
+		if n := atomic.LoadInt32(&calls); n != 2 {
+			t.Errorf("expected 2 calls, got %d", n)
+		}
+	})
+
+	t.Run("exhausted retries are typed", func(t *testing.T) {
+		t.Parallel()
+
+		runnable := compileSingleNode(t, func(context.Context, interface{}) (interface{}, error) {
+			return nil, errTransient
+		}, graph.WithRetry(graph.RetryConfig{MaxAttempts: 2}))
+
+		_, err := runnable.Invoke(context.Background(), "input")
+		var exhausted *graph.RetryExhaustedError
+		if !errors.As(err, &exhausted) {
+			t.Fatalf("expected RetryExhaustedError, got %v", err)
+		}
+		if exhausted.Attempts != 2 || exhausted.Node != "node" {
+			t.Errorf("unexpected error details: %+v", exhausted)
+		}
+		if !errors.Is(err, errTransient) {
+			t.Errorf("expected error to wrap the node error, got %v", err)
+		}
+	})
+
+	t.Run("graph policy does not retry nodes with their own policy", func(t *testing.T) {
+		t.Parallel()
+
+		var calls int32
+		g := graph.NewStateGraph()
+		g.AddNode("node", "node", func(context.Context, interface{}) (interface{}, error) {
+			atomic.AddInt32(&calls, 1)
+			return nil, errTransient
+		}, graph.WithRetry(graph.RetryConfig{MaxAttempts: 2}))
+		g.AddEdge("node", graph.END)
+		g.SetEntryPoint("node")
+		g.SetRetryPolicy(&graph.RetryPolicy{MaxRetries: 3, RetryIf: graph.RetryOnErrors(errTransient)})
+		runnable, _ := g.Compile()
+
+		if _, err := runnable.Invoke(context.Background(), "input"); err == nil {
+			t.Error("expected error")
+		}
+		if n := atomic.LoadInt32(&calls); n != 2 {
+			t.Errorf("expected 2 calls, got %d", n)
+		}
+	})
+}
+
+func TestStateGraphRetryPolicyRetryIf(t *testing.T) {
+	t.Parallel()
+
+	errTransient := errors.New("transient")
+	var seen []int
+	g := graph.NewStateGraph()
+	g.AddNode("node", "node", func(ctx context.Context, _ interface{}) (interface{}, error) {
+		seen = append(seen, graph.AttemptFromContext(ctx))
+		if len(seen) == 1 {
+			return nil, fmt.Errorf("wrapped: %w", errTransient)
+		}
+		return successResult, nil
+	})
+	g.AddEdge("node", graph.END)
+	g.SetEntryPoint("node")
+	g.SetRetryPolicy(&graph.RetryPolicy{MaxRetries: 1, RetryIf: graph.RetryOnErrors(errTransient)})
+	runnable, _ := g.Compile()
+
+	result, err := runnable.Invoke(context.Background(), "input")
+	if err != nil || result != successResult {
+		t.Fatalf("expected success, got %v, %v", result, err)
+	}
+	if fmt.Sprint(seen) != "[1 2]" {
+		t.Errorf("expected attempts [1 2], got %v", seen)
+	}
+}
+
+func TestStateGraphNodeTimeout(t *testing.T) {
+	t.Parallel()
+
+	var calls int32
+	runnable := compileSingleNode(t, func(ctx context.Context, _ interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		select {
+		case <-time.After(time.Second):
+			return successResult, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}, graph.WithTimeout(10*time.Millisecond), graph.WithRetry(graph.RetryConfig{
+		MaxAttempts:     2,
+		RetryableErrors: graph.RetryOnErrors(graph.ErrNodeTimeout),
+	}))
+
+	_, err := runnable.Invoke(context.Background(), "input")
+	if !errors.Is(err, graph.ErrNodeTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+	var timeout *graph.NodeTimeoutError
+	if !errors.As(err, &timeout) || timeout.Timeout != 10*time.Millisecond {
+		t.Errorf("expected NodeTimeoutError, got %v", err)
+	}
+	// The timeout applies to each attempt
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("expected 2 attempts, got %d", n)
+	}
+}
+
+func TestStateGraphNodeCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	var calls int32
+	runnable := compileSingleNode(t, func(context.Context, interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, errors.New("upstream down")
+	}, graph.WithCircuitBreaker(graph.CircuitBreakerConfig{FailureThreshold: 2, Timeout: time.Hour}))
+
+	for i := 0; i < 2; i++ {
+		if _, err := runnable.Invoke(context.Background(), "input"); errors.Is(err, graph.ErrCircuitOpen) {
+			t.Fatalf("circuit opened too early: %v", err)
+		}
+	}
+	// The breaker is shared across runs of the graph
+	_, err := runnable.Invoke(context.Background(), "input")
+	if !errors.Is(err, graph.ErrCircuitOpen) {
+		t.Errorf("expected ErrCircuitOpen, got %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("expected 2 calls, got %d", n)
+	}
+}
+
+func TestNodeRetryAttemptsTraced(t *testing.T) {
+	t.Parallel()
+
+	var calls int32
+	g := graph.NewMessageGraph()
+	g.AddNode("node", "node", func(context.Context, interface{}) (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) < 2 {
+			return nil, errors.New("flaky")
+		}
+		return successResult, nil
+	}, graph.WithRetry(graph.RetryConfig{MaxAttempts: 3, InitialDelay: time.Millisecond}))
+	g.AddEdge("node", graph.END)
+	g.SetEntryPoint("node")
+	runnable, _ := g.Compile()
+
+	tracer := graph.NewTracer()
+	runnable.SetTracer(tracer)
+	if _, err := runnable.Invoke(context.Background(), "input"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	found := false
+	for _, span := range tracer.GetSpans() {
+		if span.Event == graph.TraceEventNodeEnd && span.NodeName == "node" {
+			found = true
+			if span.Metadata["attempts"] != 2 {
+				t.Errorf("expected 2 attempts on the node span, got %v", span.Metadata["attempts"])
+			}
+		}
+	}
+	if !found {
+		t.Error("expected a node span")
+	}
+}
@@ -0,0 +1,181 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrShutdown is matched by the errors of runs stopped or rejected because their
+// runtime is shutting down
+var ErrShutdown = errors.New("graph runtime is shutting down")
+
+// Runtime tracks the graph runs of a process so that they can be drained on shutdown.
+// Runs belong to the runtime set with WithRuntime, or to the default runtime.
+type Runtime struct {
+	mu       sync.Mutex
+	draining bool
+	runs     map[*runtimeRun]struct{}
+	wg       sync.WaitGroup
+}
+
+// NewRuntime creates a runtime
+func NewRuntime() *Runtime {
+	return &Runtime{runs: make(map[*runtimeRun]struct{})}
+}
+
+var defaultRuntime = NewRuntime()
+
+// DefaultRuntime returns the runtime of the runs without one in their context
+func DefaultRuntime() *Runtime {
+	return defaultRuntime
+}
+
+// Shutdown drains the runs of the default runtime, see Runtime.Shutdown
+func Shutdown(ctx context.Context) error {
+	return defaultRuntime.Shutdown(ctx)
+}
+
+type runtimeKey struct{}
+
+// WithRuntime returns a context whose graph runs belong to rt
+func WithRuntime(ctx context.Context, rt *Runtime) context.Context {
+	return context.WithValue(ctx, runtimeKey{}, rt)
+}
+
+func runtimeFromContext(ctx context.Context) *Runtime {
+	if rt, ok := ctx.Value(runtimeKey{}).(*Runtime); ok {
+		return rt
+	}
+	return defaultRuntime
+}
+
+// ShuttingDown reports whether Shutdown was called
+func (rt *Runtime) ShuttingDown() bool {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.draining
+}
+
+// Shutdown stops the runtime from starting new runs and lets each in-flight run finish
+// its current superstep, after which the run stops with a *GraphInterrupt matching
+// ErrShutdown whose NextNodes are the nodes left to run. CheckpointableRunnable saves
+// such interrupts so that another process can continue the runs with Resume.
+//
+// Shutdown returns once all runs have stopped, or cancels the runs still in flight
+// and returns the context error when ctx is done first. Cancelled runs stop with a
+// *GraphInterrupt too, whose NextNodes are the nodes of the cancelled superstep.
+//
+// Shutdown is terminal: the runtime rejects new runs afterwards. Processes that keep
+// running graphs, e.g. tests, use a new runtime or call Reset.
+func (rt *Runtime) Shutdown(ctx context.Context) error {
+	rt.mu.Lock()
+	rt.draining = true
+	rt.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		rt.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		rt.mu.Lock()
+		for run := range rt.runs {
+			run.cancel(ErrShutdown)
+		}
+		rt.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Reset lets a runtime that was shut down start runs again. Runs still draining
+// keep stopping at their next superstep.
+func (rt *Runtime) Reset() {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.draining = false
+}
+
+type runtimeRunKey struct{}
+
+// runtimeRun is a top-level graph run registered with a runtime
+type runtimeRun struct {
+	rt     *Runtime
+	cancel context.CancelCauseFunc
+}
+
+// startRun registers a graph run with the runtime of the context. Runs nested in
+// another run, e.g. subgraphs, are not registered and return a nil run, so that they
+// complete with the superstep of their parent.
+func startRun(ctx context.Context) (*runtimeRun, context.Context, error) {
+	if _, nested := ctx.Value(runtimeRunKey{}).(*runtimeRun); nested {
+		return nil, ctx, nil
+	}
+
+	rt := runtimeFromContext(ctx)
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if rt.draining {
+		return nil, ctx, ErrShutdown
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	run := &runtimeRun{rt: rt, cancel: cancel}
+	rt.runs[run] = struct{}{}
+	rt.wg.Add(1)
+	return run, context.WithValue(ctx, runtimeRunKey{}, run), nil
+}
+
+// end unregisters the run
+func (r *runtimeRun) end() {
+	if r == nil {
+		return
+	}
+	r.rt.mu.Lock()
+	delete(r.rt.runs, r)
+	r.rt.mu.Unlock()
+	r.cancel(nil)
+	r.rt.wg.Done()
+}
+
+// interrupt returns the interrupt stopping the run at a superstep boundary when its
+// runtime is shutting down and nodes are left to run, or nil
+func (r *runtimeRun) interrupt(state interface{}, next []string) error {
+	if r == nil || !r.rt.ShuttingDown() {
+		return nil
+	}
+
+	remaining := make([]string, 0, len(next))
+	for _, node := range next {
+		if node != END {
+			remaining = append(remaining, node)
+		}
+	}
+	if len(remaining) == 0 {
+		return nil
+	}
+	return &GraphInterrupt{
+		Node:      remaining[0],
+		State:     state,
+		NextNodes: remaining,
+		Shutdown:  true,
+	}
+}
+
+// cancelled returns the interrupt of a run whose superstep was cancelled by
+// Shutdown, so that the nodes of the superstep run again on resume, or nil
+func (r *runtimeRun) cancelled(ctx context.Context, state interface{}, current []string) error {
+	if r == nil || len(current) == 0 || !errors.Is(context.Cause(ctx), ErrShutdown) {
+		return nil
+	}
+	return &GraphInterrupt{
+		Node:      current[0],
+		State:     state,
+		NextNodes: append([]string(nil), current...),
+		Shutdown:  true,
+	}
+}
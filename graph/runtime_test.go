@@ -0,0 +1,253 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingNode signals started when it runs and returns once release is closed
+func blockingNode(started chan<- struct{}, release <-chan struct{}, out string) func(context.Context, interface{}) (interface{}, error) {
+	return func(ctx context.Context, state interface{}) (interface{}, error) {
+		started <- struct{}{}
+		<-release
+		return state.(string) + out, nil
+	}
+}
+
+func appendNode(out string) func(context.Context, interface{}) (interface{}, error) {
+	return func(ctx context.Context, state interface{}) (interface{}, error) {
+		return state.(string) + out, nil
+	}
+}
+
+func TestRuntimeShutdown_DrainsSuperstep(t *testing.T) {
+	tests := []struct {
+		name    string
+		compile func(started chan<- struct{}, release <-chan struct{}) func(context.Context, interface{}) (interface{}, error)
+	}{
+		{
+			name: "MessageGraph",
+			compile: func(started chan<- struct{}, release <-chan struct{}) func(context.Context, interface{}) (interface{}, error) {
+				g := NewMessageGraph()
+				g.AddNode("A", "A", blockingNode(started, release, "A"))
+				g.AddNode("B", "B", appendNode("B"))
+				g.SetEntryPoint("A")
+				g.AddEdge("A", "B")
+				g.AddEdge("B", END)
+				runnable, err := g.Compile()
+				assert.NoError(t, err)
+				return runnable.Invoke
+			},
+		},
+		{
+			name: "StateGraph",
+			compile: func(started chan<- struct{}, release <-chan struct{}) func(context.Context, interface{}) (interface{}, error) {
+				g := NewStateGraph()
+				g.AddNode("A", "A", blockingNode(started, release, "A"))
+				g.AddNode("B", "B", appendNode("B"))
+				g.SetEntryPoint("A")
+				g.AddEdge("A", "B")
+				g.AddEdge("B", END)
+				runnable, err := g.Compile()
+				assert.NoError(t, err)
+				return runnable.Invoke
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started, release := make(chan struct{}, 1), make(chan struct{})
+			invoke := tt.compile(started, release)
+			rt := NewRuntime()
+			ctx := WithRuntime(context.Background(), rt)
+
+			type outcome struct {
+				state interface{}
+				err   error
+			}
+			done := make(chan outcome, 1)
+			go func() {
+				state, err := invoke(ctx, "start:")
+				done <- outcome{state, err}
+			}()
+			<-started
+
+			shutdown := make(chan error, 1)
+			go func() { shutdown <- rt.Shutdown(context.Background()) }()
+			assert.Eventually(t, rt.ShuttingDown, time.Second, time.Millisecond)
+
+			// New runs are rejected while the current superstep finishes
+			_, err := invoke(ctx, "late:")
+			assert.ErrorIs(t, err, ErrShutdown)
+			close(release)
+
+			result := <-done
+			var interrupt *GraphInterrupt
+			assert.ErrorAs(t, result.err, &interrupt)
+			assert.ErrorIs(t, result.err, ErrShutdown)
+			assert.True(t, interrupt.Shutdown)
+			assert.Equal(t, []string{"B"}, interrupt.NextNodes)
+			assert.Equal(t, "start:A", interrupt.State)
+			assert.Equal(t, "start:A", result.state)
+			assert.NoError(t, <-shutdown)
+		})
+	}
+}
+
+func TestRuntimeShutdown_ResumeInAnotherProcess(t *testing.T) {
+	store := NewMemoryCheckpointStore()
+	newRunnable := func(started chan<- struct{}, release <-chan struct{}) *CheckpointableRunnable {
+		g := NewCheckpointableMessageGraphWithConfig(CheckpointConfig{Store: store, AutoSave: true})
+		g.AddNode("A", "A", blockingNode(started, release, "A"))
+		g.AddNode("B", "B", appendNode("B"))
+		g.SetEntryPoint("A")
+		g.AddEdge("A", "B")
+		g.AddEdge("B", END)
+		runnable, err := g.CompileCheckpointable()
+		assert.NoError(t, err)
+		return runnable
+	}
+	config := &Config{Configurable: map[string]interface{}{"thread_id": "job-1"}}
+
+	// The first process is shut down while A runs
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	rt := NewRuntime()
+	errs := make(chan error, 1)
+	go func() {
+		_, err := newRunnable(started, release).InvokeWithConfig(WithRuntime(context.Background(), rt), "start:", config)
+		errs <- err
+	}()
+	<-started
+	go func() {
+		assert.Eventually(t, rt.ShuttingDown, time.Second, time.Millisecond)
+		close(release)
+	}()
+	assert.NoError(t, rt.Shutdown(context.Background()))
+	assert.ErrorIs(t, <-errs, ErrShutdown)
+
+	checkpoints, err := store.List(context.Background(), "job-1")
+	assert.NoError(t, err)
+	pending := latestCheckpoint(checkpoints)
+	assert.Equal(t, "interrupt", pending.Metadata["event"])
+	assert.Equal(t, "shutdown", pending.Metadata["reason"])
+	assert.Equal(t, []string{"B"}, checkpointNext(pending))
+
+	// A later process resumes the run from B
+	later := newRunnable(make(chan struct{}, 1), nil)
+	result, err := later.Resume(WithRuntime(context.Background(), NewRuntime()), config)
+	assert.NoError(t, err)
+	assert.Equal(t, "start:AB", result)
+
+	_, err = later.Resume(context.Background(), config)
+	assert.ErrorIs(t, err, ErrNoPendingInterrupt)
+}
+
+func TestRuntimeShutdown_Timeout(t *testing.T) {
+	started := make(chan struct{}, 1)
+	g := NewStateGraph()
+	g.AddNode("slow", "slow", func(ctx context.Context, state interface{}) (interface{}, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return nil, context.Cause(ctx)
+	})
+	g.SetEntryPoint("slow")
+	g.AddEdge("slow", END)
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	rt := NewRuntime()
+	errs := make(chan error, 1)
+	go func() {
+		_, err := runnable.Invoke(WithRuntime(context.Background(), rt), "state")
+		errs <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, rt.Shutdown(ctx), context.DeadlineExceeded)
+
+	// The run is cancelled with ErrShutdown as the cause and stops before the
+	// cancelled superstep
+	err = <-errs
+	assert.ErrorIs(t, err, ErrShutdown)
+	var interrupt *GraphInterrupt
+	if assert.ErrorAs(t, err, &interrupt) {
+		assert.Equal(t, []string{"slow"}, interrupt.NextNodes)
+		assert.Equal(t, "state", interrupt.State)
+	}
+}
+
+func TestRuntimeShutdown_TimeoutResumeInAnotherProcess(t *testing.T) {
+	store := NewMemoryCheckpointStore()
+	newRunnable := func(started chan<- struct{}) *CheckpointableRunnable {
+		g := NewCheckpointableMessageGraphWithConfig(CheckpointConfig{Store: store, AutoSave: true})
+		g.AddNode("A", "A", appendNode("A"))
+		g.AddNode("slow", "slow", func(ctx context.Context, state interface{}) (interface{}, error) {
+			if started != nil {
+				started <- struct{}{}
+				<-ctx.Done()
+				return nil, context.Cause(ctx)
+			}
+			return state.(string) + "slow", nil
+		})
+		g.SetEntryPoint("A")
+		g.AddEdge("A", "slow")
+		g.AddEdge("slow", END)
+		runnable, err := g.CompileCheckpointable()
+		assert.NoError(t, err)
+		return runnable
+	}
+	config := &Config{Configurable: map[string]interface{}{"thread_id": "job-2"}}
+
+	// The first process cancels the slow node when its shutdown times out
+	started := make(chan struct{}, 1)
+	rt := NewRuntime()
+	errs := make(chan error, 1)
+	go func() {
+		_, err := newRunnable(started).InvokeWithConfig(WithRuntime(context.Background(), rt), "start:", config)
+		errs <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, rt.Shutdown(ctx), context.DeadlineExceeded)
+	assert.ErrorIs(t, <-errs, ErrShutdown)
+
+	checkpoints, err := store.List(context.Background(), "job-2")
+	assert.NoError(t, err)
+	pending := latestCheckpoint(checkpoints)
+	assert.Equal(t, "shutdown", pending.Metadata["reason"])
+	assert.Equal(t, []string{"slow"}, checkpointNext(pending))
+
+	// A later process runs the cancelled node again
+	result, err := newRunnable(nil).Resume(WithRuntime(context.Background(), NewRuntime()), config)
+	assert.NoError(t, err)
+	assert.Equal(t, "start:Aslow", result)
+}
+
+func TestRuntime_Reset(t *testing.T) {
+	g := NewStateGraph()
+	g.AddNode("A", "A", appendNode("A"))
+	g.SetEntryPoint("A")
+	g.AddEdge("A", END)
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	rt := NewRuntime()
+	ctx := WithRuntime(context.Background(), rt)
+	assert.NoError(t, rt.Shutdown(context.Background()))
+	_, err = runnable.Invoke(ctx, "start:")
+	assert.ErrorIs(t, err, ErrShutdown)
+
+	rt.Reset()
+	assert.False(t, rt.ShuttingDown())
+	res, err := runnable.Invoke(ctx, "start:")
+	assert.NoError(t, err)
+	assert.Equal(t, "start:A", res)
+}
@@ -0,0 +1,224 @@
+package graph
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// WithPriority sets the priority of the node when nodes wait for a slot under
+// Config.MaxConcurrency or a group limit: higher priorities run first
+func WithPriority(priority int) NodeOption {
+	return func(n *Node) {
+		n.Priority = priority
+	}
+}
+
+// WithMaxConcurrency limits how many members of a parallel or map-reduce group node
+// run at once. It has no effect on other nodes.
+func WithMaxConcurrency(limit int) NodeOption {
+	return func(n *Node) {
+		n.MaxConcurrency = limit
+	}
+}
+
+// scheduler bounds the number of nodes running at once, granting free slots to the
+// waiting nodes with the highest priority first, in arrival order for equal priorities.
+// A nil scheduler does not limit anything.
+type scheduler struct {
+	mu      sync.Mutex
+	limit   int
+	running int
+	waiters waiterQueue
+	seq     uint64
+}
+
+// newScheduler creates a scheduler running up to limit nodes at once, or returns nil
+// when limit is not positive
+func newScheduler(limit int) *scheduler {
+	if limit <= 0 {
+		return nil
+	}
+	return &scheduler{limit: limit}
+}
+
+// acquire waits for a free slot
+func (s *scheduler) acquire(ctx context.Context, priority int) error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	if s.running < s.limit && len(s.waiters) == 0 {
+		s.running++
+		s.mu.Unlock()
+		return nil
+	}
+	w := &waiter{priority: priority, seq: s.seq, ready: make(chan struct{})}
+	s.seq++
+	heap.Push(&s.waiters, w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if w.index < 0 {
+			// The slot was granted concurrently, pass it on
+			s.running--
+			s.grant()
+		} else {
+			heap.Remove(&s.waiters, w.index)
+		}
+		return ctx.Err()
+	}
+}
+
+// release frees a slot
+func (s *scheduler) release() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running--
+	s.grant()
+}
+
+// grant hands the free slots to the waiters with the highest priority
+func (s *scheduler) grant() {
+	for s.running < s.limit && len(s.waiters) > 0 {
+		w := heap.Pop(&s.waiters).(*waiter)
+		s.running++
+		close(w.ready)
+	}
+}
+
+type waiter struct {
+	priority int
+	seq      uint64
+	index    int
+	ready    chan struct{}
+}
+
+// waiterQueue is a heap of waiters ordered by priority, then arrival
+type waiterQueue []*waiter
+
+func (q waiterQueue) Len() int { return len(q) }
+
+func (q waiterQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q waiterQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *waiterQueue) Push(x interface{}) {
+	w := x.(*waiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waiterQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	w.index = -1
+	*q = old[:n-1]
+	return w
+}
+
+// runScheduler creates the scheduler of a run from its config
+func runScheduler(config *Config) *scheduler {
+	if config == nil {
+		return nil
+	}
+	return newScheduler(config.MaxConcurrency)
+}
+
+type schedulerKey struct{}
+
+// withRunScheduler sets the scheduler of a top-level run from its config. Nested runs,
+// such as subgraphs, share the scheduler of the enclosing run instead, so that the
+// limit covers their nodes too, and the node running them yields its slot until the
+// returned function is called.
+func withRunScheduler(ctx context.Context, config *Config) (context.Context, func()) {
+	if schedulerFromContext(ctx) != nil {
+		return ctx, yieldSlot(ctx)
+	}
+	return withScheduler(ctx, runScheduler(config)), func() {}
+}
+
+// withScheduler sets the scheduler limiting the nodes of the run
+func withScheduler(ctx context.Context, s *scheduler) context.Context {
+	return context.WithValue(ctx, schedulerKey{}, s)
+}
+
+func schedulerFromContext(ctx context.Context) *scheduler {
+	s, _ := ctx.Value(schedulerKey{}).(*scheduler)
+	return s
+}
+
+type slotKey struct{}
+
+// slot is a slot of the run scheduler held by a running node
+type slot struct {
+	s        *scheduler
+	priority int
+	mu       sync.Mutex
+	held     bool
+}
+
+// acquireSlot waits for a slot of the run scheduler, returning a context that
+// carries it so that the node can yield it while waiting for nested nodes
+func acquireSlot(ctx context.Context, priority int) (context.Context, *slot, error) {
+	s := schedulerFromContext(ctx)
+	if s == nil {
+		return ctx, nil, nil
+	}
+	if err := s.acquire(ctx, priority); err != nil {
+		return ctx, nil, err
+	}
+	sl := &slot{s: s, priority: priority, held: true}
+	return context.WithValue(ctx, slotKey{}, sl), sl, nil
+}
+
+// release frees the slot if it is held
+func (sl *slot) release() {
+	if sl == nil {
+		return
+	}
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	if sl.held {
+		sl.held = false
+		sl.s.release()
+	}
+}
+
+// yieldSlot frees the slot held by the node of ctx while it waits for the nodes it
+// runs itself, e.g. the members of a parallel group, so that they can use it. The
+// returned function takes a slot back before the node continues.
+func yieldSlot(ctx context.Context) func() {
+	sl, ok := ctx.Value(slotKey{}).(*slot)
+	if !ok {
+		return func() {}
+	}
+	sl.release()
+	return func() {
+		sl.mu.Lock()
+		defer sl.mu.Unlock()
+		if !sl.held && sl.s.acquire(ctx, sl.priority) == nil {
+			sl.held = true
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// concurrencyProbe records the maximum number of concurrent calls of its nodes
+type concurrencyProbe struct {
+	running int32
+	max     int32
+}
+
+func (p *concurrencyProbe) node(out string) func(context.Context, interface{}) (interface{}, error) {
+	return func(ctx context.Context, state interface{}) (interface{}, error) {
+		n := atomic.AddInt32(&p.running, 1)
+		defer atomic.AddInt32(&p.running, -1)
+		for {
+			m := atomic.LoadInt32(&p.max)
+			if n <= m || atomic.CompareAndSwapInt32(&p.max, m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		return out, nil
+	}
+}
+
+func TestScheduler_PriorityOrder(t *testing.T) {
+	s := newScheduler(1)
+	ctx := context.Background()
+	assert.NoError(t, s.acquire(ctx, 0))
+
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	for i, priority := range []int{1, 5, 3, 5} {
+		wg.Add(1)
+		go func(id, priority int) {
+			defer wg.Done()
+			assert.NoError(t, s.acquire(ctx, priority))
+			mu.Lock()
+			order = append(order, id)
+			mu.Unlock()
+			s.release()
+		}(i, priority)
+		// Queue the waiters one at a time so that their arrival order is known
+		assert.Eventually(t, func() bool {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return len(s.waiters) == i+1
+		}, time.Second, time.Millisecond)
+	}
+
+	s.release()
+	wg.Wait()
+	// Highest priority first, in arrival order for equal priorities
+	assert.Equal(t, []int{1, 3, 2, 0}, order)
+}
+
+func TestScheduler_CancelWhileWaiting(t *testing.T) {
+	s := newScheduler(1)
+	assert.NoError(t, s.acquire(context.Background(), 0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, s.acquire(ctx, 0), context.DeadlineExceeded)
+	assert.Empty(t, s.waiters)
+
+	// The slot is still usable once released
+	s.release()
+	assert.NoError(t, s.acquire(context.Background(), 0))
+
+	// A nil scheduler does not limit anything
+	var unlimited *scheduler
+	assert.NoError(t, unlimited.acquire(context.Background(), 0))
+	unlimited.release()
+}
+
+// fanOutGraph is implemented by MessageGraph and StateGraph
+type fanOutGraph interface {
+	AddNode(name string, description string, fn func(ctx context.Context, state interface{}) (interface{}, error), opts ...NodeOption)
+	AddEdge(from, to string)
+	SetEntryPoint(name string)
+	SetStateMerger(merger StateMerger)
+}
+
+func TestMaxConcurrency_Superstep(t *testing.T) {
+	tests := []struct {
+		name    string
+		graph   fanOutGraph
+		compile func(g fanOutGraph) (func(context.Context, interface{}, *Config) (interface{}, error), error)
+	}{
+		{
+			name:  "MessageGraph",
+			graph: NewMessageGraph(),
+			compile: func(g fanOutGraph) (func(context.Context, interface{}, *Config) (interface{}, error), error) {
+				runnable, err := g.(*MessageGraph).Compile()
+				if err != nil {
+					return nil, err
+				}
+				return runnable.InvokeWithConfig, nil
+			},
+		},
+		{
+			name:  "StateGraph",
+			graph: NewStateGraph(),
+			compile: func(g fanOutGraph) (func(context.Context, interface{}, *Config) (interface{}, error), error) {
+				runnable, err := g.(*StateGraph).Compile()
+				if err != nil {
+					return nil, err
+				}
+				return runnable.InvokeWithConfig, nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			probe := &concurrencyProbe{}
+			g := tt.graph
+			g.SetStateMerger(func(ctx context.Context, current interface{}, states []interface{}) (interface{}, error) {
+				return "merged", nil
+			})
+			g.AddNode("start", "start", probe.node("start"))
+			g.SetEntryPoint("start")
+			const workers = 6
+			for i := 0; i < workers; i++ {
+				name := fmt.Sprintf("worker_%d", i)
+				g.AddNode(name, name, probe.node(name))
+				g.AddEdge("start", name)
+				g.AddEdge(name, END)
+			}
+			invoke, err := tt.compile(g)
+			assert.NoError(t, err)
+
+			_, err = invoke(context.Background(), "input", &Config{MaxConcurrency: 2})
+			assert.NoError(t, err)
+			assert.Equal(t, int32(2), atomic.LoadInt32(&probe.max))
+
+			// Without a limit all workers run at once
+			atomic.StoreInt32(&probe.max, 0)
+			_, err = invoke(context.Background(), "input", nil)
+			assert.NoError(t, err)
+			assert.Equal(t, int32(workers), atomic.LoadInt32(&probe.max))
+		})
+	}
+}
+
+func TestMaxConcurrency_ParallelGroup(t *testing.T) {
+	probe := &concurrencyProbe{}
+	docs := make(map[string]func(context.Context, interface{}) (interface{}, error))
+	for i := 0; i < 10; i++ {
+		docs[fmt.Sprintf("doc_%d", i)] = probe.node("summary")
+	}
+
+	g := NewMessageGraph()
+	g.AddMapReduceNode("summarize", docs, func(results []interface{}) (interface{}, error) {
+		return len(results), nil
+	}, WithMaxConcurrency(3))
+	g.SetEntryPoint("summarize")
+	g.AddEdge("summarize", END)
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	result, err := runnable.Invoke(context.Background(), "docs")
+	assert.NoError(t, err)
+	assert.Equal(t, 10, result)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&probe.max))
+
+	// The run limit also applies to the members, which use the slot of the group node
+	atomic.StoreInt32(&probe.max, 0)
+	result, err = runnable.InvokeWithConfig(context.Background(), "docs", &Config{MaxConcurrency: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 10, result)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&probe.max))
+}
+
+func TestMaxConcurrency_Priority(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) func(context.Context, interface{}) (interface{}, error) {
+		return func(ctx context.Context, state interface{}) (interface{}, error) {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			return state, nil
+		}
+	}
+
+	g := NewStateGraph()
+	g.AddNode("start", "start", record("start"))
+	g.SetEntryPoint("start")
+	priorities := map[string]int{"low": 1, "medium": 5, "high": 10, "urgent": 20}
+	for name, priority := range priorities {
+		g.AddNode(name, name, record(name), WithPriority(priority))
+		g.AddEdge("start", name)
+		g.AddEdge(name, END)
+	}
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	_, err = runnable.InvokeWithConfig(context.Background(), "input", &Config{MaxConcurrency: 1})
+	assert.NoError(t, err)
+
+	// The first node to arrive takes the free slot; the others queue by priority
+	assert.Len(t, order, 5)
+	queued := order[2:]
+	for i := 1; i < len(queued); i++ {
+		assert.Greater(t, priorities[queued[i-1]], priorities[queued[i]], order)
+	}
+}
+
+func TestMaxConcurrency_Subgraphs(t *testing.T) {
+	for _, limit := range []int{1, 2} {
+		t.Run(fmt.Sprintf("limit %d", limit), func(t *testing.T) {
+			probe := &concurrencyProbe{}
+			child := NewMessageGraph()
+			child.AddNode("fan", "fan", func(ctx context.Context, state interface{}) (interface{}, error) {
+				return state, nil
+			})
+			child.SetEntryPoint("fan")
+			for _, name := range []string{"a", "b", "c"} {
+				child.AddNode(name, name, probe.node(name))
+				child.AddEdge("fan", name)
+				child.AddEdge(name, END)
+			}
+
+			parent := NewStateGraph()
+			parent.AddNode("start", "start", func(ctx context.Context, state interface{}) (interface{}, error) {
+				return state, nil
+			})
+			parent.SetEntryPoint("start")
+			for _, name := range []string{"sub1", "sub2"} {
+				assert.NoError(t, parent.AddSubgraph(name, child))
+				parent.AddEdge("start", name)
+				parent.AddEdge(name, END)
+			}
+			runnable, err := parent.Compile()
+			assert.NoError(t, err)
+
+			// The nodes of both children count against the limit of the run
+			_, err = runnable.InvokeWithConfig(context.Background(), "input", &Config{MaxConcurrency: limit})
+			assert.NoError(t, err)
+			assert.LessOrEqual(t, atomic.LoadInt32(&probe.max), int32(limit))
+		})
+	}
+}
@@ -169,13 +169,16 @@ func AppendReducer(current, new interface{}) (interface{}, error) {
 
 	if newVal.Kind() == reflect.Slice {
 		// Append slice to slice
-		// Check if types are compatible? reflect.AppendSlice handles it or panics.
-		// We should probably check types to avoid panics.
-		if currVal.Type().Elem() != newVal.Type().Elem() {
-			// Try to append as generic interface slice?
-			// For now, let's assume types match or rely on reflect to panic/convert if possible.
-			// Actually reflect.AppendSlice requires exact match.
-			return reflect.AppendSlice(currVal, newVal).Interface(), nil
+		if elemType := currVal.Type().Elem(); elemType != newVal.Type().Elem() {
+			// Append element by element, e.g. []string to a []interface{} decoded
+			// from a checkpoint, as reflect.AppendSlice requires an exact match
+			if !newVal.Type().Elem().AssignableTo(elemType) {
+				return nil, fmt.Errorf("cannot append %s to %s", newVal.Type(), currVal.Type())
+			}
+			for i := 0; i < newVal.Len(); i++ {
+				currVal = reflect.Append(currVal, newVal.Index(i))
+			}
+			return currVal.Interface(), nil
 		}
 		return reflect.AppendSlice(currVal, newVal).Interface(), nil
 	}
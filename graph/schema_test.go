@@ -89,3 +89,13 @@ func TestStateGraph_Schema(t *testing.T) {
 	finalState := result.(map[string]interface{})
 	assert.Equal(t, []string{"start", "A", "B"}, finalState["messages"])
 }
+
+func TestAppendReducer_MixedElementTypes(t *testing.T) {
+	// Slices decoded from JSON checkpoints hold interface{} elements
+	merged, err := AppendReducer([]interface{}{"start"}, []string{"A", "B"})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"start", "A", "B"}, merged)
+
+	_, err = AppendReducer([]string{"start"}, []int{1})
+	assert.EqualError(t, err, "cannot append []int to []string")
+}
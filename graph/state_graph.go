@@ -2,6 +2,7 @@ package graph
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -29,13 +30,39 @@ type StateGraph struct {
 
 	// Schema defines the state structure and update logic
 	Schema StateSchema
+
+	// cache stores node results for nodes with a CachePolicy
+	cache Cache
+
+	// errorEdges maps nodes to the handler nodes that receive their errors
+	errorEdges map[string]string
+
+	// nodeOrder is the order of the nodes of each superstep
+	nodeOrder NodeOrder
+
+	// isolation and stateCloner configure the copies of the state handed to nodes
+	isolation   StateIsolation
+	stateCloner StateCloner
+
+	// pathMaps maps the outputs of conditional edges to target nodes, keyed by "From" node
+	pathMaps map[string]map[string]string
+
+	// subgraphs records the nodes that were added as subgraphs
+	subgraphs map[string]*Subgraph
+
+	// definitions records the declarative definitions of nodes and conditional edges
+	// built by GraphDefinition.Build, so that Export can reproduce them
+	definitions *graphDefinitionRefs
 }
 
 // RetryPolicy defines how to handle node failures
 type RetryPolicy struct {
 	MaxRetries      int
 	BackoffStrategy BackoffStrategy
+	// RetryableErrors lists substrings of the error messages to retry
 	RetryableErrors []string
+	// RetryIf, when set, also retries the errors it matches, e.g. RetryOnErrors or RetryOnErrorType
+	RetryIf func(error) bool
 }
 
 // BackoffStrategy defines different backoff strategies
@@ -55,13 +82,12 @@ func NewStateGraph() *StateGraph {
 	}
 }
 
-// AddNode adds a new node to the state graph with the given name, description and function
-func (g *StateGraph) AddNode(name string, description string, fn func(ctx context.Context, state interface{}) (interface{}, error)) {
-	g.nodes[name] = Node{
-		Name:        name,
-		Description: description,
-		Function:    fn,
-	}
+// AddNode adds a new node to the state graph with the given name, description and function.
+// Optional NodeOptions configure additional behavior such as result caching, per-node
+// retries (WithRetry), timeouts (WithTimeout) and circuit breaking (WithCircuitBreaker).
+// A node with its own retry policy is not retried again by the graph RetryPolicy.
+func (g *StateGraph) AddNode(name string, description string, fn func(ctx context.Context, state interface{}) (interface{}, error), opts ...NodeOption) {
+	g.nodes[name] = newNode(name, description, fn, opts)
 }
 
 // AddEdge adds a new edge to the state graph between the "from" and "to" nodes
@@ -74,9 +100,27 @@ func (g *StateGraph) AddEdge(from, to string) {
 
 // AddConditionalEdge adds a conditional edge where the target node is determined at runtime
 func (g *StateGraph) AddConditionalEdge(from string, condition func(ctx context.Context, state interface{}) string) {
+	delete(g.pathMaps, from)
 	g.conditionalEdges[from] = condition
 }
 
+// AddConditionalEdges adds a conditional edge whose condition output is mapped to a target
+// node through pathMap. Outputs not present in pathMap are used as the target node name.
+func (g *StateGraph) AddConditionalEdges(from string, condition func(ctx context.Context, state interface{}) string, pathMap map[string]string) {
+	if g.pathMaps == nil {
+		g.pathMaps = make(map[string]map[string]string)
+	}
+	g.pathMaps[from] = pathMap
+
+	g.conditionalEdges[from] = func(ctx context.Context, state interface{}) string {
+		next := condition(ctx, state)
+		if target, ok := pathMap[next]; ok {
+			return target
+		}
+		return next
+	}
+}
+
 // SetEntryPoint sets the entry point node name for the state graph
 func (g *StateGraph) SetEntryPoint(name string) {
 	g.entryPoint = name
@@ -97,9 +141,16 @@ func (g *StateGraph) SetSchema(schema StateSchema) {
 	g.Schema = schema
 }
 
+// SetCache sets the cache backend used by nodes that have a CachePolicy
+func (g *StateGraph) SetCache(cache Cache) {
+	g.cache = cache
+}
+
 // StateRunnable represents a compiled state graph that can be invoked
 type StateRunnable struct {
 	graph *StateGraph
+	// tracer is the optional tracer of node executions
+	tracer *Tracer
 }
 
 // Compile compiles the state graph and returns a StateRunnable instance
@@ -107,12 +158,23 @@ func (g *StateGraph) Compile() (*StateRunnable, error) {
 	if g.entryPoint == "" {
 		return nil, ErrEntryPointNotSet
 	}
+	if err := validateNodes(g.nodes); err != nil {
+		return nil, err
+	}
+	if err := validateErrorHandlers(g.errorEdges, g.nodes); err != nil {
+		return nil, err
+	}
 
 	return &StateRunnable{
 		graph: g,
 	}, nil
 }
 
+// SetTracer sets a tracer recording a span per node execution
+func (r *StateRunnable) SetTracer(tracer *Tracer) {
+	r.tracer = tracer
+}
+
 // Invoke executes the compiled state graph with the given input state
 func (r *StateRunnable) Invoke(ctx context.Context, initialState interface{}) (interface{}, error) {
 	return r.InvokeWithConfig(ctx, initialState, nil)
@@ -120,13 +182,63 @@ func (r *StateRunnable) Invoke(ctx context.Context, initialState interface{}) (i
 
 // InvokeWithConfig executes the compiled state graph with the given input state and config
 func (r *StateRunnable) InvokeWithConfig(ctx context.Context, initialState interface{}, config *Config) (interface{}, error) {
+	run, ctx, err := startRun(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer run.end()
+
 	if config != nil {
 		ctx = WithConfig(ctx, config)
 	}
+	runID := generateRunID()
+	ctx = withRunID(ctx, runID)
+
+	if config == nil || len(config.Callbacks) == 0 {
+		return r.invoke(ctx, run, initialState, config)
+	}
+
+	// Notify callbacks of the graph run, under which nodes and their calls are nested
+	serialized := map[string]interface{}{
+		"name": "graph",
+		"type": "chain",
+	}
+	parentRunID := ParentRunIDFromContext(ctx)
+	for _, cb := range config.Callbacks {
+		cb.OnChainStart(ctx, serialized, convertStateToMap(initialState), runID, parentRunID, config.Tags, config.Metadata)
+	}
 
+	state, err := r.invoke(WithParentRunID(ctx, runID), run, initialState, config)
+	for _, cb := range config.Callbacks {
+		if err != nil {
+			cb.OnChainError(ctx, err, runID)
+		} else {
+			cb.OnChainEnd(ctx, convertStateToMap(state), runID)
+		}
+	}
+	return state, err
+}
+
+// invoke runs the supersteps of the graph
+func (r *StateRunnable) invoke(ctx context.Context, run *runtimeRun, initialState interface{}, config *Config) (interface{}, error) {
 	state := initialState
 	currentNodes := []string{r.graph.entryPoint}
 
+	// Limit the nodes running at once
+	ctx, reacquireSlot := withRunScheduler(ctx, config)
+	defer reacquireSlot()
+	ctx = withStateCloner(ctx, r.graph.isolation.cloner(r.graph.stateCloner))
+
+	// Handle ResumeFrom
+	if config != nil && len(config.ResumeFrom) > 0 {
+		currentNodes = config.ResumeFrom
+
+		// Inject ResumeValue
+		if config.ResumeValue != nil {
+			ctx = WithResumeValue(ctx, config.ResumeValue)
+		}
+	}
+
 	for len(currentNodes) > 0 {
 		// Filter out END nodes
 		activeNodes := make([]string, 0, len(currentNodes))
@@ -141,10 +253,23 @@ func (r *StateRunnable) InvokeWithConfig(ctx context.Context, initialState inter
 			break
 		}
 
+		// Check InterruptBefore
+		if config != nil && len(config.InterruptBefore) > 0 {
+			for _, node := range currentNodes {
+				for _, interrupt := range config.InterruptBefore {
+					if node == interrupt {
+						return state, &GraphInterrupt{Node: node, State: state}
+					}
+				}
+			}
+		}
+
 		// Execute nodes in parallel
 		var wg sync.WaitGroup
 		results := make([]interface{}, len(currentNodes))
 		errorsList := make([]error, len(currentNodes))
+		// handledBy records the error handlers that ran in place of failed nodes
+		handledBy := make([]string, len(currentNodes))
 
 		for i, nodeName := range currentNodes {
 			node, ok := r.graph.nodes[nodeName]
@@ -156,21 +281,93 @@ func (r *StateRunnable) InvokeWithConfig(ctx context.Context, initialState inter
 			go func(index int, n Node, name string) {
 				defer wg.Done()
 
-				// Execute node with retry logic
-				res, err := r.executeNodeWithRetry(ctx, n, state)
+				// Wait for a concurrency slot of the run
+				ctx, slot, err := acquireSlot(ctx, n.Priority)
+				if err != nil {
+					errorsList[index] = fmt.Errorf("error in node %s: %w", name, err)
+					return
+				}
+				defer slot.release()
+
+				// Give the node its own copy of the state when isolation is enabled
+				state, err := isolateState(ctx, state)
 				if err != nil {
 					errorsList[index] = fmt.Errorf("error in node %s: %w", name, err)
 					return
 				}
+
+				// Execute node with retry logic, serving from cache when possible
+				var nodeSpan *TraceSpan
+				if r.tracer != nil {
+					nodeSpan = r.tracer.StartSpan(ctx, TraceEventNodeStart, name)
+					nodeSpan.State = state
+				}
+				nodeCtx, endNodeRun := startNodeRun(ctx, name, state)
+				if nodeSpan != nil {
+					nodeCtx = ContextWithSpan(nodeCtx, nodeSpan)
+				}
+				res, cacheHit, err := executeWithCache(nodeCtx, r.graph.cache, n, state, func(ctx context.Context, state interface{}) (interface{}, error) {
+					return r.executeNodeWithRetry(ctx, n, state)
+				})
+				endNodeRun(res, err)
+				if nodeSpan != nil {
+					if cacheHit {
+						nodeSpan.Metadata["cache_hit"] = true
+					}
+					r.tracer.EndSpan(ctx, nodeSpan, res, err)
+				}
+				if err != nil {
+					// Route the error to the handler of the node, if any
+					if handler := r.graph.errorHandler(name); handler != "" && handleableError(ctx, err) {
+						res, err = runErrorHandler(ctx, handler, r.graph.nodes[handler].Function, name, state, err)
+						if err != nil {
+							errorsList[index] = err
+							return
+						}
+						handledBy[index] = handler
+						results[index] = res
+						return
+					}
+
+					var nodeInterrupt *NodeInterrupt
+					if errors.As(err, &nodeInterrupt) {
+						nodeInterrupt.Node = name
+					}
+					errorsList[index] = fmt.Errorf("error in node %s: %w", name, err)
+					return
+				}
 				results[index] = res
 			}(i, node, nodeName)
 		}
 
 		wg.Wait()
 
+		// Continue from the error handlers that replaced failed nodes
+		for i, handler := range handledBy {
+			if handler != "" {
+				currentNodes[i] = handler
+			}
+		}
+
 		// Check for errors
 		for _, err := range errorsList {
 			if err != nil {
+				// Run the superstep again on resume when Shutdown cancelled it
+				if interrupt := run.cancelled(ctx, state, currentNodes); interrupt != nil {
+					return state, interrupt
+				}
+				// Stop with the state of the run when a node, or a subgraph below it,
+				// requests an interrupt, so that the run can be resumed
+				var nodeInterrupt *NodeInterrupt
+				if errors.As(err, &nodeInterrupt) {
+					return state, &GraphInterrupt{
+						Node:           nodeInterrupt.Node,
+						State:          state,
+						InterruptValue: nodeInterrupt.Value,
+						NextNodes:      []string{nodeInterrupt.Node},
+						Subgraph:       nodeInterrupt.Subgraph,
+					}
+				}
 				return nil, err
 			}
 		}
@@ -188,8 +385,10 @@ func (r *StateRunnable) InvokeWithConfig(ctx context.Context, initialState inter
 					switch g := cmd.Goto.(type) {
 					case string:
 						nextNodesFromCommands = append(nextNodesFromCommands, g)
+						notifyRoute(ctx, currentNodes[i], g)
 					case []string:
 						nextNodesFromCommands = append(nextNodesFromCommands, g...)
+						notifyRoute(ctx, currentNodes[i], g...)
 					}
 				}
 			} else {
@@ -225,17 +424,16 @@ func (r *StateRunnable) InvokeWithConfig(ctx context.Context, initialState inter
 
 		if len(nextNodesFromCommands) > 0 {
 			// Command.Goto overrides static edges
-			// We deduplicate
-			seen := make(map[string]bool)
+			var nextNodesSet nodeSet
 			for _, n := range nextNodesFromCommands {
-				if !seen[n] && n != END {
-					seen[n] = true
-					nextNodesList = append(nextNodesList, n)
+				if n != END {
+					nextNodesSet.add(n)
 				}
 			}
+			nextNodesList = nextNodesSet.list(r.graph.nodeOrder)
 		} else {
 			// Use static edges
-			nextNodesSet := make(map[string]bool)
+			var nextNodesSet nodeSet
 
 			for _, nodeName := range currentNodes {
 				// First check for conditional edges
@@ -245,13 +443,14 @@ func (r *StateRunnable) InvokeWithConfig(ctx context.Context, initialState inter
 					if nextNode == "" {
 						return nil, fmt.Errorf("conditional edge returned empty next node from %s", nodeName)
 					}
-					nextNodesSet[nextNode] = true
+					notifyRoute(ctx, nodeName, nextNode)
+					nextNodesSet.add(nextNode)
 				} else {
 					// Then check regular edges
 					foundNext := false
 					for _, edge := range r.graph.edges {
 						if edge.From == nodeName {
-							nextNodesSet[edge.To] = true
+							nextNodesSet.add(edge.To)
 							foundNext = true
 							// Do NOT break here, to allow fan-out (multiple edges from same node)
 						}
@@ -263,9 +462,22 @@ func (r *StateRunnable) InvokeWithConfig(ctx context.Context, initialState inter
 				}
 			}
 
-			// Update nextNodesList from set
-			for node := range nextNodesSet {
-				nextNodesList = append(nextNodesList, node)
+			// Update currentNodes in a stable order
+			nextNodesList = nextNodesSet.list(r.graph.nodeOrder)
+		}
+
+		// Check InterruptAfter
+		if config != nil && len(config.InterruptAfter) > 0 {
+			for _, node := range currentNodes {
+				for _, interrupt := range config.InterruptAfter {
+					if node == interrupt {
+						return state, &GraphInterrupt{
+							Node:      node,
+							State:     state,
+							NextNodes: nextNodesList,
+						}
+					}
+				}
 			}
 		}
 
@@ -292,6 +504,16 @@ func (r *StateRunnable) InvokeWithConfig(ctx context.Context, initialState inter
 				}
 			}
 		}
+
+		// Stop the run if a callback guard (e.g. a usage budget) says so
+		if err := checkRunGuards(ctx); err != nil {
+			return state, err
+		}
+
+		// Stop between supersteps when the runtime is shutting down
+		if err := run.interrupt(state, currentNodes); err != nil {
+			return state, err
+		}
 	}
 
 	return state, nil
@@ -302,12 +524,16 @@ func (r *StateRunnable) executeNodeWithRetry(ctx context.Context, node Node, sta
 	var lastErr error
 
 	maxRetries := 1 // Default: no retries
-	if r.graph.retryPolicy != nil {
+	if r.graph.retryPolicy != nil && node.Retry == nil {
 		maxRetries = r.graph.retryPolicy.MaxRetries + 1 // +1 for initial attempt
 	}
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
-		result, err := node.Function(ctx, state)
+		attemptCtx := ctx
+		if maxRetries > 1 {
+			attemptCtx = withAttempt(ctx, attempt+1)
+		}
+		result, err := node.Function(attemptCtx, state)
 		if err == nil {
 			return result, nil
 		}
@@ -317,6 +543,10 @@ func (r *StateRunnable) executeNodeWithRetry(ctx context.Context, node Node, sta
 		// Check if error is retryable
 		if r.graph.retryPolicy != nil && attempt < maxRetries-1 {
 			if r.isRetryableError(err) {
+				notifyResilience(ctx, func(h ResilienceCallbackHandler) {
+					h.OnNodeRetry(ctx, node.Name, attempt+2, err)
+				})
+
 				// Apply backoff strategy
 				delay := r.calculateBackoffDelay(attempt)
 				if delay > 0 {
@@ -344,6 +574,9 @@ func (r *StateRunnable) isRetryableError(err error) bool {
 	if r.graph.retryPolicy == nil {
 		return false
 	}
+	if r.graph.retryPolicy.RetryIf != nil && r.graph.retryPolicy.RetryIf(err) {
+		return true
+	}
 
 	errorStr := err.Error()
 	for _, retryablePattern := range r.graph.retryPolicy.RetryableErrors {
@@ -242,6 +242,16 @@ func (sl *StreamingListener) OnGraphStep(ctx context.Context, stepNode string, s
 	})
 }
 
+// OnCacheHit implements CacheCallbackHandler
+func (sl *StreamingListener) OnCacheHit(ctx context.Context, nodeName string, key string) {
+	sl.emitEvent(StreamEvent{
+		Timestamp: time.Now(),
+		Event:     NodeEventCacheHit,
+		NodeName:  nodeName,
+		Metadata:  map[string]interface{}{"cache_key": key},
+	})
+}
+
 // Close marks the listener as closed to prevent sending to closed channels
 func (sl *StreamingListener) Close() {
 	sl.mutex.Lock()
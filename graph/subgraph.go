@@ -2,6 +2,7 @@ package graph
 
 import (
 	"context"
+	"errors"
 	"fmt"
 )
 
@@ -10,47 +11,291 @@ type Subgraph struct {
 	name     string
 	graph    *MessageGraph
 	runnable *Runnable
+
+	// inputTransformer maps the parent state to the child input state
+	inputTransformer func(state interface{}) (interface{}, error)
+	// outputTransformer maps the child final state to the parent update
+	outputTransformer func(state interface{}) (interface{}, error)
+	// inputKeys restricts which parent state keys are passed to the child
+	inputKeys []string
+	// outputKeys restricts which keys of the child result are returned to the parent
+	outputKeys []string
+}
+
+// SubgraphOption configures how a subgraph exchanges state with its parent graph
+type SubgraphOption func(*Subgraph)
+
+// WithInputTransformer sets a function that maps the parent state to the child input state.
+// It runs after the input key allow-list has been applied.
+func WithInputTransformer(fn func(state interface{}) (interface{}, error)) SubgraphOption {
+	return func(s *Subgraph) {
+		s.inputTransformer = fn
+	}
+}
+
+// WithOutputTransformer sets a function that maps the child final state to the update
+// returned to the parent. It runs before the output key allow-list is applied.
+func WithOutputTransformer(fn func(state interface{}) (interface{}, error)) SubgraphOption {
+	return func(s *Subgraph) {
+		s.outputTransformer = fn
+	}
+}
+
+// WithInputKeys restricts the parent state keys passed into the subgraph.
+// The parent state must be a map[string]interface{}.
+func WithInputKeys(keys ...string) SubgraphOption {
+	return func(s *Subgraph) {
+		s.inputKeys = keys
+	}
+}
+
+// WithOutputKeys restricts the keys returned from the subgraph to the parent,
+// keeping the child's private keys out of the parent state.
+// The subgraph result must be a map[string]interface{}.
+func WithOutputKeys(keys ...string) SubgraphOption {
+	return func(s *Subgraph) {
+		s.outputKeys = keys
+	}
 }
 
 // NewSubgraph creates a new subgraph
-func NewSubgraph(name string, graph *MessageGraph) (*Subgraph, error) {
+func NewSubgraph(name string, graph *MessageGraph, opts ...SubgraphOption) (*Subgraph, error) {
 	runnable, err := graph.Compile()
 	if err != nil {
 		return nil, fmt.Errorf("failed to compile subgraph %s: %w", name, err)
 	}
 
-	return &Subgraph{
+	sg := &Subgraph{
 		name:     name,
 		graph:    graph,
 		runnable: runnable,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(sg)
+	}
+
+	return sg, nil
 }
 
-// Execute runs the subgraph as a node
+// Execute runs the subgraph as a node.
+// When the parent runs with checkpointing, the child's progress is persisted under
+// the namespace "<parent thread>|<subgraph name>", and resuming the parent into this
+// node resumes the child from its pending interrupt instead of restarting it.
+// Interrupts raised inside the child are propagated to the parent as a NodeInterrupt.
 func (s *Subgraph) Execute(ctx context.Context, state interface{}) (interface{}, error) {
-	result, err := s.runnable.Invoke(ctx, state)
+	input, err := s.mapInput(state)
+	if err != nil {
+		return nil, err
+	}
+
+	var config *Config
+	scope := checkpointScopeFromContext(ctx)
+	if scope != nil {
+		namespace := SubgraphNamespace(scope.threadID, s.name)
+		config = s.childConfig(ctx, scope.store, namespace)
+
+		if s.isResuming(ctx) {
+			pending, err := pendingInterrupt(ctx, scope.store, namespace)
+			if err != nil {
+				return nil, fmt.Errorf("subgraph %s failed to load checkpoint: %w", s.name, err)
+			}
+			if pending != nil {
+				input = pending.State
+				config.ResumeFrom = checkpointNext(pending)
+				config.ResumeValue = GetConfig(ctx).ResumeValue
+			}
+		}
+
+		ctx = withCheckpointScope(ctx, scope.store, namespace)
+	}
+
+	result, err := s.runnable.InvokeWithConfig(ctx, input, config)
 	if err != nil {
+		var interrupt *GraphInterrupt
+		if errors.As(err, &interrupt) {
+			if scope != nil {
+				namespace := SubgraphNamespace(scope.threadID, s.name)
+				if saveErr := saveInterruptCheckpoint(ctx, scope.store, namespace, interrupt); saveErr != nil {
+					return nil, fmt.Errorf("subgraph %s failed to save checkpoint: %w", s.name, saveErr)
+				}
+			}
+			return nil, &NodeInterrupt{
+				Node:     s.name,
+				Value:    interrupt.InterruptValue,
+				Subgraph: interrupt,
+			}
+		}
 		return nil, fmt.Errorf("subgraph %s execution failed: %w", s.name, err)
 	}
-	return result, nil
+
+	return s.mapOutput(result)
+}
+
+// childConfig builds the config for a checkpointed child invocation. The child
+// inherits the parent's config, e.g. its callbacks and concurrency limit, but
+// checkpoints under its own namespace instead of the parent's thread.
+func (s *Subgraph) childConfig(ctx context.Context, store CheckpointStore, namespace string) *Config {
+	config := &Config{}
+	if parent := GetConfig(ctx); parent != nil {
+		*config = *parent
+		// Interrupt and resume targets name nodes of the parent
+		config.InterruptBefore = nil
+		config.InterruptAfter = nil
+		config.ResumeFrom = nil
+		config.ResumeValue = nil
+	}
+
+	callbacks := make([]CallbackHandler, 0, len(config.Callbacks)+1)
+	for _, cb := range config.Callbacks {
+		if _, ok := cb.(*CheckpointListener); ok {
+			continue
+		}
+		callbacks = append(callbacks, cb)
+	}
+	config.Callbacks = append(callbacks, &CheckpointListener{
+		store:       store,
+		executionID: namespace,
+		autoSave:    true,
+	})
+
+	configurable := make(map[string]interface{}, len(config.Configurable)+1)
+	for k, v := range config.Configurable {
+		configurable[k] = v
+	}
+	configurable["thread_id"] = namespace
+	config.Configurable = configurable
+
+	return config
+}
+
+// isResuming reports whether the parent invocation is resuming into this subgraph
+func (s *Subgraph) isResuming(ctx context.Context) bool {
+	config := GetConfig(ctx)
+	if config == nil {
+		return false
+	}
+	for _, node := range config.ResumeFrom {
+		if node == s.name {
+			return true
+		}
+	}
+	return false
+}
+
+// mapInput converts the parent state into the child input state
+func (s *Subgraph) mapInput(state interface{}) (interface{}, error) {
+	input := state
+	if len(s.inputKeys) > 0 {
+		filtered, err := filterStateKeys(state, s.inputKeys)
+		if err != nil {
+			return nil, fmt.Errorf("subgraph %s input: %w", s.name, err)
+		}
+		input = filtered
+	}
+
+	if s.inputTransformer != nil {
+		transformed, err := s.inputTransformer(input)
+		if err != nil {
+			return nil, fmt.Errorf("subgraph %s input transformer failed: %w", s.name, err)
+		}
+		input = transformed
+	}
+
+	return input, nil
+}
+
+// mapOutput converts the child final state into the parent update
+func (s *Subgraph) mapOutput(result interface{}) (interface{}, error) {
+	output := result
+	if s.outputTransformer != nil {
+		transformed, err := s.outputTransformer(output)
+		if err != nil {
+			return nil, fmt.Errorf("subgraph %s output transformer failed: %w", s.name, err)
+		}
+		output = transformed
+	}
+
+	if len(s.outputKeys) > 0 {
+		filtered, err := filterStateKeys(output, s.outputKeys)
+		if err != nil {
+			return nil, fmt.Errorf("subgraph %s output: %w", s.name, err)
+		}
+		output = filtered
+	}
+
+	return output, nil
+}
+
+// filterStateKeys returns a copy of a map state containing only the given keys
+func filterStateKeys(state interface{}, keys []string) (map[string]interface{}, error) {
+	m, ok := state.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("key filtering requires map[string]interface{} state, got %T", state)
+	}
+
+	filtered := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		if v, exists := m[k]; exists {
+			filtered[k] = v
+		}
+	}
+	return filtered, nil
+}
+
+// AddSubgraph adds a subgraph as a node in the parent graph.
+// Options control how state is mapped between the parent and the child,
+// allowing a child with its own schema to be embedded without leaking its keys.
+func (g *MessageGraph) AddSubgraph(name string, subgraph *MessageGraph, opts ...SubgraphOption) error {
+	sg, err := NewSubgraph(name, subgraph, opts...)
+	if err != nil {
+		return err
+	}
+
+	g.AddNode(name, "Subgraph: "+name, sg.Execute)
+	g.registerSubgraph(name, sg)
+	return nil
+}
+
+// AddSubgraph adds a subgraph as a listenable node in the graph
+func (g *ListenableMessageGraph) AddSubgraph(name string, subgraph *MessageGraph, opts ...SubgraphOption) error {
+	sg, err := NewSubgraph(name, subgraph, opts...)
+	if err != nil {
+		return err
+	}
+
+	g.AddNode(name, "Subgraph: "+name, sg.Execute)
+	g.registerSubgraph(name, sg)
+	return nil
 }
 
-// AddSubgraph adds a subgraph as a node in the parent graph
-func (g *MessageGraph) AddSubgraph(name string, subgraph *MessageGraph) error {
-	sg, err := NewSubgraph(name, subgraph)
+// AddSubgraph adds a message graph as a subgraph node in the state graph
+func (g *StateGraph) AddSubgraph(name string, subgraph *MessageGraph, opts ...SubgraphOption) error {
+	sg, err := NewSubgraph(name, subgraph, opts...)
 	if err != nil {
 		return err
 	}
 
 	g.AddNode(name, "Subgraph: "+name, sg.Execute)
+	if g.subgraphs == nil {
+		g.subgraphs = make(map[string]*Subgraph)
+	}
+	g.subgraphs[name] = sg
 	return nil
 }
 
+// registerSubgraph records a subgraph node for state inspection
+func (g *MessageGraph) registerSubgraph(name string, sg *Subgraph) {
+	if g.subgraphs == nil {
+		g.subgraphs = make(map[string]*Subgraph)
+	}
+	g.subgraphs[name] = sg
+}
+
 // CreateSubgraph creates and adds a subgraph using a builder function
-func (g *MessageGraph) CreateSubgraph(name string, builder func(*MessageGraph)) error {
+func (g *MessageGraph) CreateSubgraph(name string, builder func(*MessageGraph), opts ...SubgraphOption) error {
 	subgraph := NewMessageGraph()
 	builder(subgraph)
-	return g.AddSubgraph(name, subgraph)
+	return g.AddSubgraph(name, subgraph, opts...)
 }
 
 // CompositeGraph allows composing multiple graphs together
@@ -2,6 +2,9 @@ package graph
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -45,3 +48,269 @@ func TestSubgraph(t *testing.T) {
 	assert.True(t, mRes["parent_visited"].(bool))
 	assert.True(t, mRes["child_visited"].(bool))
 }
+
+func TestSubgraph_IsolatedSchema(t *testing.T) {
+	// Child keeps a private scratchpad and its own message history
+	child := NewMessageGraph()
+	childSchema := NewMapSchema()
+	childSchema.RegisterReducer("scratchpad", AppendReducer)
+	child.SetSchema(childSchema)
+	child.AddNode("think", "think", func(ctx context.Context, state interface{}) (interface{}, error) {
+		m := state.(map[string]interface{})
+		return map[string]interface{}{
+			"scratchpad": []string{"thinking about " + m["question"].(string)},
+		}, nil
+	})
+	child.AddNode("answer", "answer", func(ctx context.Context, state interface{}) (interface{}, error) {
+		m := state.(map[string]interface{})
+		notes := m["scratchpad"].([]string)
+		return map[string]interface{}{
+			"answer": fmt.Sprintf("answered after %d notes", len(notes)),
+		}, nil
+	})
+	child.SetEntryPoint("think")
+	child.AddEdge("think", "answer")
+	child.AddEdge("answer", END)
+
+	parent := NewMessageGraph()
+	parent.SetSchema(NewMapSchema())
+	err := parent.AddSubgraph("research", child,
+		WithInputKeys("topic"),
+		WithInputTransformer(func(state interface{}) (interface{}, error) {
+			m := state.(map[string]interface{})
+			return map[string]interface{}{"question": m["topic"]}, nil
+		}),
+		WithOutputTransformer(func(state interface{}) (interface{}, error) {
+			m := state.(map[string]interface{})
+			return map[string]interface{}{"result": m["answer"], "scratchpad": m["scratchpad"]}, nil
+		}),
+		WithOutputKeys("result"),
+	)
+	assert.NoError(t, err)
+	parent.SetEntryPoint("research")
+	parent.AddEdge("research", END)
+
+	runnable, err := parent.Compile()
+	assert.NoError(t, err)
+
+	res, err := runnable.Invoke(context.Background(), map[string]interface{}{
+		"topic":  "go",
+		"secret": "parent only",
+	})
+	assert.NoError(t, err)
+
+	mRes := res.(map[string]interface{})
+	assert.Equal(t, "answered after 1 notes", mRes["result"])
+	assert.Equal(t, "parent only", mRes["secret"])
+	assert.NotContains(t, mRes, "scratchpad")
+	assert.NotContains(t, mRes, "question")
+	assert.NotContains(t, mRes, "answer")
+}
+
+func TestSubgraph_KeyFilterRequiresMap(t *testing.T) {
+	child := NewMessageGraph()
+	child.AddNode("noop", "noop", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return state, nil
+	})
+	child.SetEntryPoint("noop")
+	child.AddEdge("noop", END)
+
+	sg, err := NewSubgraph("child", child, WithInputKeys("a"))
+	assert.NoError(t, err)
+
+	_, err = sg.Execute(context.Background(), "not a map")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "subgraph child input")
+}
+
+func newInterruptingChild(visits map[string]int) *MessageGraph {
+	child := NewMessageGraph()
+	child.AddNode("prepare", "prepare", func(ctx context.Context, state interface{}) (interface{}, error) {
+		visits["prepare"]++
+		m := state.(map[string]interface{})
+		m["prepared"] = true
+		return m, nil
+	})
+	child.AddNode("approve", "approve", func(ctx context.Context, state interface{}) (interface{}, error) {
+		visits["approve"]++
+		answer, err := Interrupt(ctx, "approve?")
+		if err != nil {
+			return nil, err
+		}
+		m := state.(map[string]interface{})
+		m["approved"] = answer
+		return m, nil
+	})
+	child.SetEntryPoint("prepare")
+	child.AddEdge("prepare", "approve")
+	child.AddEdge("approve", END)
+	return child
+}
+
+func TestSubgraph_InterruptPropagation(t *testing.T) {
+	parent := NewMessageGraph()
+	err := parent.AddSubgraph("review", newInterruptingChild(map[string]int{}))
+	assert.NoError(t, err)
+	parent.SetEntryPoint("review")
+	parent.AddEdge("review", END)
+
+	runnable, err := parent.Compile()
+	assert.NoError(t, err)
+
+	_, err = runnable.Invoke(context.Background(), map[string]interface{}{})
+
+	var interrupt *GraphInterrupt
+	assert.True(t, errors.As(err, &interrupt))
+	assert.Equal(t, "review", interrupt.Node)
+	assert.Equal(t, "approve?", interrupt.InterruptValue)
+	if assert.NotNil(t, interrupt.Subgraph) {
+		assert.Equal(t, "approve", interrupt.Subgraph.Node)
+	}
+}
+
+func TestSubgraph_CheckpointNamespaceAndResume(t *testing.T) {
+	visits := map[string]int{}
+	store := NewMemoryCheckpointStore()
+
+	parent := NewCheckpointableMessageGraphWithConfig(CheckpointConfig{
+		Store:    store,
+		AutoSave: true,
+	})
+	parent.AddNode("start", "start", func(ctx context.Context, state interface{}) (interface{}, error) {
+		visits["start"]++
+		return state, nil
+	})
+	err := parent.AddSubgraph("review", newInterruptingChild(visits))
+	assert.NoError(t, err)
+	parent.SetEntryPoint("start")
+	parent.AddEdge("start", "review")
+	parent.AddEdge("review", END)
+
+	runnable, err := parent.CompileCheckpointable()
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	config := &Config{Configurable: map[string]interface{}{"thread_id": "t1"}}
+
+	_, err = runnable.InvokeWithConfig(ctx, map[string]interface{}{}, config)
+	var interrupt *GraphInterrupt
+	assert.True(t, errors.As(err, &interrupt))
+	assert.Equal(t, "review", interrupt.Node)
+
+	// The child checkpoints under its own namespace
+	childCheckpoints, err := store.List(ctx, SubgraphNamespace("t1", "review"))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, childCheckpoints)
+
+	snapshot, err := runnable.GetState(ctx, config, WithSubgraphs())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"review"}, snapshot.Next)
+	if assert.Contains(t, snapshot.Subgraphs, "review") {
+		child := snapshot.Subgraphs["review"]
+		assert.Equal(t, []string{"approve"}, child.Next)
+		assert.Equal(t, true, child.Values.(map[string]interface{})["prepared"])
+	}
+
+	// Resuming continues inside the interrupted child node
+	res, err := runnable.InvokeWithConfig(ctx, snapshot.Values, &Config{
+		Configurable: map[string]interface{}{"thread_id": "t1"},
+		ResumeFrom:   snapshot.Next,
+		ResumeValue:  "yes",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "yes", res.(map[string]interface{})["approved"])
+	assert.Equal(t, 1, visits["start"])
+	assert.Equal(t, 1, visits["prepare"])
+	assert.Equal(t, 2, visits["approve"])
+}
+
+// llmStartCounter counts the LLM calls reported to it
+type llmStartCounter struct {
+	NoOpCallbackHandler
+	mu    sync.Mutex
+	calls int
+}
+
+func (c *llmStartCounter) OnLLMStart(ctx context.Context, serialized map[string]interface{}, prompts []string, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+}
+
+func TestSubgraph_CheckpointedChildKeepsParentCallbacks(t *testing.T) {
+	store := NewMemoryCheckpointStore()
+
+	child := NewMessageGraph()
+	child.AddNode("generate", "generate", llmNode(&usageLLM{input: 1, output: 1}, "small"))
+	child.SetEntryPoint("generate")
+	child.AddEdge("generate", END)
+
+	parent := NewCheckpointableMessageGraphWithConfig(CheckpointConfig{
+		Store:    store,
+		AutoSave: true,
+	})
+	err := parent.AddSubgraph("child", child)
+	assert.NoError(t, err)
+	parent.SetEntryPoint("child")
+	parent.AddEdge("child", END)
+
+	runnable, err := parent.CompileCheckpointable()
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	counter := &llmStartCounter{}
+	_, err = runnable.InvokeWithConfig(ctx, map[string]interface{}{}, &Config{
+		Callbacks:    []CallbackHandler{counter},
+		Configurable: map[string]interface{}{"thread_id": "t1"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, counter.calls)
+
+	// Steps of the child are not checkpointed on the parent's thread
+	checkpoints, err := store.List(ctx, "t1")
+	assert.NoError(t, err)
+	for _, cp := range checkpoints {
+		assert.NotEqual(t, "generate", cp.NodeName)
+	}
+}
+
+func TestStateGraph_SubgraphInterruptAndResume(t *testing.T) {
+	visits := map[string]int{}
+	parent := NewStateGraph()
+	parent.AddNode("start", "start", func(ctx context.Context, state interface{}) (interface{}, error) {
+		visits["start"]++
+		m := state.(map[string]interface{})
+		m["started"] = true
+		return m, nil
+	})
+	err := parent.AddSubgraph("review", newInterruptingChild(visits))
+	assert.NoError(t, err)
+	parent.SetEntryPoint("start")
+	parent.AddEdge("start", "review")
+	parent.AddEdge("review", END)
+
+	runnable, err := parent.Compile()
+	assert.NoError(t, err)
+
+	// The interrupt of the child stops the parent with its state
+	res, err := runnable.Invoke(context.Background(), map[string]interface{}{})
+	var interrupt *GraphInterrupt
+	if assert.True(t, errors.As(err, &interrupt)) {
+		assert.Equal(t, "review", interrupt.Node)
+		assert.Equal(t, "approve?", interrupt.InterruptValue)
+		assert.Equal(t, []string{"review"}, interrupt.NextNodes)
+		if assert.NotNil(t, interrupt.Subgraph) {
+			assert.Equal(t, "approve", interrupt.Subgraph.Node)
+		}
+	}
+	assert.Equal(t, true, res.(map[string]interface{})["started"])
+
+	// Resuming runs the child again with the resume value
+	res, err = runnable.InvokeWithConfig(context.Background(), res, &Config{
+		ResumeFrom:  interrupt.NextNodes,
+		ResumeValue: "yes",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "yes", res.(map[string]interface{})["approved"])
+	assert.Equal(t, 1, visits["start"])
+}
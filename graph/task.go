@@ -0,0 +1,132 @@
+package graph
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/smallnest/langgraphgo/log"
+)
+
+// taskEvent marks the checkpoints that record the results of tasks
+const taskEvent = "task"
+
+// Task runs fn, a side effect such as charging a card or sending a message, once per
+// execution of the calling node, even when the node is re-executed. When the graph runs
+// with checkpointing, the result of fn is recorded the first time it succeeds, and when
+// the node runs again before completing its step, e.g. after a crash or when resuming
+// an interrupt, the recorded result is returned without calling fn. Keys identify tasks
+// within a node, so a node running several tasks must give each its own key.
+//
+// Results are recorded apart from the checkpoints of the thread, and deleted once the
+// step of the node is saved. They are decoded into T when the checkpoint store returns
+// them as generic JSON values, so T must survive a JSON round trip. Without
+// checkpointing fn always runs.
+func Task[T any](ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	scope := checkpointScopeFromContext(ctx)
+	if scope == nil {
+		return fn(ctx)
+	}
+	node := NodeNameFromContext(ctx)
+	id := taskCheckpointID(scope.threadID, node, key)
+
+	recorded, err := scope.store.Load(ctx, id)
+	switch {
+	case err == nil:
+		scope.addTask(node, id)
+		result, err := decodeTaskResult[T](recorded.State)
+		if err != nil {
+			return zero, fmt.Errorf("failed to decode result of task %s: %w", key, err)
+		}
+		return result, nil
+	case !errors.Is(err, ErrCheckpointNotFound):
+		return zero, fmt.Errorf("failed to load task %s: %w", key, err)
+	}
+
+	result, err := fn(ctx)
+	if err != nil {
+		return zero, err
+	}
+
+	checkpoint := &Checkpoint{
+		ID:        id,
+		NodeName:  node,
+		State:     result,
+		Timestamp: time.Now(),
+		Version:   1,
+		Metadata: map[string]interface{}{
+			"execution_id": taskNamespace(scope.threadID),
+			"event":        taskEvent,
+			"task_key":     key,
+		},
+	}
+	addCheckpointMetadata(ctx, checkpoint.Metadata)
+
+	if err := scope.store.Save(ctx, checkpoint); err != nil {
+		return zero, fmt.Errorf("failed to record task %s: %w", key, err)
+	}
+	scope.addTask(node, id)
+	return result, nil
+}
+
+// taskNamespace returns the execution ID under which the task results of a thread
+// are recorded, keeping them out of the thread's history
+func taskNamespace(threadID string) string {
+	return threadID + CheckpointNamespaceSeparator + "#tasks"
+}
+
+// taskCheckpointID returns the ID of the checkpoint recording a task of a node
+func taskCheckpointID(threadID, node, key string) string {
+	sum := sha256.Sum256([]byte(threadID + "\x00" + node + "\x00" + key))
+	return "task_" + hex.EncodeToString(sum[:])
+}
+
+// addTask records that the running execution of a node uses a task record
+func (s *checkpointScope) addTask(node, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tasks == nil {
+		s.tasks = make(map[string][]string)
+	}
+	s.tasks[node] = append(s.tasks[node], id)
+}
+
+// endTasks deletes the task records of the nodes whose execution completed
+func (s *checkpointScope) endTasks(ctx context.Context, nodes []string) {
+	s.mu.Lock()
+	var ids []string
+	for _, node := range nodes {
+		ids = append(ids, s.tasks[node]...)
+		delete(s.tasks, node)
+	}
+	s.mu.Unlock()
+
+	for _, id := range ids {
+		if err := s.store.Delete(ctx, id); err != nil {
+			log.WarnContext(ctx, "failed to delete task record", "checkpoint_id", id, "error", err)
+		}
+	}
+}
+
+// decodeTaskResult converts a recorded task result into T
+func decodeTaskResult[T any](value interface{}) (T, error) {
+	if result, ok := value.(T); ok {
+		return result, nil
+	}
+
+	var result T
+	if value == nil {
+		return result, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return result, err
+	}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
@@ -0,0 +1,167 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type receipt struct {
+	ID     string  `json:"id"`
+	Amount float64 `json:"amount"`
+}
+
+// jsonCheckpointStore stores checkpoints as JSON, like the persistent stores do
+type jsonCheckpointStore struct {
+	*MemoryCheckpointStore
+}
+
+func (s *jsonCheckpointStore) Save(ctx context.Context, checkpoint *Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	var decoded Checkpoint
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	return s.MemoryCheckpointStore.Save(ctx, &decoded)
+}
+
+// chargeGraph builds a graph whose charge node charges a card once and then fails
+// while crash is set
+func chargeGraph(t *testing.T, store CheckpointStore, charges *int, crash *bool) *CheckpointableRunnable {
+	g := NewCheckpointableMessageGraphWithConfig(CheckpointConfig{Store: store, AutoSave: true})
+	g.AddNode("order", "order", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return state.(string) + ">order", nil
+	})
+	g.AddNode("charge", "charge", func(ctx context.Context, state interface{}) (interface{}, error) {
+		r, err := Task(ctx, "charge-card", func(ctx context.Context) (receipt, error) {
+			*charges++
+			return receipt{ID: "ch_1", Amount: 9.99}, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if *crash {
+			return nil, errors.New("crashed after charging")
+		}
+		return state.(string) + ">charged " + r.ID, nil
+	})
+	g.SetEntryPoint("order")
+	g.AddEdge("order", "charge")
+	g.AddEdge("charge", END)
+	runnable, err := g.CompileCheckpointable()
+	assert.NoError(t, err)
+	return runnable
+}
+
+func TestTask_ResumeAfterCrash(t *testing.T) {
+	for name, store := range map[string]CheckpointStore{
+		"Memory": NewMemoryCheckpointStore(),
+		"JSON":   &jsonCheckpointStore{NewMemoryCheckpointStore()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			charges, crash := 0, true
+			runnable := chargeGraph(t, store, &charges, &crash)
+			config := &Config{Configurable: map[string]interface{}{"thread_id": "order-1"}}
+
+			_, err := runnable.InvokeWithConfig(context.Background(), "start", config)
+			assert.Error(t, err)
+			assert.Equal(t, 1, charges)
+
+			// The recorded task stays out of the thread's history
+			history, err := store.List(context.Background(), "order-1")
+			assert.NoError(t, err)
+			for _, cp := range history {
+				assert.NotEqual(t, taskEvent, cp.Metadata["event"])
+			}
+			snapshot, err := runnable.GetState(context.Background(), config)
+			assert.NoError(t, err)
+			assert.Equal(t, "start>order", snapshot.Values)
+
+			// Resuming the failed node returns the recorded receipt without charging again
+			crash = false
+			resume := &Config{Configurable: config.Configurable, ResumeFrom: []string{"charge"}}
+			result, err := runnable.InvokeWithConfig(context.Background(), snapshot.Values, resume)
+			assert.NoError(t, err)
+			assert.Equal(t, "start>order>charged ch_1", result)
+			assert.Equal(t, 1, charges)
+
+			// The record is deleted once the step of the node is saved
+			records, err := store.List(context.Background(), taskNamespace("order-1"))
+			assert.NoError(t, err)
+			assert.Empty(t, records)
+
+			// Once the node completed, a new run of the thread charges again
+			_, err = runnable.InvokeWithConfig(context.Background(), "again", config)
+			assert.NoError(t, err)
+			assert.Equal(t, 2, charges)
+		})
+	}
+}
+
+func TestTask_ResumeInterrupt(t *testing.T) {
+	sent := 0
+	g := NewCheckpointableMessageGraph()
+	g.AddNode("notify", "notify", func(ctx context.Context, state interface{}) (interface{}, error) {
+		id, err := Task(ctx, "send", func(ctx context.Context) (string, error) {
+			sent++
+			return "msg_1", nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		answer, err := Interrupt(ctx, "sent "+id+", confirm?")
+		if err != nil {
+			return nil, err
+		}
+		return id + ":" + answer.(string), nil
+	})
+	g.SetEntryPoint("notify")
+	g.AddEdge("notify", END)
+	runnable, err := g.CompileCheckpointable()
+	assert.NoError(t, err)
+
+	config := &Config{Configurable: map[string]interface{}{"thread_id": "t1"}}
+	_, err = runnable.InvokeWithConfig(context.Background(), "state", config)
+	var interrupt *GraphInterrupt
+	assert.ErrorAs(t, err, &interrupt)
+	assert.Equal(t, "sent msg_1, confirm?", interrupt.InterruptValue)
+
+	result, err := runnable.InvokeWithConfig(context.Background(), "state", &Config{
+		Configurable: config.Configurable,
+		ResumeFrom:   []string{"notify"},
+		ResumeValue:  "yes",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "msg_1:yes", result)
+	assert.Equal(t, 1, sent)
+}
+
+func TestTask_WithoutCheckpointing(t *testing.T) {
+	calls := 0
+	for i := 0; i < 2; i++ {
+		out, err := Task(context.Background(), "count", func(ctx context.Context) (int, error) {
+			calls++
+			return calls, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, i+1, out)
+	}
+
+	// Failed tasks are not recorded
+	ctx := withCheckpointScope(context.Background(), NewMemoryCheckpointStore(), "t1")
+	_, err := Task(ctx, "fail", func(ctx context.Context) (int, error) {
+		return 0, errors.New("declined")
+	})
+	assert.EqualError(t, err, "declined")
+	out, err := Task(ctx, "fail", func(ctx context.Context) (int, error) {
+		return 42, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 42, out)
+}
@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"github.com/tmc/langchaingo/llms"
+)
+
+// TokenUsage holds the token counts reported by an LLM call
+type TokenUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+// Add returns the sum of two token usages
+func (u TokenUsage) Add(other TokenUsage) TokenUsage {
+	return TokenUsage{
+		InputTokens:  u.InputTokens + other.InputTokens,
+		OutputTokens: u.OutputTokens + other.OutputTokens,
+		TotalTokens:  u.TotalTokens + other.TotalTokens,
+	}
+}
+
+// IsZero reports whether no tokens were recorded
+func (u TokenUsage) IsZero() bool {
+	return u.InputTokens == 0 && u.OutputTokens == 0 && u.TotalTokens == 0
+}
+
+// Generation info keys used by the langchaingo providers
+var (
+	inputTokenKeys  = []string{"PromptTokens", "InputTokens", "inputTokens"}
+	outputTokenKeys = []string{"CompletionTokens", "OutputTokens", "outputTokens"}
+	totalTokenKeys  = []string{"TotalTokens", "totalTokens"}
+)
+
+// TokenUsageFromResponse extracts the token counts from the response passed to OnLLMEnd.
+// It understands langchaingo *llms.ContentResponse values and raw generation info maps.
+// Providers repeat the usage of a call on every choice, so the first choice reporting
+// usage is used.
+func TokenUsageFromResponse(response interface{}) TokenUsage {
+	switch resp := response.(type) {
+	case *llms.ContentResponse:
+		if resp == nil {
+			return TokenUsage{}
+		}
+		for _, choice := range resp.Choices {
+			if usage := TokenUsageFromGenerationInfo(choice.GenerationInfo); !usage.IsZero() {
+				return usage
+			}
+		}
+	case llms.ContentResponse:
+		return TokenUsageFromResponse(&resp)
+	case map[string]interface{}:
+		return TokenUsageFromGenerationInfo(resp)
+	}
+	return TokenUsage{}
+}
+
+// TokenUsageFromGenerationInfo extracts token counts from a choice's generation info
+func TokenUsageFromGenerationInfo(info map[string]interface{}) TokenUsage {
+	usage := TokenUsage{
+		InputTokens:  firstInt(info, inputTokenKeys),
+		OutputTokens: firstInt(info, outputTokenKeys),
+		TotalTokens:  firstInt(info, totalTokenKeys),
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.InputTokens + usage.OutputTokens
+	}
+	return usage
+}
+
+// firstInt returns the first numeric value found under the given keys
+func firstInt(info map[string]interface{}, keys []string) int {
+	for _, key := range keys {
+		switch v := info[key].(type) {
+		case int:
+			return v
+		case int32:
+			return int(v)
+		case int64:
+			return int(v)
+		case float64:
+			return int(v)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,31 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestTokenUsageFromResponse(t *testing.T) {
+	// OpenAI style
+	usage := TokenUsageFromResponse(&llms.ContentResponse{Choices: []*llms.ContentChoice{{
+		GenerationInfo: map[string]interface{}{"PromptTokens": 10, "CompletionTokens": 4, "TotalTokens": 14},
+	}}})
+	assert.Equal(t, TokenUsage{InputTokens: 10, OutputTokens: 4, TotalTokens: 14}, usage)
+
+	// Anthropic style repeats usage on every choice; it is counted once
+	usage = TokenUsageFromResponse(&llms.ContentResponse{Choices: []*llms.ContentChoice{
+		{GenerationInfo: map[string]interface{}{"InputTokens": 7, "OutputTokens": 3}},
+		{GenerationInfo: map[string]interface{}{"InputTokens": 7, "OutputTokens": 3}},
+	}})
+	assert.Equal(t, TokenUsage{InputTokens: 7, OutputTokens: 3, TotalTokens: 10}, usage)
+
+	// Raw generation info with JSON numbers
+	usage = TokenUsageFromResponse(map[string]interface{}{"inputTokens": float64(2), "outputTokens": float64(1)})
+	assert.Equal(t, 3, usage.TotalTokens)
+
+	assert.True(t, TokenUsageFromResponse("no usage").IsZero())
+	assert.Equal(t, TokenUsage{InputTokens: 3, OutputTokens: 2, TotalTokens: 5},
+		TokenUsage{InputTokens: 1, OutputTokens: 1, TotalTokens: 2}.Add(TokenUsage{InputTokens: 2, OutputTokens: 1, TotalTokens: 3}))
+}
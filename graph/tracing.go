@@ -2,7 +2,10 @@ package graph
 
 import (
 	"context"
+	"sync"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // TraceEvent represents different types of events in graph execution
@@ -83,6 +86,7 @@ func (f TraceHookFunc) OnEvent(ctx context.Context, span *TraceSpan) {
 
 // Tracer manages trace collection and hooks
 type Tracer struct {
+	mu    sync.RWMutex
 	hooks []TraceHook
 	spans map[string]*TraceSpan
 }
@@ -97,9 +101,25 @@ func NewTracer() *Tracer {
 
 // AddHook registers a new trace hook
 func (t *Tracer) AddHook(hook TraceHook) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.hooks = append(t.hooks, hook)
 }
 
+// getHooks returns a snapshot of the registered hooks
+func (t *Tracer) getHooks() []TraceHook {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.hooks
+}
+
+// addSpan stores a span
+func (t *Tracer) addSpan(span *TraceSpan) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans[span.ID] = span
+}
+
 // StartSpan creates a new trace span
 func (t *Tracer) StartSpan(ctx context.Context, event TraceEvent, nodeName string) *TraceSpan {
 	span := &TraceSpan{
@@ -115,10 +135,10 @@ func (t *Tracer) StartSpan(ctx context.Context, event TraceEvent, nodeName strin
 		span.ParentID = parentSpan.ID
 	}
 
-	t.spans[span.ID] = span
+	t.addSpan(span)
 
 	// Notify hooks
-	for _, hook := range t.hooks {
+	for _, hook := range t.getHooks() {
 		hook.OnEvent(ctx, span)
 	}
 
@@ -142,7 +162,7 @@ func (t *Tracer) EndSpan(ctx context.Context, span *TraceSpan, state interface{}
 	}
 
 	// Notify hooks
-	for _, hook := range t.hooks {
+	for _, hook := range t.getHooks() {
 		hook.OnEvent(ctx, span)
 	}
 }
@@ -165,21 +185,30 @@ func (t *Tracer) TraceEdgeTraversal(ctx context.Context, fromNode, toNode string
 		span.ParentID = parentSpan.ID
 	}
 
-	t.spans[span.ID] = span
+	t.addSpan(span)
 
 	// Notify hooks
-	for _, hook := range t.hooks {
+	for _, hook := range t.getHooks() {
 		hook.OnEvent(ctx, span)
 	}
 }
 
 // GetSpans returns all collected spans
 func (t *Tracer) GetSpans() map[string]*TraceSpan {
-	return t.spans
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	spans := make(map[string]*TraceSpan, len(t.spans))
+	for id, span := range t.spans {
+		spans[id] = span
+	}
+	return spans
 }
 
 // Clear removes all collected spans
 func (t *Tracer) Clear() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.spans = make(map[string]*TraceSpan)
 }
 
@@ -203,7 +232,7 @@ func SpanFromContext(ctx context.Context) *TraceSpan {
 
 // generateSpanID creates a unique span identifier
 func generateSpanID() string {
-	return time.Now().Format("20060102150405.000000")
+	return uuid.New().String()
 }
 
 // TracedRunnable wraps a Runnable with tracing capabilities
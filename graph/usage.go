@@ -0,0 +1,442 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrBudgetExceeded is returned (wrapped in a *BudgetExceededError) when a run
+// uses more tokens or money than its UsageBudget allows
+var ErrBudgetExceeded = errors.New("usage budget exceeded")
+
+// RunGuard is implemented by callbacks that can stop a run between steps.
+// The runnables call CheckRun after every step and abort with the returned error.
+type RunGuard interface {
+	CheckRun(ctx context.Context) error
+}
+
+// checkRunGuards returns the first error reported by a RunGuard in the config callbacks
+func checkRunGuards(ctx context.Context) error {
+	config := GetConfig(ctx)
+	if config == nil {
+		return nil
+	}
+	for _, cb := range config.Callbacks {
+		if guard, ok := cb.(RunGuard); ok {
+			if err := guard.CheckRun(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CheckpointMetadataProvider is implemented by callbacks that add entries to the
+// metadata of checkpoints saved during a run
+type CheckpointMetadataProvider interface {
+	CheckpointMetadata(ctx context.Context) map[string]interface{}
+}
+
+// addCheckpointMetadata merges the metadata of the providers in the config callbacks
+func addCheckpointMetadata(ctx context.Context, metadata map[string]interface{}) {
+	config := GetConfig(ctx)
+	if config == nil {
+		return
+	}
+	for _, cb := range config.Callbacks {
+		if provider, ok := cb.(CheckpointMetadataProvider); ok {
+			for k, v := range provider.CheckpointMetadata(ctx) {
+				metadata[k] = v
+			}
+		}
+	}
+}
+
+// ModelPrice is the price of a model in currency units per million tokens
+type ModelPrice struct {
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+}
+
+// PriceTable maps model names to their prices. A model without an exact entry
+// uses the longest entry that prefixes its name, so "gpt-4o" also prices
+// "gpt-4o-2024-08-06".
+type PriceTable map[string]ModelPrice
+
+// Cost returns the cost of the given usage of a model, or 0 if the model is not priced
+func (t PriceTable) Cost(model string, usage TokenUsage) float64 {
+	price, ok := t[model]
+	if !ok {
+		best := ""
+		for name, p := range t {
+			if strings.HasPrefix(model, name) && len(name) > len(best) {
+				best, price = name, p
+			}
+		}
+	}
+	return float64(usage.InputTokens)*price.InputPerMillion/1e6 +
+		float64(usage.OutputTokens)*price.OutputPerMillion/1e6
+}
+
+// Usage aggregates token counts and cost of LLM calls
+type Usage struct {
+	TokenUsage
+	Cost  float64 `json:"cost"`
+	Calls int     `json:"calls"`
+}
+
+// add records one LLM call
+func (u Usage) add(tokens TokenUsage, cost float64) Usage {
+	return Usage{TokenUsage: u.TokenUsage.Add(tokens), Cost: u.Cost + cost, Calls: u.Calls + 1}
+}
+
+// UsageReport is the usage of a run, thread or tracker broken down by node and model
+type UsageReport struct {
+	Total   Usage            `json:"total"`
+	ByNode  map[string]Usage `json:"by_node,omitempty"`
+	ByModel map[string]Usage `json:"by_model,omitempty"`
+}
+
+// record adds one LLM call to the report
+func (r *UsageReport) record(node, model string, tokens TokenUsage, cost float64) {
+	r.Total = r.Total.add(tokens, cost)
+	if node != "" {
+		if r.ByNode == nil {
+			r.ByNode = make(map[string]Usage)
+		}
+		r.ByNode[node] = r.ByNode[node].add(tokens, cost)
+	}
+	if r.ByModel == nil {
+		r.ByModel = make(map[string]Usage)
+	}
+	r.ByModel[model] = r.ByModel[model].add(tokens, cost)
+}
+
+// clone returns a deep copy of the report
+func (r *UsageReport) clone() UsageReport {
+	if r == nil {
+		return UsageReport{}
+	}
+	c := UsageReport{Total: r.Total}
+	if r.ByNode != nil {
+		c.ByNode = make(map[string]Usage, len(r.ByNode))
+		for k, v := range r.ByNode {
+			c.ByNode[k] = v
+		}
+	}
+	if r.ByModel != nil {
+		c.ByModel = make(map[string]Usage, len(r.ByModel))
+		for k, v := range r.ByModel {
+			c.ByModel[k] = v
+		}
+	}
+	return c
+}
+
+// UsageMetadataKey is the checkpoint metadata key holding the UsageReport of the thread
+const UsageMetadataKey = "usage"
+
+// UsageFromMetadata returns the usage report stored in checkpoint or snapshot metadata.
+// It handles metadata read back from persistent stores, where the report is a decoded JSON map.
+func UsageFromMetadata(metadata map[string]interface{}) (UsageReport, bool) {
+	switch v := metadata[UsageMetadataKey].(type) {
+	case UsageReport:
+		return v, true
+	case *UsageReport:
+		if v != nil {
+			return *v, true
+		}
+	case map[string]interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return UsageReport{}, false
+		}
+		var report UsageReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			return UsageReport{}, false
+		}
+		return report, true
+	}
+	return UsageReport{}, false
+}
+
+// UsageBudget limits the usage of a single run. Zero values mean no limit.
+type UsageBudget struct {
+	MaxTokens int     `json:"max_tokens"`
+	MaxCost   float64 `json:"max_cost"`
+}
+
+// exceededBy reports whether the usage is over the budget
+func (b UsageBudget) exceededBy(u Usage) bool {
+	return (b.MaxTokens > 0 && u.TotalTokens > b.MaxTokens) ||
+		(b.MaxCost > 0 && u.Cost > b.MaxCost)
+}
+
+// BudgetExceededError reports a run stopped because it went over its UsageBudget
+type BudgetExceededError struct {
+	RunID  string
+	Usage  Usage
+	Budget UsageBudget
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("%v: run %s used %d tokens (limit %d), cost %.6f (limit %.6f)",
+		ErrBudgetExceeded, e.RunID, e.Usage.TotalTokens, e.Budget.MaxTokens, e.Usage.Cost, e.Budget.MaxCost)
+}
+
+// Is makes errors.Is(err, ErrBudgetExceeded) match
+func (e *BudgetExceededError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// UsageTrackerOption configures a UsageTracker
+type UsageTrackerOption func(*UsageTracker)
+
+// WithPriceTable sets the prices used to compute costs
+func WithPriceTable(prices PriceTable) UsageTrackerOption {
+	return func(t *UsageTracker) {
+		t.prices = prices
+	}
+}
+
+// WithUsageBudget aborts runs whose usage goes over the budget with a *BudgetExceededError
+func WithUsageBudget(budget UsageBudget) UsageTrackerOption {
+	return func(t *UsageTracker) {
+		t.budget = budget
+	}
+}
+
+// WithMaxThreads bounds the number of threads whose usage is kept, default 10000.
+// The usage of the least recently active threads is dropped first; zero keeps all threads.
+func WithMaxThreads(limit int) UsageTrackerOption {
+	return func(t *UsageTracker) {
+		t.maxThreads = limit
+	}
+}
+
+// llmCall is an in-flight LLM call
+type llmCall struct {
+	model  string
+	node   string
+	thread string
+	run    string
+}
+
+// UsageTracker is a callback handler that accounts the token usage and cost of LLM calls.
+// Usage is aggregated per node, per model, per thread and per run; the usage of the
+// thread is added to the metadata of checkpoints under UsageMetadataKey, and an
+// optional budget stops runs that go over it.
+//
+// The usage of a run is kept until the run ends, and the usage of the most recently
+// active threads up to WithMaxThreads, so that a tracker can be shared by a long-lived
+// service. Reset clears all the usage.
+type UsageTracker struct {
+	NoOpCallbackHandler
+
+	prices     PriceTable
+	budget     UsageBudget
+	maxThreads int
+
+	mu          sync.Mutex
+	calls       map[string]llmCall
+	total       UsageReport
+	threads     map[string]*UsageReport
+	threadSeen  map[string]uint64
+	threadClock uint64
+	runs        map[string]*UsageReport
+}
+
+// NewUsageTracker creates a new usage tracker
+func NewUsageTracker(opts ...UsageTrackerOption) *UsageTracker {
+	t := &UsageTracker{
+		maxThreads: 10000,
+		calls:      make(map[string]llmCall),
+		threads:    make(map[string]*UsageReport),
+		threadSeen: make(map[string]uint64),
+		runs:       make(map[string]*UsageReport),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// callFromContext describes an LLM call made with the given context
+func callFromContext(ctx context.Context, model string) llmCall {
+	if model == "" {
+		model = "unknown"
+	}
+	return llmCall{
+		model:  model,
+		node:   NodeNameFromContext(ctx),
+		thread: threadIDFromContext(ctx),
+		run:    rootRunIDFromContext(ctx),
+	}
+}
+
+// OnLLMStart implements CallbackHandler
+func (t *UsageTracker) OnLLMStart(ctx context.Context, serialized map[string]interface{}, prompts []string, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	model, _ := serialized["model"].(string)
+	if model == "" {
+		model, _ = metadata["model"].(string)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls[runID] = callFromContext(ctx, model)
+}
+
+// OnLLMEnd implements CallbackHandler, recording the token usage of the response
+func (t *UsageTracker) OnLLMEnd(ctx context.Context, response interface{}, runID string) {
+	tokens := TokenUsageFromResponse(response)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	call, ok := t.calls[runID]
+	if !ok {
+		call = callFromContext(ctx, "")
+	}
+	delete(t.calls, runID)
+
+	if tokens.IsZero() {
+		return
+	}
+	cost := t.prices.Cost(call.model, tokens)
+
+	t.total.record(call.node, call.model, tokens, cost)
+	if call.thread != "" {
+		t.report(t.threads, call.thread).record(call.node, call.model, tokens, cost)
+		t.touchThread(call.thread)
+	}
+	if call.run != "" {
+		t.report(t.runs, call.run).record(call.node, call.model, tokens, cost)
+	}
+}
+
+// OnLLMError implements CallbackHandler
+func (t *UsageTracker) OnLLMError(ctx context.Context, err error, runID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.calls, runID)
+}
+
+// OnChainEnd implements CallbackHandler, dropping the usage of a finished run
+func (t *UsageTracker) OnChainEnd(ctx context.Context, outputs map[string]interface{}, runID string) {
+	t.endRun(ctx, runID)
+}
+
+// OnChainError implements CallbackHandler, dropping the usage of a failed run
+func (t *UsageTracker) OnChainError(ctx context.Context, err error, runID string) {
+	t.endRun(ctx, runID)
+}
+
+// endRun drops the usage of a top-level run once it ends; its subgraph runs are
+// accounted to it and end before it
+func (t *UsageTracker) endRun(ctx context.Context, runID string) {
+	if rootRunIDFromContext(ctx) != runID {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.runs, runID)
+}
+
+// touchThread marks a thread as recently active, dropping the least recently active
+// thread beyond the limit
+func (t *UsageTracker) touchThread(threadID string) {
+	t.threadClock++
+	t.threadSeen[threadID] = t.threadClock
+	if t.maxThreads <= 0 || len(t.threads) <= t.maxThreads {
+		return
+	}
+
+	oldest, oldestSeen := "", uint64(0)
+	for id, seen := range t.threadSeen {
+		if oldest == "" || seen < oldestSeen {
+			oldest, oldestSeen = id, seen
+		}
+	}
+	delete(t.threads, oldest)
+	delete(t.threadSeen, oldest)
+}
+
+// Reset clears the usage recorded so far
+func (t *UsageTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total = UsageReport{}
+	t.threads = make(map[string]*UsageReport)
+	t.threadSeen = make(map[string]uint64)
+	t.runs = make(map[string]*UsageReport)
+}
+
+// report returns the report for key, creating it if needed
+func (t *UsageTracker) report(reports map[string]*UsageReport, key string) *UsageReport {
+	r, ok := reports[key]
+	if !ok {
+		r = &UsageReport{}
+		reports[key] = r
+	}
+	return r
+}
+
+// Total returns the usage of all calls seen by the tracker
+func (t *UsageTracker) Total() UsageReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.total.clone()
+}
+
+// ThreadUsage returns the usage of all runs of a thread
+func (t *UsageTracker) ThreadUsage(threadID string) UsageReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.threads[threadID].clone()
+}
+
+// RunUsage returns the usage of a single graph run, including its subgraphs, while the
+// run is in progress
+func (t *UsageTracker) RunUsage(runID string) UsageReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.runs[runID].clone()
+}
+
+// CheckRun implements RunGuard, enforcing the budget of the current run
+func (t *UsageTracker) CheckRun(ctx context.Context) error {
+	runID := rootRunIDFromContext(ctx)
+	if runID == "" {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r, ok := t.runs[runID]
+	if !ok || !t.budget.exceededBy(r.Total) {
+		return nil
+	}
+	return &BudgetExceededError{RunID: runID, Usage: r.Total, Budget: t.budget}
+}
+
+// CheckpointMetadata implements CheckpointMetadataProvider, recording the usage of the thread
+func (t *UsageTracker) CheckpointMetadata(ctx context.Context) map[string]interface{} {
+	threadID := threadIDFromContext(ctx)
+	if threadID == "" {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r, ok := t.threads[threadID]
+	if !ok {
+		return nil
+	}
+	return map[string]interface{}{UsageMetadataKey: r.clone()}
+}
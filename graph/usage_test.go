@@ -0,0 +1,188 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// usageLLM is a model reporting a fixed token usage on every call
+type usageLLM struct {
+	input, output int
+}
+
+func (m *usageLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+		Content:        "ok",
+		GenerationInfo: map[string]interface{}{"PromptTokens": m.input, "CompletionTokens": m.output},
+	}}}, nil
+}
+
+func (m *usageLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func llmNode(model llms.Model, modelName string) func(context.Context, interface{}) (interface{}, error) {
+	return func(ctx context.Context, state interface{}) (interface{}, error) {
+		_, err := GenerateContent(ctx, model, []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "hi")}, llms.WithModel(modelName))
+		return state, err
+	}
+}
+
+func TestPriceTable_Cost(t *testing.T) {
+	prices := PriceTable{
+		"gpt-4o":      {InputPerMillion: 2.5, OutputPerMillion: 10},
+		"gpt-4o-mini": {InputPerMillion: 0.15, OutputPerMillion: 0.6},
+	}
+	usage := TokenUsage{InputTokens: 1000000, OutputTokens: 1000000}
+
+	assert.InDelta(t, 12.5, prices.Cost("gpt-4o", usage), 1e-9)
+	assert.InDelta(t, 12.5, prices.Cost("gpt-4o-2024-08-06", usage), 1e-9)
+	assert.InDelta(t, 0.75, prices.Cost("gpt-4o-mini-2024-07-18", usage), 1e-9)
+	assert.Equal(t, 0.0, prices.Cost("claude", usage))
+}
+
+func TestUsageTracker_AggregatesRun(t *testing.T) {
+	tracker := NewUsageTracker(WithPriceTable(PriceTable{
+		"big":   {InputPerMillion: 1000, OutputPerMillion: 2000},
+		"small": {InputPerMillion: 100, OutputPerMillion: 200},
+	}))
+	model := &usageLLM{input: 10, output: 5}
+
+	g := NewStateGraph()
+	g.AddNode("planner", "planner", llmNode(model, "big"))
+	g.AddNode("worker", "worker", llmNode(model, "small"))
+	g.SetEntryPoint("planner")
+	g.AddEdge("planner", "worker")
+	g.AddEdge("worker", END)
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	config := &Config{
+		Callbacks:    []CallbackHandler{tracker},
+		Configurable: map[string]interface{}{"thread_id": "t1"},
+	}
+	for i := 0; i < 2; i++ {
+		_, err = runnable.InvokeWithConfig(context.Background(), "in", config)
+		assert.NoError(t, err)
+	}
+
+	thread := tracker.ThreadUsage("t1")
+	assert.Equal(t, 4, thread.Total.Calls)
+	assert.Equal(t, 60, thread.Total.TotalTokens)
+	assert.InDelta(t, 2*(0.02+0.002), thread.Total.Cost, 1e-9)
+	assert.Equal(t, 2, thread.ByNode["planner"].Calls)
+	assert.Equal(t, 30, thread.ByModel["small"].TotalTokens)
+
+	assert.Equal(t, thread, tracker.Total())
+	assert.Equal(t, 0, tracker.ThreadUsage("other").Total.Calls)
+}
+
+func TestUsageTracker_Budget(t *testing.T) {
+	tracker := NewUsageTracker(WithUsageBudget(UsageBudget{MaxTokens: 40}))
+	model := &usageLLM{input: 10, output: 5}
+
+	g := NewStateGraph()
+	g.AddNode("agent", "agent", llmNode(model, "gpt"))
+	g.SetEntryPoint("agent")
+	g.AddConditionalEdge("agent", func(ctx context.Context, state interface{}) string {
+		return "agent"
+	})
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	_, err = runnable.InvokeWithConfig(context.Background(), "in", &Config{Callbacks: []CallbackHandler{tracker}})
+	assert.True(t, errors.Is(err, ErrBudgetExceeded))
+
+	var budgetErr *BudgetExceededError
+	if assert.True(t, errors.As(err, &budgetErr)) {
+		assert.Equal(t, 45, budgetErr.Usage.TotalTokens)
+		assert.Equal(t, 3, budgetErr.Usage.Calls)
+		// The usage of the run is dropped once it ends
+		assert.Equal(t, 0, tracker.RunUsage(budgetErr.RunID).Total.Calls)
+	}
+}
+
+func TestUsageTracker_Retention(t *testing.T) {
+	tracker := NewUsageTracker(WithMaxThreads(2))
+	model := &usageLLM{input: 3, output: 2}
+
+	var runID string
+	g := NewStateGraph()
+	g.AddNode("agent", "agent", func(ctx context.Context, state interface{}) (interface{}, error) {
+		_, err := llmNode(model, "gpt")(ctx, state)
+		runID = RunIDFromContext(ctx)
+		assert.Equal(t, 5, tracker.RunUsage(runID).Total.TotalTokens)
+		return state, err
+	})
+	g.SetEntryPoint("agent")
+	g.AddEdge("agent", END)
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	for _, thread := range []string{"t1", "t2", "t1", "t3"} {
+		_, err = runnable.InvokeWithConfig(context.Background(), "in", &Config{
+			Callbacks:    []CallbackHandler{tracker},
+			Configurable: map[string]interface{}{"thread_id": thread},
+		})
+		assert.NoError(t, err)
+	}
+
+	// Finished runs are dropped, and the least recently active thread beyond the limit
+	assert.Empty(t, tracker.runs)
+	assert.Equal(t, 0, tracker.RunUsage(runID).Total.Calls)
+	assert.Equal(t, 2, tracker.ThreadUsage("t1").Total.Calls)
+	assert.Equal(t, 0, tracker.ThreadUsage("t2").Total.Calls)
+	assert.Equal(t, 1, tracker.ThreadUsage("t3").Total.Calls)
+	assert.Equal(t, 4, tracker.Total().Total.Calls)
+
+	tracker.Reset()
+	assert.Equal(t, 0, tracker.Total().Total.Calls)
+	assert.Equal(t, 0, tracker.ThreadUsage("t1").Total.Calls)
+}
+
+func TestUsageTracker_CheckpointMetadata(t *testing.T) {
+	tracker := NewUsageTracker()
+	model := &usageLLM{input: 3, output: 2}
+
+	g := NewCheckpointableMessageGraph()
+	g.AddNode("agent", "agent", llmNode(model, "gpt"))
+	g.AddNode("review", "review", llmNode(model, "gpt"))
+	g.SetEntryPoint("agent")
+	g.AddEdge("agent", "review")
+	g.AddEdge("review", END)
+
+	runnable, err := g.CompileCheckpointable()
+	assert.NoError(t, err)
+
+	config := &Config{
+		Callbacks:    []CallbackHandler{tracker},
+		Configurable: map[string]interface{}{"thread_id": "usage-thread"},
+	}
+	_, err = runnable.InvokeWithConfig(context.Background(), "in", config)
+	assert.NoError(t, err)
+
+	snapshot, err := runnable.GetState(context.Background(), config)
+	assert.NoError(t, err)
+
+	report, ok := UsageFromMetadata(snapshot.Metadata)
+	if assert.True(t, ok) {
+		assert.Equal(t, 10, report.Total.TotalTokens)
+		assert.Equal(t, 1, report.ByNode["review"].Calls)
+	}
+
+	// Reports read back from persistent stores are decoded JSON maps
+	decoded := map[string]interface{}{UsageMetadataKey: map[string]interface{}{
+		"total": map[string]interface{}{"input_tokens": 6.0, "output_tokens": 4.0, "total_tokens": 10.0, "calls": 2.0},
+	}}
+	report, ok = UsageFromMetadata(decoded)
+	assert.True(t, ok)
+	assert.Equal(t, 10, report.Total.TotalTokens)
+	assert.Equal(t, 2, report.Total.Calls)
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 
 	"github.com/google/uuid"
+	"github.com/smallnest/langgraphgo/log"
 )
 
 // generateRunID generates a unique run ID for callbacks
@@ -62,3 +64,89 @@ func GetConfig(ctx context.Context) *Config {
 	}
 	return nil
 }
+
+type runScopeKey struct{}
+
+// runScope identifies the graph run a context belongs to
+type runScope struct {
+	runID  string
+	rootID string
+}
+
+// withRunID marks the context as belonging to the given graph run.
+// Nested runs (e.g. subgraphs) keep the ID of the outermost run as their root.
+// The run and thread IDs are also attached to records logged with the context.
+func withRunID(ctx context.Context, runID string) context.Context {
+	scope := runScope{runID: runID, rootID: runID}
+	if parent, ok := ctx.Value(runScopeKey{}).(runScope); ok {
+		scope.rootID = parent.rootID
+	}
+	ctx = context.WithValue(ctx, runScopeKey{}, scope)
+
+	attrs := []slog.Attr{slog.String(log.KeyRunID, runID)}
+	if threadID := threadIDFromContext(ctx); threadID != "" {
+		attrs = append(attrs, slog.String(log.KeyThreadID, threadID))
+	}
+	return log.ContextWithAttrs(ctx, attrs...)
+}
+
+// RunIDFromContext returns the ID of the graph run executing with the context
+func RunIDFromContext(ctx context.Context) string {
+	if scope, ok := ctx.Value(runScopeKey{}).(runScope); ok {
+		return scope.runID
+	}
+	return ""
+}
+
+// rootRunIDFromContext returns the ID of the outermost graph run of the context
+func rootRunIDFromContext(ctx context.Context) string {
+	if scope, ok := ctx.Value(runScopeKey{}).(runScope); ok {
+		return scope.rootID
+	}
+	return ""
+}
+
+type parentRunKey struct{}
+
+// WithParentRunID returns a context whose callback runs are reported as children of
+// the given run. The graph sets it to the graph run and then to each node run, so the
+// LLM and tool calls made inside a node form a run tree under it.
+func WithParentRunID(ctx context.Context, runID string) context.Context {
+	return context.WithValue(ctx, parentRunKey{}, runID)
+}
+
+// ParentRunIDFromContext returns the ID of the innermost callback run of the context,
+// to be passed as parentRunID to the callbacks of nested runs, or nil outside any run
+func ParentRunIDFromContext(ctx context.Context) *string {
+	if runID, ok := ctx.Value(parentRunKey{}).(string); ok && runID != "" {
+		return &runID
+	}
+	return nil
+}
+
+type nodeNameKey struct{}
+
+// withNodeName marks the context as belonging to the execution of the given node,
+// which is also attached to records logged with the context
+func withNodeName(ctx context.Context, nodeName string) context.Context {
+	ctx = context.WithValue(ctx, nodeNameKey{}, nodeName)
+	return log.ContextWithAttrs(ctx, slog.String(log.KeyNode, nodeName))
+}
+
+// NodeNameFromContext returns the name of the node executing with the context
+func NodeNameFromContext(ctx context.Context) string {
+	if name, ok := ctx.Value(nodeNameKey{}).(string); ok {
+		return name
+	}
+	return ""
+}
+
+// threadIDFromContext returns the thread_id of the config in the context
+func threadIDFromContext(ctx context.Context) string {
+	if config := GetConfig(ctx); config != nil && config.Configurable != nil {
+		if tid, ok := config.Configurable["thread_id"].(string); ok {
+			return tid
+		}
+	}
+	return ""
+}
@@ -4,22 +4,123 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 )
 
 // Exporter provides methods to export graphs in different formats
 type Exporter struct {
-	graph *MessageGraph
+	topology *graphTopology
 }
 
 // NewExporter creates a new graph exporter for the given graph
 func NewExporter(graph *MessageGraph) *Exporter {
-	return &Exporter{graph: graph}
+	return &Exporter{topology: messageGraphTopology(graph)}
+}
+
+// NewStateGraphExporter creates a new graph exporter for the given state graph
+func NewStateGraphExporter(graph *StateGraph) *Exporter {
+	return &Exporter{topology: stateGraphTopology(graph)}
+}
+
+// graphTopology is the static structure of a graph used for rendering
+type graphTopology struct {
+	nodes      []string
+	entryPoint string
+	edges      []Edge
+
+	// conditional maps the "From" node of conditional edges to their path map (nil if unknown)
+	conditional map[string]map[string]string
+
+	// subgraphs holds the topology of nodes that are subgraphs
+	subgraphs map[string]*graphTopology
+}
+
+func messageGraphTopology(g *MessageGraph) *graphTopology {
+	t := &graphTopology{
+		entryPoint:  g.entryPoint,
+		edges:       g.edges,
+		conditional: make(map[string]map[string]string),
+		subgraphs:   make(map[string]*graphTopology),
+	}
+	for name := range g.nodes {
+		t.nodes = append(t.nodes, name)
+	}
+	sort.Strings(t.nodes)
+
+	for from := range g.conditionalEdges {
+		t.conditional[from] = nil
+	}
+	for name, sg := range g.subgraphs {
+		t.subgraphs[name] = messageGraphTopology(sg.graph)
+	}
+	return t
+}
+
+func stateGraphTopology(g *StateGraph) *graphTopology {
+	t := &graphTopology{
+		entryPoint:  g.entryPoint,
+		edges:       g.edges,
+		conditional: make(map[string]map[string]string),
+		subgraphs:   make(map[string]*graphTopology),
+	}
+	for name := range g.nodes {
+		t.nodes = append(t.nodes, name)
+	}
+	sort.Strings(t.nodes)
+
+	for from := range g.conditionalEdges {
+		t.conditional[from] = g.pathMaps[from]
+	}
+	for name, sg := range g.subgraphs {
+		t.subgraphs[name] = messageGraphTopology(sg.graph)
+	}
+	return t
+}
+
+// conditionalFrom returns the sorted "From" nodes of conditional edges
+func (t *graphTopology) conditionalFrom() []string {
+	from := make([]string, 0, len(t.conditional))
+	for name := range t.conditional {
+		from = append(from, name)
+	}
+	sort.Strings(from)
+	return from
+}
+
+// sortedPaths returns the labels of a path map in sorted order
+func sortedPaths(pathMap map[string]string) []string {
+	labels := make([]string, 0, len(pathMap))
+	for label := range pathMap {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// hasEnd reports whether any edge or path leads to END
+func (t *graphTopology) hasEnd() bool {
+	for _, edge := range t.edges {
+		if edge.To == END {
+			return true
+		}
+	}
+	for _, pathMap := range t.conditional {
+		for _, target := range pathMap {
+			if target == END {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // MermaidOptions defines configuration for Mermaid diagram generation
 type MermaidOptions struct {
 	// Direction of the flowchart (e.g., "TD", "LR")
 	Direction string
+
+	// Trace highlights the path taken by an execution, if set
+	Trace *ExecutionTrace
 }
 
 // DrawMermaid generates a Mermaid diagram representation of the graph
@@ -29,122 +130,451 @@ func (ge *Exporter) DrawMermaid() string {
 	})
 }
 
+// mermaidWriter tracks link indexes so that traced edges can be styled
+type mermaidWriter struct {
+	sb          strings.Builder
+	trace       *ExecutionTrace
+	linkIndex   int
+	tracedLinks []string
+}
+
+// link writes an edge and records it for highlighting when it was traversed
+func (w *mermaidWriter) link(indent, line string, traced bool) {
+	w.sb.WriteString(indent + line + "\n")
+	if traced {
+		w.tracedLinks = append(w.tracedLinks, fmt.Sprintf("%d", w.linkIndex))
+	}
+	w.linkIndex++
+}
+
 // DrawMermaidWithOptions generates a Mermaid diagram with custom options
 func (ge *Exporter) DrawMermaidWithOptions(opts MermaidOptions) string {
-	var sb strings.Builder
+	t := ge.topology
+	w := &mermaidWriter{trace: opts.Trace}
 
 	// Start Mermaid flowchart
 	direction := opts.Direction
 	if direction == "" {
 		direction = "TD"
 	}
-	sb.WriteString(fmt.Sprintf("flowchart %s\n", direction))
+	w.sb.WriteString(fmt.Sprintf("flowchart %s\n", direction))
 
 	// Add entry point styling
-	if ge.graph.entryPoint != "" {
-		sb.WriteString(fmt.Sprintf("    %s[[\"%s\"]]\n", ge.graph.entryPoint, ge.graph.entryPoint))
-		sb.WriteString(fmt.Sprintf("    %s --> %s\n", "START", ge.graph.entryPoint))
-		sb.WriteString("    START([\"START\"])\n")
-		sb.WriteString("    style START fill:#90EE90\n")
+	if t.entryPoint != "" {
+		if _, ok := t.subgraphs[t.entryPoint]; ok {
+			w.writeMermaidCluster("    ", t.entryPoint, t.entryPoint, t.subgraphs[t.entryPoint])
+		} else {
+			w.sb.WriteString(fmt.Sprintf("    %s[[\"%s\"]]\n", t.entryPoint, w.label(t.entryPoint, t.entryPoint)))
+		}
+		w.link("    ", fmt.Sprintf("%s --> %s", "START", t.entryPoint), w.trace.hasEdge(START, t.entryPoint))
+		w.sb.WriteString("    START([\"START\"])\n")
+		w.sb.WriteString("    style START fill:#90EE90\n")
+	}
+
+	w.writeMermaidBody("    ", "", t, true)
+
+	// Style entry point
+	if t.entryPoint != "" {
+		w.sb.WriteString(fmt.Sprintf("    style %s fill:#87CEEB\n", t.entryPoint))
 	}
 
-	// Get sorted node names for consistent output
-	nodeNames := make([]string, 0, len(ge.graph.nodes))
-	for name := range ge.graph.nodes {
-		if name != ge.graph.entryPoint && name != END {
-			nodeNames = append(nodeNames, name)
+	// Highlight the traced path
+	if w.trace != nil {
+		w.writeMermaidTraceStyles("", t)
+		if len(w.tracedLinks) > 0 {
+			w.sb.WriteString(fmt.Sprintf("    linkStyle %s stroke:#FF8C00,stroke-width:3px\n", strings.Join(w.tracedLinks, ",")))
 		}
 	}
-	sort.Strings(nodeNames)
 
+	return w.sb.String()
+}
+
+// writeMermaidBody writes the nodes and edges of a graph level.
+// Node IDs are prefixed so that nested subgraphs do not collide with their parents.
+func (w *mermaidWriter) writeMermaidBody(indent, prefix string, t *graphTopology, topLevel bool) {
 	// Add regular nodes
-	for _, name := range nodeNames {
-		sb.WriteString(fmt.Sprintf("    %s[\"%s\"]\n", name, name))
+	for _, name := range t.nodes {
+		if name == END || (topLevel && name == t.entryPoint) {
+			continue
+		}
+		if sub, ok := t.subgraphs[name]; ok {
+			w.writeMermaidCluster(indent, prefix+name, name, sub)
+			continue
+		}
+		w.sb.WriteString(fmt.Sprintf("%s%s[\"%s\"]\n", indent, prefix+name, w.label(prefix+name, name)))
 	}
 
 	// Add END node if referenced
-	hasEnd := false
-	for _, edge := range ge.graph.edges {
-		if edge.To == END {
-			hasEnd = true
-			break
-		}
+	if t.hasEnd() {
+		w.sb.WriteString(fmt.Sprintf("%s%sEND([\"END\"])\n", indent, prefix))
+		w.sb.WriteString(fmt.Sprintf("%sstyle %sEND fill:#FFB6C1\n", indent, prefix))
 	}
 
-	if hasEnd {
-		sb.WriteString("    END([\"END\"])\n")
-		sb.WriteString("    style END fill:#FFB6C1\n")
+	// Add edges
+	for _, edge := range t.edges {
+		w.link(indent, fmt.Sprintf("%s --> %s", prefix+edge.From, prefix+edge.To), w.trace.hasEdge(prefix+edge.From, prefix+edge.To))
 	}
 
-	// Add edges
-	for _, edge := range ge.graph.edges {
-		sb.WriteString(fmt.Sprintf("    %s --> %s\n", edge.From, edge.To))
+	// Add conditional edges, labelled with their path maps when known
+	for _, from := range t.conditionalFrom() {
+		pathMap := t.conditional[from]
+		if len(pathMap) == 0 {
+			w.link(indent, fmt.Sprintf("%s -.-> %s_condition((?))", prefix+from, prefix+from), false)
+			w.sb.WriteString(fmt.Sprintf("%sstyle %s_condition fill:#FFFFE0,stroke:#333,stroke-dasharray: 5 5\n", indent, prefix+from))
+			continue
+		}
+		for _, label := range sortedPaths(pathMap) {
+			target := pathMap[label]
+			w.link(indent, fmt.Sprintf("%s -.->|%s| %s", prefix+from, label, prefix+target), w.trace.hasEdge(prefix+from, prefix+target))
+		}
 	}
+}
 
-	// Add conditional edges
-	for from := range ge.graph.conditionalEdges {
-		sb.WriteString(fmt.Sprintf("    %s -.-> %s_condition((?))\n", from, from))
-		sb.WriteString(fmt.Sprintf("    style %s_condition fill:#FFFFE0,stroke:#333,stroke-dasharray: 5 5\n", from))
+// writeMermaidCluster renders a subgraph node as a Mermaid subgraph block
+func (w *mermaidWriter) writeMermaidCluster(indent, id, name string, t *graphTopology) {
+	w.sb.WriteString(fmt.Sprintf("%ssubgraph %s[\"%s\"]\n", indent, id, w.label(id, name)))
+	w.writeMermaidBody(indent+"    ", id+"_", t, false)
+	w.sb.WriteString(indent + "end\n")
+}
+
+// writeMermaidTraceStyles highlights the traced nodes
+func (w *mermaidWriter) writeMermaidTraceStyles(prefix string, t *graphTopology) {
+	for _, name := range t.nodes {
+		if sub, ok := t.subgraphs[name]; ok {
+			w.writeMermaidTraceStyles(prefix+name+"_", sub)
+		}
+		if _, ok := w.trace.Nodes[prefix+name]; ok {
+			w.sb.WriteString(fmt.Sprintf("    style %s fill:#FFD700,stroke:#FF8C00,stroke-width:3px\n", prefix+name))
+		}
 	}
+}
 
-	// Style entry point
-	if ge.graph.entryPoint != "" {
-		sb.WriteString(fmt.Sprintf("    style %s fill:#87CEEB\n", ge.graph.entryPoint))
+// label returns the display label of a node, annotated with its traced duration
+func (w *mermaidWriter) label(id, name string) string {
+	if suffix := w.trace.nodeSummary(id); suffix != "" {
+		return name + "<br/>" + suffix
 	}
+	return name
+}
 
-	return sb.String()
+// DOTOptions defines configuration for DOT diagram generation
+type DOTOptions struct {
+	// Direction of the graph (e.g., "TD", "LR")
+	Direction string
+
+	// Trace highlights the path taken by an execution, if set
+	Trace *ExecutionTrace
 }
 
 // DrawDOT generates a DOT (Graphviz) representation of the graph
 func (ge *Exporter) DrawDOT() string {
-	var sb strings.Builder
+	return ge.DrawDOTWithOptions(DOTOptions{
+		Direction: "TD",
+	})
+}
+
+// dotWriter renders a graph level in DOT format
+type dotWriter struct {
+	sb    strings.Builder
+	trace *ExecutionTrace
+}
 
-	sb.WriteString("digraph G {\n")
-	sb.WriteString("    rankdir=TD;\n")
-	sb.WriteString("    node [shape=box];\n")
+// DrawDOTWithOptions generates a DOT (Graphviz) representation with custom options
+func (ge *Exporter) DrawDOTWithOptions(opts DOTOptions) string {
+	t := ge.topology
+	w := &dotWriter{trace: opts.Trace}
+
+	direction := opts.Direction
+	if direction == "" {
+		direction = "TD"
+	}
+
+	w.sb.WriteString("digraph G {\n")
+	w.sb.WriteString(fmt.Sprintf("    rankdir=%s;\n", direction))
+	w.sb.WriteString("    node [shape=box];\n")
+	if len(t.subgraphs) > 0 {
+		// Allow edges to be clipped at cluster boundaries
+		w.sb.WriteString("    compound=true;\n")
+	}
 
 	// Add START node if there's an entry point
-	if ge.graph.entryPoint != "" {
-		sb.WriteString("    START [label=\"START\", shape=ellipse, style=filled, fillcolor=lightgreen];\n")
-		sb.WriteString(fmt.Sprintf("    START -> %s;\n", ge.graph.entryPoint))
+	if t.entryPoint != "" {
+		w.sb.WriteString("    START [label=\"START\", shape=ellipse, style=filled, fillcolor=lightgreen];\n")
+		w.writeDOTEdge("    ", "", t, START, t.entryPoint, nil)
 	}
 
 	// Add entry point styling
-	if ge.graph.entryPoint != "" {
-		sb.WriteString(fmt.Sprintf("    %s [style=filled, fillcolor=lightblue];\n", ge.graph.entryPoint))
+	if t.entryPoint != "" {
+		if _, ok := t.subgraphs[t.entryPoint]; !ok {
+			w.sb.WriteString(fmt.Sprintf("    %s [style=filled, fillcolor=lightblue%s];\n", t.entryPoint, w.nodeAttrs(t.entryPoint)))
+		}
 	}
 
-	// Add END node styling if referenced
-	hasEnd := false
-	for _, edge := range ge.graph.edges {
-		if edge.To == END {
-			hasEnd = true
-			break
+	w.writeDOTBody("    ", "", t, true)
+
+	w.sb.WriteString("}\n")
+	return w.sb.String()
+}
+
+// writeDOTBody writes the nodes and edges of a graph level
+func (w *dotWriter) writeDOTBody(indent, prefix string, t *graphTopology, topLevel bool) {
+	// Add nodes, clusters for subgraphs
+	for _, name := range t.nodes {
+		if sub, ok := t.subgraphs[name]; ok {
+			id := prefix + name
+			w.sb.WriteString(fmt.Sprintf("%ssubgraph cluster_%s {\n", indent, id))
+			w.sb.WriteString(fmt.Sprintf("%s    label=\"%s\";\n", indent, name))
+			w.writeDOTBody(indent+"    ", id+"_", sub, false)
+			w.sb.WriteString(indent + "}\n")
+			continue
+		}
+		if topLevel && name == t.entryPoint {
+			continue // Already styled
+		}
+		if attrs := w.nodeAttrs(prefix + name); attrs != "" || !topLevel {
+			w.sb.WriteString(fmt.Sprintf("%s%s [label=\"%s\"%s];\n", indent, prefix+name, name, attrs))
 		}
 	}
 
-	if hasEnd {
-		sb.WriteString("    END [label=\"END\", shape=ellipse, style=filled, fillcolor=lightpink];\n")
+	// Add END node styling if referenced
+	if t.hasEnd() {
+		w.sb.WriteString(fmt.Sprintf("%s%sEND [label=\"END\", shape=ellipse, style=filled, fillcolor=lightpink];\n", indent, prefix))
 	}
 
 	// Add edges
-	for _, edge := range ge.graph.edges {
-		sb.WriteString(fmt.Sprintf("    %s -> %s;\n", edge.From, edge.To))
+	for _, edge := range t.edges {
+		w.writeDOTEdge(indent, prefix, t, edge.From, edge.To, nil)
 	}
 
-	// Add conditional edges
-	for from := range ge.graph.conditionalEdges {
-		sb.WriteString(fmt.Sprintf("    %s -> %s_condition [style=dashed, label=\"?\"];\n", from, from))
-		sb.WriteString(fmt.Sprintf("    %s_condition [label=\"?\", shape=diamond, style=filled, fillcolor=lightyellow];\n", from))
+	// Add conditional edges, labelled with their path maps when known
+	for _, from := range t.conditionalFrom() {
+		pathMap := t.conditional[from]
+		if len(pathMap) == 0 {
+			w.sb.WriteString(fmt.Sprintf("%s%s -> %s_condition [style=dashed, label=\"?\"];\n", indent, prefix+from, prefix+from))
+			w.sb.WriteString(fmt.Sprintf("%s%s_condition [label=\"?\", shape=diamond, style=filled, fillcolor=lightyellow];\n", indent, prefix+from))
+			continue
+		}
+		for _, label := range sortedPaths(pathMap) {
+			w.writeDOTEdge(indent, prefix, t, from, pathMap[label], []string{"style=dashed", fmt.Sprintf("label=\"%s\"", label)})
+		}
 	}
+}
 
-	sb.WriteString("}\n")
-	return sb.String()
+// writeDOTEdge writes an edge, attaching edges of subgraph nodes to their clusters
+func (w *dotWriter) writeDOTEdge(indent, prefix string, t *graphTopology, from, to string, attrs []string) {
+	source := prefix + from
+	target := prefix + to
+
+	if sub, ok := t.subgraphs[from]; ok {
+		source = prefix + from + "_" + sub.exitNode()
+		attrs = append(attrs, fmt.Sprintf("ltail=cluster_%s", prefix+from))
+	}
+	if sub, ok := t.subgraphs[to]; ok {
+		target = prefix + to + "_" + sub.entryPoint
+		attrs = append(attrs, fmt.Sprintf("lhead=cluster_%s", prefix+to))
+	}
+
+	if w.trace.hasEdge(prefix+from, prefix+to) {
+		attrs = append(attrs, "color=darkorange", "penwidth=3")
+	}
+
+	if len(attrs) == 0 {
+		w.sb.WriteString(fmt.Sprintf("%s%s -> %s;\n", indent, source, target))
+		return
+	}
+	w.sb.WriteString(fmt.Sprintf("%s%s -> %s [%s];\n", indent, source, target, strings.Join(attrs, ", ")))
+}
+
+// nodeAttrs returns the extra DOT attributes of a traced node
+func (w *dotWriter) nodeAttrs(id string) string {
+	if w.trace == nil {
+		return ""
+	}
+	if _, ok := w.trace.Nodes[id]; !ok {
+		return ""
+	}
+	attrs := ", color=darkorange, penwidth=3"
+	if summary := w.trace.nodeSummary(id); summary != "" {
+		attrs += fmt.Sprintf(", xlabel=\"%s\"", summary)
+	}
+	return attrs
+}
+
+// exitNode returns the node used as the tail of edges leaving a subgraph cluster
+func (t *graphTopology) exitNode() string {
+	if t.hasEnd() {
+		return END
+	}
+	return t.entryPoint
+}
+
+// ExecutionTrace records the nodes and edges visited by an execution,
+// used to overlay the path taken on a graph diagram.
+// Nodes inside subgraphs are keyed as "<subgraph>_<node>".
+type ExecutionTrace struct {
+	// Nodes maps visited nodes to their execution summary
+	Nodes map[string]*NodeTrace
+
+	// Edges contains the edges traversed during execution
+	Edges map[Edge]bool
+}
+
+// NodeTrace summarizes the executions of a single node
+type NodeTrace struct {
+	// Count is the number of times the node was executed
+	Count int
+
+	// Duration is the total execution time of the node, zero if unknown
+	Duration time.Duration
+}
+
+// NewExecutionTrace creates an empty execution trace
+func NewExecutionTrace() *ExecutionTrace {
+	return &ExecutionTrace{
+		Nodes: make(map[string]*NodeTrace),
+		Edges: make(map[Edge]bool),
+	}
+}
+
+// AddNode records an execution of a node
+func (et *ExecutionTrace) AddNode(name string, duration time.Duration) {
+	nt, ok := et.Nodes[name]
+	if !ok {
+		nt = &NodeTrace{}
+		et.Nodes[name] = nt
+	}
+	nt.Count++
+	nt.Duration += duration
+}
+
+// AddEdge records a traversal from one node to another
+func (et *ExecutionTrace) AddEdge(from, to string) {
+	et.Edges[Edge{From: from, To: to}] = true
+}
+
+// hasEdge reports whether the trace traversed the edge. It is safe on a nil trace.
+func (et *ExecutionTrace) hasEdge(from, to string) bool {
+	if et == nil {
+		return false
+	}
+	return et.Edges[Edge{From: from, To: to}]
+}
+
+// nodeSummary formats the count and duration of a traced node. It is safe on a nil trace.
+func (et *ExecutionTrace) nodeSummary(name string) string {
+	if et == nil {
+		return ""
+	}
+	nt, ok := et.Nodes[name]
+	if !ok {
+		return ""
+	}
+
+	var parts []string
+	if nt.Count > 1 {
+		parts = append(parts, fmt.Sprintf("%dx", nt.Count))
+	}
+	if nt.Duration > 0 {
+		parts = append(parts, nt.Duration.Round(time.Microsecond).String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// NewExecutionTraceFromSpans builds an execution trace from the spans of a Tracer.
+// Edge traversal spans are used when present; otherwise edges are inferred from
+// the order in which nodes started.
+func NewExecutionTraceFromSpans(spans map[string]*TraceSpan) *ExecutionTrace {
+	et := NewExecutionTrace()
+
+	var nodeSpans []*TraceSpan
+	hasTraversals := false
+	for _, span := range spans {
+		switch span.Event {
+		case TraceEventNodeStart, TraceEventNodeEnd, TraceEventNodeError:
+			if span.NodeName != "" {
+				nodeSpans = append(nodeSpans, span)
+			}
+		case TraceEventEdgeTraversal:
+			hasTraversals = true
+			et.AddEdge(span.FromNode, span.ToNode)
+		}
+	}
+
+	sort.Slice(nodeSpans, func(i, j int) bool {
+		return nodeSpans[i].StartTime.Before(nodeSpans[j].StartTime)
+	})
+
+	for i, span := range nodeSpans {
+		et.AddNode(span.NodeName, span.Duration)
+		if hasTraversals {
+			continue
+		}
+		if i == 0 {
+			et.AddEdge(START, span.NodeName)
+		} else {
+			et.AddEdge(nodeSpans[i-1].NodeName, span.NodeName)
+		}
+	}
+	if !hasTraversals && len(nodeSpans) > 0 {
+		et.AddEdge(nodeSpans[len(nodeSpans)-1].NodeName, END)
+	}
+
+	return et
+}
+
+// NewExecutionTraceFromCheckpoints builds an execution trace from a checkpoint history.
+// Checkpoint timestamps only bound each step, so node durations are approximated by
+// the time elapsed since the previous checkpoint.
+func NewExecutionTraceFromCheckpoints(checkpoints []*Checkpoint) *ExecutionTrace {
+	et := NewExecutionTrace()
+
+	sorted := make([]*Checkpoint, len(checkpoints))
+	copy(sorted, checkpoints)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	previous := []string{START}
+	var previousTime time.Time
+	for _, cp := range sorted {
+		if cp.Metadata["event"] == "interrupt" {
+			continue // Interrupts record where execution stopped, not a completed step
+		}
+
+		nodes := checkpointStepNodes(cp.NodeName)
+		var duration time.Duration
+		if !previousTime.IsZero() {
+			duration = cp.Timestamp.Sub(previousTime)
+		}
+
+		for _, node := range nodes {
+			et.AddNode(node, duration)
+			for _, prev := range previous {
+				et.AddEdge(prev, node)
+			}
+		}
+
+		previous = nodes
+		previousTime = cp.Timestamp
+	}
+
+	return et
+}
+
+// checkpointStepNodes parses the node names of a checkpoint,
+// which may be a single node or a step such as "step:[a b]"
+func checkpointStepNodes(name string) []string {
+	if !strings.HasPrefix(name, "step:") {
+		return []string{name}
+	}
+	name = strings.TrimPrefix(name, "step:")
+	name = strings.TrimSuffix(strings.TrimPrefix(name, "["), "]")
+	return strings.Fields(name)
 }
 
 // DrawASCII generates an ASCII tree representation of the graph
 func (ge *Exporter) DrawASCII() string {
-	if ge.graph.entryPoint == "" {
+	if ge.topology.entryPoint == "" {
 		return "No entry point set\n"
 	}
 
@@ -154,7 +584,7 @@ func (ge *Exporter) DrawASCII() string {
 	sb.WriteString("Graph Execution Flow:\n")
 	sb.WriteString("├── START\n")
 
-	ge.drawASCIINode(ge.graph.entryPoint, "│   ", true, visited, &sb)
+	ge.drawASCIINode(ge.topology.entryPoint, "│   ", true, visited, &sb)
 
 	return sb.String()
 }
@@ -188,14 +618,14 @@ func (ge *Exporter) drawASCIINode(nodeName string, prefix string, isLast bool, v
 
 	// Find outgoing edges
 	outgoingEdges := make([]string, 0)
-	for _, edge := range ge.graph.edges {
+	for _, edge := range ge.topology.edges {
 		if edge.From == nodeName {
 			outgoingEdges = append(outgoingEdges, edge.To)
 		}
 	}
 
 	// Check for conditional edge
-	if _, ok := ge.graph.conditionalEdges[nodeName]; ok {
+	if _, ok := ge.topology.conditional[nodeName]; ok {
 		outgoingEdges = append(outgoingEdges, "(Conditional)")
 	}
 
@@ -223,3 +653,8 @@ func (ge *Exporter) drawASCIINode(nodeName string, prefix string, isLast bool, v
 func (r *Runnable) GetGraph() *Exporter {
 	return NewExporter(r.graph)
 }
+
+// GetGraph returns a Exporter for the compiled state graph's visualization
+func (r *StateRunnable) GetGraph() *Exporter {
+	return NewStateGraphExporter(r.graph)
+}
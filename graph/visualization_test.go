@@ -3,6 +3,7 @@ package graph
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -48,3 +49,96 @@ func TestVisualization(t *testing.T) {
 	// C is not reachable via static edges from B, so it won't be shown under B.
 	// This is expected behavior for static visualization of dynamic graphs.
 }
+
+func TestStateGraphVisualization(t *testing.T) {
+	child := NewMessageGraph()
+	child.AddNode("fetch", "fetch", func(ctx context.Context, state interface{}) (interface{}, error) { return state, nil })
+	child.SetEntryPoint("fetch")
+	child.AddEdge("fetch", END)
+
+	g := NewStateGraph()
+	g.AddNode("route", "route", func(ctx context.Context, state interface{}) (interface{}, error) { return state, nil })
+	g.AddNode("answer", "answer", func(ctx context.Context, state interface{}) (interface{}, error) { return state, nil })
+	assert.NoError(t, g.AddSubgraph("research", child))
+	g.SetEntryPoint("route")
+	g.AddConditionalEdges("route", func(ctx context.Context, state interface{}) string { return "direct" },
+		map[string]string{"direct": "answer", "lookup": "research"})
+	g.AddEdge("research", "answer")
+	g.AddEdge("answer", END)
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+	exporter := runnable.GetGraph()
+
+	mermaid := exporter.DrawMermaid()
+	assert.Contains(t, mermaid, "route -.->|direct| answer")
+	assert.Contains(t, mermaid, "route -.->|lookup| research")
+	assert.Contains(t, mermaid, "subgraph research[\"research\"]")
+	assert.Contains(t, mermaid, "research_fetch --> research_END")
+	assert.Contains(t, mermaid, "research --> answer")
+
+	dot := exporter.DrawDOT()
+	assert.Contains(t, dot, "compound=true;")
+	assert.Contains(t, dot, "subgraph cluster_research {")
+	assert.Contains(t, dot, "route -> answer [style=dashed, label=\"direct\"];")
+	assert.Contains(t, dot, "route -> research_fetch [style=dashed, label=\"lookup\", lhead=cluster_research];")
+	assert.Contains(t, dot, "research_END -> answer [ltail=cluster_research];")
+}
+
+func TestVisualizationTraceOverlay(t *testing.T) {
+	g := NewMessageGraph()
+	g.AddNode("A", "A", func(ctx context.Context, state interface{}) (interface{}, error) { return state, nil })
+	g.AddNode("B", "B", func(ctx context.Context, state interface{}) (interface{}, error) { return state, nil })
+	g.AddNode("C", "C", func(ctx context.Context, state interface{}) (interface{}, error) { return state, nil })
+	g.SetEntryPoint("A")
+	g.AddEdge("A", "B")
+	g.AddEdge("B", END)
+	g.AddEdge("C", END)
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+	tracer := NewTracer()
+	runnable.SetTracer(tracer)
+
+	_, err = runnable.Invoke(context.Background(), "in")
+	assert.NoError(t, err)
+
+	trace := NewExecutionTraceFromSpans(tracer.GetSpans())
+	assert.Contains(t, trace.Nodes, "A")
+	assert.Contains(t, trace.Nodes, "B")
+	assert.NotContains(t, trace.Nodes, "C")
+	assert.True(t, trace.Edges[Edge{From: "A", To: "B"}])
+
+	exporter := runnable.GetGraph()
+
+	mermaid := exporter.DrawMermaidWithOptions(MermaidOptions{Trace: trace})
+	assert.Contains(t, mermaid, "style A fill:#FFD700")
+	assert.Contains(t, mermaid, "style B fill:#FFD700")
+	assert.NotContains(t, mermaid, "style C fill:#FFD700")
+	// START --> A, A --> B and B --> END are links 0, 1 and 2; C --> END is not traced
+	assert.Contains(t, mermaid, "linkStyle 0,1,2 stroke:#FF8C00")
+
+	dot := exporter.DrawDOTWithOptions(DOTOptions{Trace: trace})
+	assert.Contains(t, dot, "A -> B [color=darkorange, penwidth=3];")
+	assert.Contains(t, dot, "C -> END;")
+	assert.Contains(t, dot, "B [label=\"B\", color=darkorange, penwidth=3")
+}
+
+func TestExecutionTraceFromCheckpoints(t *testing.T) {
+	base := time.Now()
+	trace := NewExecutionTraceFromCheckpoints([]*Checkpoint{
+		{NodeName: "step:[b c]", Timestamp: base.Add(30 * time.Millisecond)},
+		{NodeName: "step:[a]", Timestamp: base.Add(10 * time.Millisecond)},
+		{NodeName: "d", Timestamp: base.Add(40 * time.Millisecond)},
+		{NodeName: "d", Timestamp: base.Add(50 * time.Millisecond), Metadata: map[string]interface{}{"event": "interrupt"}},
+	})
+
+	assert.Equal(t, 1, trace.Nodes["a"].Count)
+	assert.Equal(t, time.Duration(0), trace.Nodes["a"].Duration)
+	assert.Equal(t, 20*time.Millisecond, trace.Nodes["b"].Duration)
+	assert.Equal(t, 10*time.Millisecond, trace.Nodes["d"].Duration)
+	assert.True(t, trace.Edges[Edge{From: START, To: "a"}])
+	assert.True(t, trace.Edges[Edge{From: "a", To: "c"}])
+	assert.True(t, trace.Edges[Edge{From: "c", To: "d"}])
+	assert.Equal(t, 1, trace.Nodes["d"].Count)
+}
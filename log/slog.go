@@ -0,0 +1,189 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Attribute keys the framework attaches to log records of graph runs
+const (
+	KeyRunID    = "run_id"
+	KeyThreadID = "thread_id"
+	KeyNode     = "node"
+	KeyAttempt  = "attempt"
+)
+
+// StructuredLogger is a Logger that can also emit leveled records with key/value attributes.
+// The package-level *Context functions use it when it is the default logger.
+type StructuredLogger interface {
+	Logger
+	Log(ctx context.Context, level slog.Level, msg string, args ...any)
+}
+
+type contextAttrsKey struct{}
+
+// ContextWithAttrs returns a context carrying the given attributes, which are added to
+// every record logged with it. An attribute replaces an earlier one with the same key.
+func ContextWithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing := AttrsFromContext(ctx)
+	merged := make([]slog.Attr, 0, len(existing)+len(attrs))
+	for _, a := range existing {
+		replaced := false
+		for _, b := range attrs {
+			if a.Key == b.Key {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, a)
+		}
+	}
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, contextAttrsKey{}, merged)
+}
+
+// AttrsFromContext returns the attributes carried by the context
+func AttrsFromContext(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(contextAttrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// ContextHandler is a slog.Handler adding the attributes of the record's context
+type ContextHandler struct {
+	slog.Handler
+}
+
+// NewContextHandler wraps a handler so records include the attributes of their context
+func NewContextHandler(handler slog.Handler) *ContextHandler {
+	if h, ok := handler.(*ContextHandler); ok {
+		return h
+	}
+	return &ContextHandler{Handler: handler}
+}
+
+// Handle implements slog.Handler
+func (h *ContextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if attrs := AttrsFromContext(ctx); len(attrs) > 0 {
+		record = record.Clone()
+		record.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// SlogLogger implements StructuredLogger on top of a *slog.Logger
+type SlogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger creates a logger writing to the given slog logger, or slog.Default() if nil.
+// Records include the attributes of their context, such as run_id, thread_id and node.
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SlogLogger{logger: slog.New(NewContextHandler(logger.Handler()))}
+}
+
+// Slog returns the underlying slog logger
+func (l *SlogLogger) Slog() *slog.Logger {
+	return l.logger
+}
+
+// Log emits a record with key/value attributes
+func (l *SlogLogger) Log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	l.logger.Log(ctx, level, msg, args...)
+}
+
+// Debug logs debug messages
+func (l *SlogLogger) Debug(format string, v ...interface{}) {
+	l.logger.Debug(fmt.Sprintf(format, v...))
+}
+
+// Info logs informational messages
+func (l *SlogLogger) Info(format string, v ...interface{}) {
+	l.logger.Info(fmt.Sprintf(format, v...))
+}
+
+// Warn logs warning messages
+func (l *SlogLogger) Warn(format string, v ...interface{}) {
+	l.logger.Warn(fmt.Sprintf(format, v...))
+}
+
+// Error logs error messages
+func (l *SlogLogger) Error(format string, v ...interface{}) {
+	l.logger.Error(fmt.Sprintf(format, v...))
+}
+
+// Log emits a leveled record with key/value attributes through the package-level logger.
+// Loggers that are not a StructuredLogger receive the message followed by key=value pairs.
+func Log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	logger := defaultLogger
+	if sl, ok := logger.(StructuredLogger); ok {
+		sl.Log(ctx, level, msg, args...)
+		return
+	}
+
+	line := formatRecord(ctx, msg, args)
+	switch {
+	case level >= slog.LevelError:
+		logger.Error("%s", line)
+	case level >= slog.LevelWarn:
+		logger.Warn("%s", line)
+	case level >= slog.LevelInfo:
+		logger.Info("%s", line)
+	default:
+		logger.Debug("%s", line)
+	}
+}
+
+// formatRecord renders a message and its attributes as "msg key=value ..."
+func formatRecord(ctx context.Context, msg string, args []any) string {
+	record := slog.NewRecord(time.Time{}, slog.LevelInfo, msg, 0)
+	record.Add(args...)
+	record.AddAttrs(AttrsFromContext(ctx)...)
+
+	var sb strings.Builder
+	sb.WriteString(msg)
+	record.Attrs(func(a slog.Attr) bool {
+		fmt.Fprintf(&sb, " %s=%v", a.Key, a.Value)
+		return true
+	})
+	return sb.String()
+}
+
+// DebugContext logs a debug record with key/value attributes using the package-level logger
+func DebugContext(ctx context.Context, msg string, args ...any) {
+	Log(ctx, slog.LevelDebug, msg, args...)
+}
+
+// InfoContext logs an informational record with key/value attributes using the package-level logger
+func InfoContext(ctx context.Context, msg string, args ...any) {
+	Log(ctx, slog.LevelInfo, msg, args...)
+}
+
+// WarnContext logs a warning record with key/value attributes using the package-level logger
+func WarnContext(ctx context.Context, msg string, args ...any) {
+	Log(ctx, slog.LevelWarn, msg, args...)
+}
+
+// ErrorContext logs an error record with key/value attributes using the package-level logger
+func ErrorContext(ctx context.Context, msg string, args ...any) {
+	Log(ctx, slog.LevelError, msg, args...)
+}
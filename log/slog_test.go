@@ -0,0 +1,74 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestSlogLogger tests that records include the attributes of their context
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	ctx := ContextWithAttrs(context.Background(), slog.String(KeyRunID, "run-1"), slog.String(KeyNode, "a"))
+	ctx = ContextWithAttrs(ctx, slog.String(KeyNode, "b"))
+	logger.Log(ctx, slog.LevelInfo, "node finished", "duration_ms", 12)
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("invalid JSON record %q: %v", buf.String(), err)
+	}
+
+	expected := map[string]interface{}{
+		"msg":         "node finished",
+		"level":       "INFO",
+		KeyRunID:      "run-1",
+		KeyNode:       "b",
+		"duration_ms": float64(12),
+	}
+	for key, value := range expected {
+		if record[key] != value {
+			t.Errorf("Expected %s=%v, got %v", key, value, record[key])
+		}
+	}
+
+	buf.Reset()
+	logger.Debug("printf %s", "style")
+	if !strings.Contains(buf.String(), `"msg":"printf style"`) {
+		t.Errorf("Expected printf-style message, got: %s", buf.String())
+	}
+}
+
+// TestPackageLevelContextFunctions tests the structured package-level functions
+func TestPackageLevelContextFunctions(t *testing.T) {
+	originalLogger := GetDefaultLogger()
+	defer SetDefaultLogger(originalLogger)
+
+	ctx := ContextWithAttrs(context.Background(), slog.String(KeyThreadID, "t1"))
+
+	// Printf-style loggers receive the attributes as key=value pairs
+	var buf bytes.Buffer
+	SetDefaultLogger(NewCustomLogger(&buf, LogLevelInfo))
+	InfoContext(ctx, "agent started", "agent", "react")
+	DebugContext(ctx, "hidden")
+
+	output := buf.String()
+	if !strings.Contains(output, "[INFO] agent started agent=react thread_id=t1") {
+		t.Errorf("Expected formatted record, got: %s", output)
+	}
+	if strings.Contains(output, "hidden") {
+		t.Errorf("Expected debug record to be filtered, got: %s", output)
+	}
+
+	// Structured loggers receive the record as is
+	buf.Reset()
+	SetDefaultLogger(NewSlogLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+	WarnContext(ctx, "slow node", "node", "tools")
+	if !strings.Contains(buf.String(), `level=WARN msg="slow node" node=tools thread_id=t1`) {
+		t.Errorf("Expected slog record, got: %s", buf.String())
+	}
+}
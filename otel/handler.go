@@ -0,0 +1,552 @@
+// Package otel bridges graph execution callbacks to OpenTelemetry.
+//
+// A Handler is passed in graph.Config.Callbacks and turns the callback events of a
+// run into OpenTelemetry spans and metrics:
+//
+//	graph run (root)
+//	├── step        one span per superstep
+//	├── node        one span per node execution
+//	│   ├── llm     LLM calls reported with the node's run ID as parent
+//	│   ├── tool    tool calls
+//	│   └── retriever
+//
+// Spans are parented through the runID/parentRunID of the callbacks. Root runs are
+// parented to the span found in the callback context, if any.
+package otel
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/smallnest/langgraphgo/graph"
+	gootel "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the tracer and meter of this package
+const instrumentationName = "github.com/smallnest/langgraphgo/otel"
+
+// Attribute keys set on spans and metrics
+const (
+	AttrGraph        = attribute.Key("langgraph.graph")
+	AttrThreadID     = attribute.Key("langgraph.thread_id")
+	AttrRunID        = attribute.Key("langgraph.run_id")
+	AttrNode         = attribute.Key("langgraph.node")
+	AttrStep         = attribute.Key("langgraph.step")
+	AttrStepNodes    = attribute.Key("langgraph.step.nodes")
+	AttrTool         = attribute.Key("langgraph.tool")
+	AttrRetriever    = attribute.Key("langgraph.retriever")
+	AttrDocuments    = attribute.Key("langgraph.retriever.documents")
+	AttrStatus       = attribute.Key("langgraph.status")
+	AttrModel        = attribute.Key("gen_ai.request.model")
+	AttrInputTokens  = attribute.Key("gen_ai.usage.input_tokens")
+	AttrOutputTokens = attribute.Key("gen_ai.usage.output_tokens")
+	AttrTokenType    = attribute.Key("gen_ai.token.type")
+)
+
+// runKind identifies what a callback run represents
+type runKind string
+
+const (
+	kindGraph     runKind = "graph"
+	kindNode      runKind = "node"
+	kindChain     runKind = "chain"
+	kindLLM       runKind = "llm"
+	kindTool      runKind = "tool"
+	kindRetriever runKind = "retriever"
+)
+
+// run is an in-flight callback run and its span
+type run struct {
+	kind     runKind
+	name     string
+	ctx      context.Context
+	span     trace.Span
+	start    time.Time
+	graph    string
+	threadID string
+	node     string
+	model    string
+
+	// superstep tracking for graph runs
+	steps    int
+	lastStep time.Time
+}
+
+// Option configures a Handler
+type Option func(*options)
+
+type options struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	graphName      string
+}
+
+// WithTracerProvider sets the tracer provider, defaults to the global provider
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = tp
+	}
+}
+
+// WithMeterProvider sets the meter provider, defaults to the global provider
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(o *options) {
+		o.meterProvider = mp
+	}
+}
+
+// WithGraphName sets the graph name recorded on spans and metrics.
+// When unset, the RunName of the graph config is used.
+func WithGraphName(name string) Option {
+	return func(o *options) {
+		o.graphName = name
+	}
+}
+
+// Handler records graph callback events as OpenTelemetry spans and metrics.
+// It implements graph.GraphCallbackHandler and is safe for concurrent runs.
+type Handler struct {
+	tracer    trace.Tracer
+	graphName string
+
+	runCount      metric.Int64Counter
+	runDuration   metric.Float64Histogram
+	stepCount     metric.Int64Counter
+	nodeDuration  metric.Float64Histogram
+	llmDuration   metric.Float64Histogram
+	tokenCount    metric.Int64Counter
+	toolCount     metric.Int64Counter
+	toolDuration  metric.Float64Histogram
+	retrieverTime metric.Float64Histogram
+
+	mu   sync.Mutex
+	runs map[string]*run
+
+	// graphRuns maps the config of a running graph to its root run,
+	// used to attribute OnGraphStep events which carry no run ID
+	graphRuns map[*graph.Config]*run
+}
+
+// NewHandler creates an OpenTelemetry callback handler
+func NewHandler(opts ...Option) (*Handler, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.tracerProvider == nil {
+		o.tracerProvider = gootel.GetTracerProvider()
+	}
+	if o.meterProvider == nil {
+		o.meterProvider = gootel.GetMeterProvider()
+	}
+
+	h := &Handler{
+		tracer:    o.tracerProvider.Tracer(instrumentationName),
+		graphName: o.graphName,
+		runs:      make(map[string]*run),
+		graphRuns: make(map[*graph.Config]*run),
+	}
+
+	meter := o.meterProvider.Meter(instrumentationName)
+	var err error
+	if h.runCount, err = meter.Int64Counter("langgraph.graph.runs",
+		metric.WithDescription("Number of graph runs")); err != nil {
+		return nil, fmt.Errorf("failed to create metric: %w", err)
+	}
+	if h.runDuration, err = meter.Float64Histogram("langgraph.graph.duration",
+		metric.WithDescription("Duration of graph runs"), metric.WithUnit("s")); err != nil {
+		return nil, fmt.Errorf("failed to create metric: %w", err)
+	}
+	if h.stepCount, err = meter.Int64Counter("langgraph.graph.steps",
+		metric.WithDescription("Number of supersteps executed")); err != nil {
+		return nil, fmt.Errorf("failed to create metric: %w", err)
+	}
+	if h.nodeDuration, err = meter.Float64Histogram("langgraph.node.duration",
+		metric.WithDescription("Duration of node executions"), metric.WithUnit("s")); err != nil {
+		return nil, fmt.Errorf("failed to create metric: %w", err)
+	}
+	if h.llmDuration, err = meter.Float64Histogram("langgraph.llm.duration",
+		metric.WithDescription("Duration of LLM calls"), metric.WithUnit("s")); err != nil {
+		return nil, fmt.Errorf("failed to create metric: %w", err)
+	}
+	if h.tokenCount, err = meter.Int64Counter("langgraph.llm.tokens",
+		metric.WithDescription("Number of LLM tokens used"), metric.WithUnit("{token}")); err != nil {
+		return nil, fmt.Errorf("failed to create metric: %w", err)
+	}
+	if h.toolCount, err = meter.Int64Counter("langgraph.tool.calls",
+		metric.WithDescription("Number of tool calls")); err != nil {
+		return nil, fmt.Errorf("failed to create metric: %w", err)
+	}
+	if h.toolDuration, err = meter.Float64Histogram("langgraph.tool.duration",
+		metric.WithDescription("Duration of tool calls"), metric.WithUnit("s")); err != nil {
+		return nil, fmt.Errorf("failed to create metric: %w", err)
+	}
+	if h.retrieverTime, err = meter.Float64Histogram("langgraph.retriever.duration",
+		metric.WithDescription("Duration of retriever calls"), metric.WithUnit("s")); err != nil {
+		return nil, fmt.Errorf("failed to create metric: %w", err)
+	}
+
+	return h, nil
+}
+
+// startRun starts the span of a run and registers it
+func (h *Handler) startRun(ctx context.Context, kind runKind, name string, runID string, parentRunID *string, attrs ...attribute.KeyValue) *run {
+	h.mu.Lock()
+	var parent *run
+	if parentRunID != nil {
+		parent = h.runs[*parentRunID]
+	}
+	h.mu.Unlock()
+
+	r := &run{
+		kind:  kind,
+		name:  name,
+		start: time.Now(),
+	}
+
+	if parent != nil {
+		ctx = parent.ctx
+		r.graph = parent.graph
+		r.threadID = parent.threadID
+		r.node = parent.node
+	} else {
+		r.graph = h.graphName
+		if config := graph.GetConfig(ctx); config != nil {
+			if r.graph == "" {
+				r.graph = config.RunName
+			}
+			if tid, ok := config.Configurable["thread_id"].(string); ok {
+				r.threadID = tid
+			}
+		}
+	}
+	if kind == kindNode {
+		r.node = name
+	}
+
+	attrs = append(attrs, AttrRunID.String(runID))
+	if r.graph != "" {
+		attrs = append(attrs, AttrGraph.String(r.graph))
+	}
+	if r.threadID != "" {
+		attrs = append(attrs, AttrThreadID.String(r.threadID))
+	}
+	if r.node != "" {
+		attrs = append(attrs, AttrNode.String(r.node))
+	}
+
+	spanName := string(kind)
+	if name != "" {
+		spanName += " " + name
+	}
+	r.ctx, r.span = h.tracer.Start(ctx, spanName, trace.WithAttributes(attrs...))
+	r.lastStep = r.start
+
+	h.mu.Lock()
+	h.runs[runID] = r
+	h.mu.Unlock()
+
+	return r
+}
+
+// endRun removes a run and ends its span, recording the error if any
+func (h *Handler) endRun(runID string, err error) *run {
+	h.mu.Lock()
+	r, ok := h.runs[runID]
+	delete(h.runs, runID)
+	h.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	if err != nil {
+		r.span.RecordError(err)
+		r.span.SetStatus(codes.Error, err.Error())
+	} else {
+		r.span.SetStatus(codes.Ok, "")
+	}
+	r.span.End()
+	return r
+}
+
+// childKind classifies a run by the kind of its parent run.
+// Runs directly under a graph run are node executions.
+func (h *Handler) childKind(parentRunID *string, kind runKind) runKind {
+	if parentRunID == nil {
+		return kind
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if parent, ok := h.runs[*parentRunID]; ok && parent.kind == kindGraph {
+		return kindNode
+	}
+	return kind
+}
+
+// status returns the status label of a finished run
+func status(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// metricAttrs returns the common metric attributes of a run
+func (r *run) metricAttrs(err error, extra ...attribute.KeyValue) metric.MeasurementOption {
+	attrs := append([]attribute.KeyValue{AttrStatus.String(status(err))}, extra...)
+	if r.graph != "" {
+		attrs = append(attrs, AttrGraph.String(r.graph))
+	}
+	return metric.WithAttributes(attrs...)
+}
+
+// serializedName returns the name of a run from its serialized description
+func serializedName(serialized map[string]interface{}) string {
+	if name, ok := serialized["name"].(string); ok {
+		return name
+	}
+	return ""
+}
+
+// OnChainStart starts a graph run span, or a node/chain span for nested chains
+func (h *Handler) OnChainStart(ctx context.Context, serialized map[string]interface{}, inputs map[string]interface{}, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	kind := h.childKind(parentRunID, kindChain)
+	if parentRunID == nil {
+		kind = kindGraph
+	}
+
+	name := serializedName(serialized)
+	if kind == kindGraph && h.graphName != "" {
+		name = h.graphName
+	}
+
+	r := h.startRun(ctx, kind, name, runID, parentRunID)
+
+	if kind == kindGraph {
+		if config := graph.GetConfig(ctx); config != nil {
+			h.mu.Lock()
+			h.graphRuns[config] = r
+			h.mu.Unlock()
+		}
+	}
+}
+
+// OnChainEnd ends a chain span
+func (h *Handler) OnChainEnd(ctx context.Context, outputs map[string]interface{}, runID string) {
+	h.endChain(ctx, runID, nil)
+}
+
+// OnChainError ends a chain span with an error
+func (h *Handler) OnChainError(ctx context.Context, err error, runID string) {
+	h.endChain(ctx, runID, err)
+}
+
+func (h *Handler) endChain(ctx context.Context, runID string, err error) {
+	r := h.endRun(runID, err)
+	if r == nil {
+		return
+	}
+
+	elapsed := time.Since(r.start).Seconds()
+	switch r.kind {
+	case kindGraph:
+		h.mu.Lock()
+		for config, gr := range h.graphRuns {
+			if gr == r {
+				delete(h.graphRuns, config)
+			}
+		}
+		h.mu.Unlock()
+		h.runCount.Add(ctx, 1, r.metricAttrs(err))
+		h.runDuration.Record(ctx, elapsed, r.metricAttrs(err))
+	case kindNode:
+		h.nodeDuration.Record(ctx, elapsed, r.metricAttrs(err, AttrNode.String(r.node)))
+	}
+}
+
+// OnLLMStart starts an LLM span
+func (h *Handler) OnLLMStart(ctx context.Context, serialized map[string]interface{}, prompts []string, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	model := serializedName(serialized)
+	if m, ok := serialized["model"].(string); ok {
+		model = m
+	} else if m, ok := metadata["model"].(string); ok {
+		model = m
+	}
+
+	var attrs []attribute.KeyValue
+	if model != "" {
+		attrs = append(attrs, AttrModel.String(model))
+	}
+	r := h.startRun(ctx, kindLLM, model, runID, parentRunID, attrs...)
+	r.model = model
+}
+
+// OnLLMEnd ends an LLM span, recording token usage from the response
+func (h *Handler) OnLLMEnd(ctx context.Context, response interface{}, runID string) {
+	h.mu.Lock()
+	r, ok := h.runs[runID]
+	h.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	usage := graph.TokenUsageFromResponse(response)
+	if !usage.IsZero() {
+		r.span.SetAttributes(
+			AttrInputTokens.Int(usage.InputTokens),
+			AttrOutputTokens.Int(usage.OutputTokens),
+		)
+		model := AttrModel.String(r.model)
+		h.tokenCount.Add(ctx, int64(usage.InputTokens), metric.WithAttributes(model, AttrTokenType.String("input")))
+		h.tokenCount.Add(ctx, int64(usage.OutputTokens), metric.WithAttributes(model, AttrTokenType.String("output")))
+	}
+
+	h.endLLM(ctx, runID, nil)
+}
+
+// OnLLMError ends an LLM span with an error
+func (h *Handler) OnLLMError(ctx context.Context, err error, runID string) {
+	h.endLLM(ctx, runID, err)
+}
+
+func (h *Handler) endLLM(ctx context.Context, runID string, err error) {
+	if r := h.endRun(runID, err); r != nil {
+		h.llmDuration.Record(ctx, time.Since(r.start).Seconds(), r.metricAttrs(err, AttrModel.String(r.model)))
+	}
+}
+
+// OnToolStart starts a tool span. Tool events reported directly under a graph run
+// are node executions and are recorded as node spans.
+func (h *Handler) OnToolStart(ctx context.Context, serialized map[string]interface{}, inputStr string, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	name := serializedName(serialized)
+	kind := h.childKind(parentRunID, kindTool)
+
+	var attrs []attribute.KeyValue
+	if kind == kindTool {
+		attrs = append(attrs, AttrTool.String(name))
+	}
+	h.startRun(ctx, kind, name, runID, parentRunID, attrs...)
+}
+
+// OnToolEnd ends a tool span
+func (h *Handler) OnToolEnd(ctx context.Context, output string, runID string) {
+	h.endTool(ctx, runID, nil)
+}
+
+// OnToolError ends a tool span with an error
+func (h *Handler) OnToolError(ctx context.Context, err error, runID string) {
+	h.endTool(ctx, runID, err)
+}
+
+func (h *Handler) endTool(ctx context.Context, runID string, err error) {
+	r := h.endRun(runID, err)
+	if r == nil {
+		return
+	}
+
+	elapsed := time.Since(r.start).Seconds()
+	if r.kind == kindNode {
+		h.nodeDuration.Record(ctx, elapsed, r.metricAttrs(err, AttrNode.String(r.node)))
+		return
+	}
+	h.toolCount.Add(ctx, 1, r.metricAttrs(err, AttrTool.String(r.name)))
+	h.toolDuration.Record(ctx, elapsed, r.metricAttrs(err, AttrTool.String(r.name)))
+}
+
+// OnRetrieverStart starts a retriever span
+func (h *Handler) OnRetrieverStart(ctx context.Context, serialized map[string]interface{}, query string, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	name := serializedName(serialized)
+	h.startRun(ctx, kindRetriever, name, runID, parentRunID, AttrRetriever.String(name))
+}
+
+// OnRetrieverEnd ends a retriever span, recording the number of documents
+func (h *Handler) OnRetrieverEnd(ctx context.Context, documents []interface{}, runID string) {
+	h.mu.Lock()
+	r, ok := h.runs[runID]
+	h.mu.Unlock()
+	if ok {
+		r.span.SetAttributes(AttrDocuments.Int(len(documents)))
+	}
+	h.endRetriever(ctx, runID, nil)
+}
+
+// OnRetrieverError ends a retriever span with an error
+func (h *Handler) OnRetrieverError(ctx context.Context, err error, runID string) {
+	h.endRetriever(ctx, runID, err)
+}
+
+func (h *Handler) endRetriever(ctx context.Context, runID string, err error) {
+	if r := h.endRun(runID, err); r != nil {
+		h.retrieverTime.Record(ctx, time.Since(r.start).Seconds(), r.metricAttrs(err, AttrRetriever.String(r.name)))
+	}
+}
+
+// OnGraphStep records a completed superstep as a span covering the time since the
+// previous step of the same run
+func (h *Handler) OnGraphStep(ctx context.Context, stepNode string, state interface{}) {
+	r := h.graphRun(ctx)
+	if r == nil {
+		return
+	}
+
+	h.mu.Lock()
+	r.steps++
+	step := r.steps
+	start := r.lastStep
+	now := time.Now()
+	r.lastStep = now
+	h.mu.Unlock()
+
+	attrs := []attribute.KeyValue{
+		AttrStep.Int(step),
+		AttrStepNodes.String(stepNode),
+	}
+	if r.graph != "" {
+		attrs = append(attrs, AttrGraph.String(r.graph))
+	}
+	if r.threadID != "" {
+		attrs = append(attrs, AttrThreadID.String(r.threadID))
+	}
+
+	_, span := h.tracer.Start(r.ctx, "step", trace.WithTimestamp(start), trace.WithAttributes(attrs...))
+	span.End(trace.WithTimestamp(now))
+
+	var graphAttrs []attribute.KeyValue
+	if r.graph != "" {
+		graphAttrs = append(graphAttrs, AttrGraph.String(r.graph))
+	}
+	h.stepCount.Add(ctx, 1, metric.WithAttributes(graphAttrs...))
+}
+
+// graphRun finds the graph run a step belongs to by the config in the context,
+// falling back to the only running graph
+func (h *Handler) graphRun(ctx context.Context) *run {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if config := graph.GetConfig(ctx); config != nil {
+		if r, ok := h.graphRuns[config]; ok {
+			return r
+		}
+	}
+
+	var found *run
+	for _, r := range h.runs {
+		if r.kind == kindGraph {
+			if found != nil {
+				return nil // Ambiguous
+			}
+			found = r
+		}
+	}
+	return found
+}
+
+var _ graph.GraphCallbackHandler = (*Handler)(nil)
@@ -0,0 +1,168 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/smallnest/langgraphgo/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/tmc/langchaingo/llms"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTestHandler(t *testing.T) (*Handler, *tracetest.SpanRecorder, *sdkmetric.ManualReader) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	h, err := NewHandler(WithTracerProvider(tp), WithMeterProvider(mp), WithGraphName("test_graph"))
+	assert.NoError(t, err)
+	return h, recorder, reader
+}
+
+func spansByName(spans []sdktrace.ReadOnlySpan) map[string]sdktrace.ReadOnlySpan {
+	byName := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range spans {
+		byName[span.Name()] = span
+	}
+	return byName
+}
+
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func findMetric(t *testing.T, reader *sdkmetric.ManualReader, name string) metricdata.Aggregation {
+	var rm metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &rm))
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m.Data
+			}
+		}
+	}
+	t.Fatalf("metric %s not found", name)
+	return nil
+}
+
+func TestHandler_GraphRun(t *testing.T) {
+	h, recorder, reader := newTestHandler(t)
+
+	g := graph.NewMessageGraph()
+	g.AddNode("agent", "agent", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return state.(string) + " agent", nil
+	})
+	g.AddNode("tools", "tools", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return state.(string) + " tools", nil
+	})
+	g.SetEntryPoint("agent")
+	g.AddEdge("agent", "tools")
+	g.AddEdge("tools", graph.END)
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	_, err = runnable.InvokeWithConfig(context.Background(), "in", &graph.Config{
+		Callbacks:    []graph.CallbackHandler{h},
+		Configurable: map[string]interface{}{"thread_id": "thread-1"},
+	})
+	assert.NoError(t, err)
+
+	spans := spansByName(recorder.Ended())
+	root, ok := spans["graph test_graph"]
+	assert.True(t, ok)
+	assert.Equal(t, "thread-1", attr(root, AttrThreadID).AsString())
+	assert.Equal(t, codes.Ok, root.Status().Code)
+
+	for _, name := range []string{"node agent", "node tools"} {
+		node, ok := spans[name]
+		if assert.True(t, ok, name) {
+			assert.Equal(t, root.SpanContext().SpanID(), node.Parent().SpanID())
+			assert.Equal(t, root.SpanContext().TraceID(), node.SpanContext().TraceID())
+			assert.Equal(t, "thread-1", attr(node, AttrThreadID).AsString())
+		}
+	}
+
+	steps := 0
+	for _, span := range recorder.Ended() {
+		if span.Name() == "step" {
+			steps++
+			assert.Equal(t, root.SpanContext().SpanID(), span.Parent().SpanID())
+		}
+	}
+	assert.Equal(t, 2, steps)
+
+	runs := findMetric(t, reader, "langgraph.graph.runs").(metricdata.Sum[int64])
+	assert.Equal(t, int64(1), runs.DataPoints[0].Value)
+	nodes := findMetric(t, reader, "langgraph.node.duration").(metricdata.Histogram[float64])
+	assert.Len(t, nodes.DataPoints, 2)
+}
+
+func TestHandler_NestedCalls(t *testing.T) {
+	h, recorder, reader := newTestHandler(t)
+	ctx := context.Background()
+
+	graphRun := "graph-run"
+	nodeRun := "node-run"
+	h.OnChainStart(ctx, map[string]interface{}{"name": "graph"}, nil, graphRun, nil, nil, nil)
+	h.OnChainStart(ctx, map[string]interface{}{"name": "agent"}, nil, nodeRun, &graphRun, nil, nil)
+
+	h.OnLLMStart(ctx, map[string]interface{}{"model": "gpt-4o"}, []string{"hi"}, "llm-run", &nodeRun, nil, nil)
+	h.OnLLMEnd(ctx, &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+		Content: "hello",
+		GenerationInfo: map[string]interface{}{
+			"PromptTokens":     12,
+			"CompletionTokens": 5,
+		},
+	}}}, "llm-run")
+
+	h.OnToolStart(ctx, map[string]interface{}{"name": "search"}, "q", "tool-run", &nodeRun, nil, nil)
+	h.OnToolError(ctx, errors.New("boom"), "tool-run")
+
+	h.OnRetrieverStart(ctx, map[string]interface{}{"name": "docs"}, "q", "retriever-run", &nodeRun, nil, nil)
+	h.OnRetrieverEnd(ctx, []interface{}{"a", "b"}, "retriever-run")
+
+	h.OnChainEnd(ctx, nil, nodeRun)
+	h.OnChainEnd(ctx, nil, graphRun)
+
+	spans := spansByName(recorder.Ended())
+	node := spans["node agent"]
+	assert.Equal(t, spans["graph test_graph"].SpanContext().SpanID(), node.Parent().SpanID())
+
+	llm := spans["llm gpt-4o"]
+	assert.Equal(t, node.SpanContext().SpanID(), llm.Parent().SpanID())
+	assert.Equal(t, "agent", attr(llm, AttrNode).AsString())
+	assert.Equal(t, int64(12), attr(llm, AttrInputTokens).AsInt64())
+	assert.Equal(t, int64(5), attr(llm, AttrOutputTokens).AsInt64())
+
+	tool := spans["tool search"]
+	assert.Equal(t, node.SpanContext().SpanID(), tool.Parent().SpanID())
+	assert.Equal(t, codes.Error, tool.Status().Code)
+
+	retriever := spans["retriever docs"]
+	assert.Equal(t, int64(2), attr(retriever, AttrDocuments).AsInt64())
+
+	tokens := findMetric(t, reader, "langgraph.llm.tokens").(metricdata.Sum[int64])
+	total := int64(0)
+	for _, dp := range tokens.DataPoints {
+		total += dp.Value
+	}
+	assert.Equal(t, int64(17), total)
+
+	tools := findMetric(t, reader, "langgraph.tool.calls").(metricdata.Sum[int64])
+	status, _ := tools.DataPoints[0].Attributes.Value(AttrStatus)
+	assert.Equal(t, "error", status.AsString())
+}
@@ -0,0 +1,329 @@
+package prebuilt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/smallnest/langgraphgo/graph"
+	"github.com/smallnest/langgraphgo/tool"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/tools"
+)
+
+// Node names of the agent loop besides "agent" and "tools"
+const (
+	PreModelHookNode       = "pre_model_hook"
+	PostModelHookNode      = "post_model_hook"
+	StructuredResponseNode = "structured_response"
+)
+
+// DefaultStructuredResponseKey is the state key of the structured response when
+// the ResponseFormat does not set one
+const DefaultStructuredResponseKey = "structured_response"
+
+// MaxIterationsMessage is the final answer of an agent that still requests tools
+// after its maximum number of iterations
+const MaxIterationsMessage = "Sorry, need more steps to process this request."
+
+// ModelHook runs before or after each model call of an agent. It receives the
+// current state and returns a state update, e.g. messages to append.
+type ModelHook func(ctx context.Context, state map[string]interface{}) (map[string]interface{}, error)
+
+// ResponseFormat describes the structured final answer of an agent. Once the agent
+// answers without tool calls, the model is asked to fill the schema with a forced
+// tool call, and the validated value is stored in the state.
+type ResponseFormat struct {
+	// Name of the tool used to produce the response, defaults to "structured_response"
+	Name string
+	// Description of the response for the model
+	Description string
+	// Schema is the JSON Schema of the response, e.g. from tool.SchemaOf
+	Schema map[string]any
+	// StateKey is the state key of the decoded response, defaults to DefaultStructuredResponseKey
+	StateKey string
+}
+
+// WithMaxIterations limits the number of tool-calling rounds per user message. When
+// the model requests tools after the limit, the agent ends with MaxIterationsMessage.
+func WithMaxIterations(maxIterations int) CreateAgentOption {
+	return func(o *CreateAgentOptions) {
+		o.MaxIterations = maxIterations
+	}
+}
+
+// WithResponseFormat makes the agent produce a structured final answer
+func WithResponseFormat(format ResponseFormat) CreateAgentOption {
+	return func(o *CreateAgentOptions) {
+		o.ResponseFormat = &format
+	}
+}
+
+// WithToolChoice forces the tool choice of the model until it has called a tool for
+// the current user message. The choice is "auto", "required", "none", the name of
+// one of the agent's tools or an llms.ToolChoice.
+func WithToolChoice(choice any) CreateAgentOption {
+	return func(o *CreateAgentOptions) {
+		o.ToolChoice = choice
+	}
+}
+
+// WithPreModelHook runs a hook before each model call
+func WithPreModelHook(hook ModelHook) CreateAgentOption {
+	return func(o *CreateAgentOptions) {
+		o.PreModelHook = hook
+	}
+}
+
+// WithPostModelHook runs a hook after each model call, before the agent routes to
+// the tools or ends
+func WithPostModelHook(hook ModelHook) CreateAgentOption {
+	return func(o *CreateAgentOptions) {
+		o.PostModelHook = hook
+	}
+}
+
+// validate checks the options of the agent loop
+func (o *CreateAgentOptions) validate() error {
+	if o.MaxIterations < 0 {
+		return fmt.Errorf("max iterations must not be negative: %d", o.MaxIterations)
+	}
+	if o.ResponseFormat != nil && o.ResponseFormat.Schema == nil {
+		return fmt.Errorf("response format requires a schema")
+	}
+	return nil
+}
+
+// modelInput returns the messages sent to the model: the system message, if any,
+// followed by the conversation, both passed to the state modifier
+func (o *CreateAgentOptions) modelInput(messages []llms.MessageContent) []llms.MessageContent {
+	msgsToSend := messages
+	if o.SystemMessage != "" {
+		sysMsg := llms.TextParts(llms.ChatMessageTypeSystem, o.SystemMessage)
+		msgsToSend = append([]llms.MessageContent{sysMsg}, msgsToSend...)
+	}
+	if o.StateModifier != nil {
+		msgsToSend = o.StateModifier(msgsToSend)
+	}
+	return msgsToSend
+}
+
+// callModel calls the model of the agent node and returns its AI message
+func (o *CreateAgentOptions) callModel(ctx context.Context, model llms.Model, messages []llms.MessageContent, agentTools []tools.Tool) (llms.MessageContent, error) {
+	iterations := toolIterations(messages)
+
+	callOpts := []llms.CallOption{
+		llms.WithTools(toolDefinitions(agentTools)),
+	}
+	// Forcing the tool choice after a tool call would loop forever
+	if o.ToolChoice != nil && iterations == 0 {
+		callOpts = append(callOpts, llms.WithToolChoice(toolChoice(o.ToolChoice, agentTools)))
+	}
+
+	resp, err := graph.GenerateContent(ctx, model, o.modelInput(messages), callOpts...)
+	if err != nil {
+		return llms.MessageContent{}, err
+	}
+	if len(resp.Choices) == 0 {
+		return llms.MessageContent{}, fmt.Errorf("no response from model")
+	}
+
+	aiMsg := aiMessage(resp.Choices[0])
+	if o.MaxIterations > 0 && iterations >= o.MaxIterations && hasToolCalls(aiMsg) {
+		logProgress(ctx, o.Verbose, "Agent reached its maximum number of iterations", "max_iterations", o.MaxIterations)
+		aiMsg = llms.TextParts(llms.ChatMessageTypeAI, MaxIterationsMessage)
+	}
+	return aiMsg, nil
+}
+
+// aiMessage converts a choice of the model to an AI message
+func aiMessage(choice *llms.ContentChoice) llms.MessageContent {
+	aiMsg := llms.MessageContent{
+		Role: llms.ChatMessageTypeAI,
+	}
+	if choice.Content != "" {
+		aiMsg.Parts = append(aiMsg.Parts, llms.TextPart(choice.Content))
+	}
+	for _, tc := range choice.ToolCalls {
+		aiMsg.Parts = append(aiMsg.Parts, tc)
+	}
+	return aiMsg
+}
+
+// hasToolCalls reports whether a message requests tool calls
+func hasToolCalls(msg llms.MessageContent) bool {
+	for _, part := range msg.Parts {
+		if _, ok := part.(llms.ToolCall); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// toolIterations counts the AI messages with tool calls since the last user message
+func toolIterations(messages []llms.MessageContent) int {
+	iterations := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == llms.ChatMessageTypeHuman {
+			break
+		}
+		if messages[i].Role == llms.ChatMessageTypeAI && hasToolCalls(messages[i]) {
+			iterations++
+		}
+	}
+	return iterations
+}
+
+// toolChoice converts the name of one of the tools to an llms.ToolChoice and
+// returns other choices as they are
+func toolChoice(choice any, agentTools []tools.Tool) any {
+	name, ok := choice.(string)
+	if !ok {
+		return choice
+	}
+	for _, t := range agentTools {
+		if t.Name() == name {
+			return llms.ToolChoice{
+				Type:     "function",
+				Function: &llms.FunctionReference{Name: name},
+			}
+		}
+	}
+	return choice
+}
+
+// entryNode returns the node starting each round of the agent loop
+func (o *CreateAgentOptions) entryNode() string {
+	if o.PreModelHook != nil {
+		return PreModelHookNode
+	}
+	return "agent"
+}
+
+// addAgentLoop adds the model hooks and the structured response node to a workflow
+// with "agent" and "tools" nodes, and connects them. The caller sets the entry point.
+func (o *CreateAgentOptions) addAgentLoop(workflow *graph.StateGraph, model llms.Model) {
+	routeFrom := "agent"
+	if o.PreModelHook != nil {
+		workflow.AddNode(PreModelHookNode, "Hook run before the model", hookNode(o.PreModelHook))
+		workflow.AddEdge(PreModelHookNode, "agent")
+	}
+	if o.PostModelHook != nil {
+		workflow.AddNode(PostModelHookNode, "Hook run after the model", hookNode(o.PostModelHook))
+		workflow.AddEdge("agent", PostModelHookNode)
+		routeFrom = PostModelHookNode
+	}
+	if o.ResponseFormat != nil {
+		workflow.AddNode(StructuredResponseNode, "Structured final answer", o.structuredResponse(model))
+		workflow.AddEdge(StructuredResponseNode, graph.END)
+	}
+
+	workflow.AddConditionalEdge(routeFrom, o.route)
+
+	workflow.AddEdge("tools", o.entryNode())
+}
+
+// route picks the node following a model call: the tools it requested, the
+// structured response or the end of the run. Runs without messages to route on, e.g.
+// after a hook replaced them, end.
+func (o *CreateAgentOptions) route(ctx context.Context, state interface{}) string {
+	mState, ok := state.(map[string]interface{})
+	if !ok {
+		return graph.END
+	}
+	messages, ok := mState["messages"].([]llms.MessageContent)
+	if !ok || len(messages) == 0 {
+		return graph.END
+	}
+	if hasToolCalls(messages[len(messages)-1]) {
+		return "tools"
+	}
+	if o.ResponseFormat != nil {
+		return StructuredResponseNode
+	}
+	return graph.END
+}
+
+// hookNode wraps a model hook as a graph node
+func hookNode(hook ModelHook) func(ctx context.Context, state interface{}) (interface{}, error) {
+	return func(ctx context.Context, state interface{}) (interface{}, error) {
+		mState, ok := state.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid state type: %T", state)
+		}
+		update, err := hook(ctx, mState)
+		if err != nil {
+			return nil, err
+		}
+		if update == nil {
+			update = map[string]interface{}{}
+		}
+		return update, nil
+	}
+}
+
+// structuredResponse returns the node asking the model for the structured final
+// answer. Models ignoring the forced tool call may answer with the JSON instead.
+func (o *CreateAgentOptions) structuredResponse(model llms.Model) func(ctx context.Context, state interface{}) (interface{}, error) {
+	format := *o.ResponseFormat
+	if format.Name == "" {
+		format.Name = StructuredResponseNode
+	}
+	if format.StateKey == "" {
+		format.StateKey = DefaultStructuredResponseKey
+	}
+	definition := llms.Tool{
+		Type: "function",
+		Function: &llms.FunctionDefinition{
+			Name:        format.Name,
+			Description: format.Description,
+			Parameters:  format.Schema,
+		},
+	}
+
+	return func(ctx context.Context, state interface{}) (interface{}, error) {
+		mState, ok := state.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid state type: %T", state)
+		}
+		messages, ok := mState["messages"].([]llms.MessageContent)
+		if !ok {
+			return nil, fmt.Errorf("messages key not found or invalid type")
+		}
+
+		resp, err := graph.GenerateContent(ctx, model, o.modelInput(messages),
+			llms.WithTools([]llms.Tool{definition}),
+			llms.WithToolChoice(llms.ToolChoice{
+				Type:     "function",
+				Function: &llms.FunctionReference{Name: format.Name},
+			}),
+		)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Choices) == 0 {
+			return nil, fmt.Errorf("no structured response from model")
+		}
+
+		choice := resp.Choices[0]
+		arguments := choice.Content
+		for _, tc := range choice.ToolCalls {
+			if tc.FunctionCall != nil && tc.FunctionCall.Name == format.Name {
+				arguments = tc.FunctionCall.Arguments
+				break
+			}
+		}
+
+		if err := tool.ValidateArguments(format.Schema, arguments); err != nil {
+			return nil, fmt.Errorf("invalid structured response: %w", err)
+		}
+		var value any
+		if err := json.Unmarshal([]byte(arguments), &value); err != nil {
+			return nil, fmt.Errorf("invalid structured response: %w", err)
+		}
+
+		return map[string]interface{}{
+			format.StateKey: value,
+		}, nil
+	}
+}
@@ -2,7 +2,6 @@ package prebuilt
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 
 	"strings"
@@ -10,6 +9,7 @@ import (
 	"github.com/smallnest/goskills"
 	adapter "github.com/smallnest/langgraphgo/adapter/goskills"
 	"github.com/smallnest/langgraphgo/graph"
+	"github.com/smallnest/langgraphgo/log"
 	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/tools"
 )
@@ -21,6 +21,13 @@ type CreateAgentOptions struct {
 	SystemMessage string
 	StateModifier func(messages []llms.MessageContent) []llms.MessageContent
 	Checkpointer  graph.CheckpointStore
+	ToolOptions   []ToolExecutorOption
+
+	MaxIterations  int
+	ResponseFormat *ResponseFormat
+	ToolChoice     any
+	PreModelHook   ModelHook
+	PostModelHook  ModelHook
 }
 
 // CreateAgentOption is a function that configures CreateAgentOptions
@@ -48,6 +55,14 @@ func WithCheckpointer(checkpointer graph.CheckpointStore) CreateAgentOption {
 	}
 }
 
+// WithToolExecutorOptions configures the execution of the agent's tool calls, e.g.
+// WithMaxParallelTools or WithToolTimeout
+func WithToolExecutorOptions(opts ...ToolExecutorOption) CreateAgentOption {
+	return func(o *CreateAgentOptions) {
+		o.ToolOptions = append(o.ToolOptions, opts...)
+	}
+}
+
 // WithSkillDir sets the skill directory for the agent
 func WithSkillDir(skillDir string) CreateAgentOption {
 	return func(o *CreateAgentOptions) {
@@ -68,6 +83,9 @@ func CreateAgent(model llms.Model, inputTools []tools.Tool, opts ...CreateAgentO
 	for _, opt := range opts {
 		opt(options)
 	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
 
 	// Define the graph
 	workflow := graph.NewStateGraph()
@@ -113,50 +131,36 @@ func CreateAgent(model llms.Model, inputTools []tools.Tool, opts ...CreateAgentO
 			}
 
 			// --- STEP 1: SKILL DISCOVERY ---
-			if options.Verbose {
-				fmt.Printf("🔎 Discovering available skills in %s...\n", options.skillDir)
-			}
+			logProgress(ctx, options.Verbose, "discovering skills", "skill_dir", options.skillDir)
 			availableSkills, err := discoverSkills(options.skillDir)
 			if err != nil {
 				return nil, fmt.Errorf("failed to discover skills: %w", err)
 			}
 			if len(availableSkills) == 0 {
-				if options.Verbose {
-					fmt.Println("⚠️ No skills found.")
-				}
+				logProgress(ctx, options.Verbose, "no skills found", "skill_dir", options.skillDir)
 				return nil, nil
 			}
-			if options.Verbose {
-				fmt.Printf("✅ Found %d skills.\n\n", len(availableSkills))
-			}
+			logProgress(ctx, options.Verbose, "skills discovered", "count", len(availableSkills))
 
 			// --- STEP 2: SKILL SELECTION ---
-			if options.Verbose {
-				fmt.Println("🧠 Asking LLM to select the best skill...")
-			}
+			logProgress(ctx, options.Verbose, "selecting skill")
 			selectedSkillName, err := selectSkill(ctx, model, userPrompt, availableSkills)
 			if err != nil {
 				return nil, fmt.Errorf("failed during skill selection: %w", err)
 			}
 
 			if selectedSkillName == "" {
-				if options.Verbose {
-					fmt.Println("🤷 LLM did not select any skill.")
-				}
+				logProgress(ctx, options.Verbose, "no skill selected")
 				return nil, nil
 			}
 
 			selectedSkill, ok := availableSkills[selectedSkillName]
 			if !ok {
 				// LLM hallucinated a skill name
-				if options.Verbose {
-					fmt.Printf("⚠️ LLM selected a non-existent skill '%s'. Ignoring.\n", selectedSkillName)
-				}
+				log.WarnContext(ctx, "ignoring unknown skill selected by LLM", "skill", selectedSkillName)
 				return nil, nil
 			}
-			if options.Verbose {
-				fmt.Printf("✅ LLM selected skill: %s\n\n", selectedSkillName)
-			}
+			logProgress(ctx, options.Verbose, "skill selected", "skill", selectedSkillName)
 
 			// Convert skill to tools
 			skillTools, err := adapter.SkillsToTools(*selectedSkill)
@@ -182,8 +186,6 @@ func CreateAgent(model llms.Model, inputTools []tools.Tool, opts ...CreateAgentO
 			return nil, fmt.Errorf("messages key not found or invalid type")
 		}
 
-		// Convert tools to ToolInfo for the model
-		var toolDefs []llms.Tool
 		// Combine input tools with extra tools
 		var allTools []tools.Tool
 		allTools = append(allTools, inputTools...)
@@ -204,91 +206,11 @@ func CreateAgent(model llms.Model, inputTools []tools.Tool, opts ...CreateAgentO
 			}
 		}
 
-		// Convert tools to ToolInfo for the model
-		// var toolDefs []llms.Tool // Removed redeclaration
-		for _, t := range allTools {
-			toolDefs = append(toolDefs, llms.Tool{
-				Type: "function",
-				Function: &llms.FunctionDefinition{
-					Name:        t.Name(),
-					Description: t.Description(),
-					Parameters: map[string]interface{}{
-						"type": "object",
-						"properties": map[string]interface{}{
-							"input": map[string]interface{}{
-								"type":        "string",
-								"description": "The input query for the tool",
-							},
-						},
-						"required":             []string{"input"},
-						"additionalProperties": false,
-					},
-				},
-			})
-		}
-
-		// We need to pass tools to the model
-		callOpts := []llms.CallOption{
-			llms.WithTools(toolDefs),
-		}
-
-		// Apply StateModifier if provided
-		msgsToSend := messages
-
-		// Prepend system message if provided (and not handled by StateModifier)
-		// If StateModifier is provided, it's responsible for the whole message list structure,
-		// but usually SystemMessage is separate.
-		// LangChain logic: SystemMessage is prepended. StateModifier can modify everything.
-		// Let's prepend SystemMessage first, then apply StateModifier?
-		// Or apply StateModifier to the raw history, then prepend SystemMessage?
-		// LangChain docs say: "This is useful for doing things like... removing the system message"
-		// So StateModifier should probably run AFTER SystemMessage is added?
-		// But if SystemMessage is just a string, we construct it here.
-		// Let's construct SystemMessage first.
-
-		if options.SystemMessage != "" {
-			sysMsg := llms.TextParts(llms.ChatMessageTypeSystem, options.SystemMessage)
-			// Check if the first message is already a system message?
-			// For simplicity, just prepend.
-			msgsToSend = append([]llms.MessageContent{sysMsg}, msgsToSend...)
-		}
-
-		// Now apply StateModifier if it exists
-		// Wait, if StateModifier is used to REMOVE system message, it must run AFTER.
-		// But if it's used to filter history, it might run BEFORE.
-		// LangChain `create_react_agent` source:
-		// 1. `_modify_state` runs on input state.
-		// 2. `system_message` is added.
-		// Actually, `create_agent` in LangChain 0.2+ might be different.
-		// Let's stick to: SystemMessage is added to the front. StateModifier sees the result.
-		if options.StateModifier != nil {
-			msgsToSend = options.StateModifier(msgsToSend)
-		}
-
-		resp, err := model.GenerateContent(ctx, msgsToSend, callOpts...)
+		aiMsg, err := options.callModel(ctx, model, messages, allTools)
 		if err != nil {
 			return nil, err
 		}
 
-		choice := resp.Choices[0]
-
-		// Create AIMessage
-		aiMsg := llms.MessageContent{
-			Role: llms.ChatMessageTypeAI,
-		}
-
-		if choice.Content != "" {
-			aiMsg.Parts = append(aiMsg.Parts, llms.TextPart(choice.Content))
-		}
-
-		// Handle tool calls
-		if len(choice.ToolCalls) > 0 {
-			for _, tc := range choice.ToolCalls {
-				// ToolCall implements ContentPart
-				aiMsg.Parts = append(aiMsg.Parts, tc)
-			}
-		}
-
 		return map[string]interface{}{
 			"messages": []llms.MessageContent{aiMsg},
 		}, nil
@@ -308,64 +230,23 @@ func CreateAgent(model llms.Model, inputTools []tools.Tool, opts ...CreateAgentO
 			return nil, fmt.Errorf("last message is not an AI message")
 		}
 
-		var toolMessages []llms.MessageContent
-
-		for _, part := range lastMsg.Parts {
-			if tc, ok := part.(llms.ToolCall); ok {
-				// Parse arguments to get input
-				var args map[string]interface{}
-				if err := json.Unmarshal([]byte(tc.FunctionCall.Arguments), &args); err != nil {
-					// If unmarshal fails, try to use the raw string if it's not JSON object
-				}
-
-				inputVal := ""
-				if val, ok := args["input"].(string); ok {
-					inputVal = val
-				} else {
-					inputVal = tc.FunctionCall.Arguments
-				}
-
-				// Combine input tools with extra tools for execution
-				var allTools []tools.Tool
-				allTools = append(allTools, inputTools...)
-				if extra, ok := mState["extra_tools"].([]tools.Tool); ok {
-					allTools = append(allTools, extra...)
-				} else if extra, ok := mState["extra_tools"].([]interface{}); ok {
-					for _, t := range extra {
-						if tool, ok := t.(tools.Tool); ok {
-							allTools = append(allTools, tool)
-						}
-					}
-				}
-
-				// Create a temporary executor for this run
-				// Optimization: We could cache this if tools don't change often, but here they might.
-				currentToolExecutor := NewToolExecutor(allTools)
-
-				// Execute tool
-				res, err := currentToolExecutor.Execute(ctx, ToolInvocation{
-					Tool:      tc.FunctionCall.Name,
-					ToolInput: inputVal,
-				})
-				if err != nil {
-					res = fmt.Sprintf("Error: %v", err)
-				}
-
-				// Create ToolMessage
-				toolMsg := llms.MessageContent{
-					Role: llms.ChatMessageTypeTool,
-					Parts: []llms.ContentPart{
-						llms.ToolCallResponse{
-							ToolCallID: tc.ID,
-							Name:       tc.FunctionCall.Name,
-							Content:    res,
-						},
-					},
+		// Combine input tools with extra tools for execution
+		var allTools []tools.Tool
+		allTools = append(allTools, inputTools...)
+		if extra, ok := mState["extra_tools"].([]tools.Tool); ok {
+			allTools = append(allTools, extra...)
+		} else if extra, ok := mState["extra_tools"].([]interface{}); ok {
+			for _, t := range extra {
+				if tool, ok := t.(tools.Tool); ok {
+					allTools = append(allTools, tool)
 				}
-				toolMessages = append(toolMessages, toolMsg)
 			}
 		}
 
+		// Execute the tool calls concurrently, reporting each result or error to the model
+		toolExecutor := NewToolExecutor(allTools, options.ToolOptions...)
+		toolMessages := toolExecutor.executeToolCalls(ctx, lastMsg)
+
 		return map[string]interface{}{
 			"messages": toolMessages,
 		}, nil
@@ -374,31 +255,12 @@ func CreateAgent(model llms.Model, inputTools []tools.Tool, opts ...CreateAgentO
 	// Define edges
 	if options.skillDir != "" {
 		workflow.SetEntryPoint("skill")
-		workflow.AddEdge("skill", "agent")
+		workflow.AddEdge("skill", options.entryNode())
 	} else {
-		workflow.SetEntryPoint("agent")
+		workflow.SetEntryPoint(options.entryNode())
 	}
 
-	workflow.AddConditionalEdge("agent", func(ctx context.Context, state interface{}) string {
-		mState := state.(map[string]interface{})
-		messages := mState["messages"].([]llms.MessageContent)
-		lastMsg := messages[len(messages)-1]
-
-		hasToolCalls := false
-		for _, part := range lastMsg.Parts {
-			if _, ok := part.(llms.ToolCall); ok {
-				hasToolCalls = true
-				break
-			}
-		}
-
-		if hasToolCalls {
-			return "tools"
-		}
-		return graph.END
-	})
-
-	workflow.AddEdge("tools", "agent")
+	options.addAgentLoop(workflow, model)
 
 	return workflow.Compile()
 }
@@ -437,7 +299,7 @@ Instructions:
 
 Output:`, skillDescriptions, userPrompt)
 
-	resp, err := model.GenerateContent(ctx, []llms.MessageContent{
+	resp, err := graph.GenerateContent(ctx, model, []llms.MessageContent{
 		llms.TextParts(llms.ChatMessageTypeHuman, prompt),
 	})
 	if err != nil {
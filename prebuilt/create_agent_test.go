@@ -179,3 +179,27 @@ func TestCreateAgent_StateModifier(t *testing.T) {
 	assert.Equal(t, llms.ChatMessageTypeHuman, firstCallMessages[0].Role)
 	assert.Equal(t, "Modified: Hello", firstCallMessages[0].Parts[0].(llms.TextContent).Text)
 }
+
+func TestCreateAgent_ResponseFormatAndMaxIterations(t *testing.T) {
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"answer": map[string]any{"type": "string"}},
+		"required":   []string{"answer"},
+	}
+	model := &scriptedLLM{choices: []llms.ContentChoice{
+		toolCallChoice("call-1", "test-tool", `{"input": "x"}`),
+		toolCallChoice("call-2", "test-tool", `{"input": "y"}`),
+		toolCallChoice("call-3", StructuredResponseNode, `{"answer": "gave up"}`),
+	}}
+	agent, err := CreateAgent(model, []tools.Tool{&MockTool{name: "test-tool"}},
+		WithMaxIterations(1), WithResponseFormat(ResponseFormat{Schema: schema}))
+	assert.NoError(t, err)
+
+	res, err := agent.Invoke(context.Background(), humanState("Answer"))
+	assert.NoError(t, err)
+
+	mState := res.(map[string]interface{})
+	messages := mState["messages"].([]llms.MessageContent)
+	assert.Equal(t, llms.TextParts(llms.ChatMessageTypeAI, MaxIterationsMessage), messages[len(messages)-1])
+	assert.Equal(t, map[string]any{"answer": "gave up"}, mState[DefaultStructuredResponseKey])
+}
@@ -0,0 +1,18 @@
+package prebuilt
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/smallnest/langgraphgo/log"
+)
+
+// logProgress logs the progress of an agent. Records are emitted at info level when
+// the agent is verbose and at debug level otherwise.
+func logProgress(ctx context.Context, verbose bool, msg string, args ...any) {
+	level := slog.LevelDebug
+	if verbose {
+		level = slog.LevelInfo
+	}
+	log.Log(ctx, level, msg, args...)
+}
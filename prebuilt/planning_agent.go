@@ -12,24 +12,15 @@ import (
 	"github.com/tmc/langchaingo/tools"
 )
 
-// WorkflowPlan represents the parsed workflow plan from LLM
-type WorkflowPlan struct {
-	Nodes []WorkflowNode `json:"nodes"`
-	Edges []WorkflowEdge `json:"edges"`
-}
+// WorkflowPlan represents the parsed workflow plan from LLM.
+// It is a graph.GraphDefinition, so a plan can also be built with a graph.Registry.
+type WorkflowPlan = graph.GraphDefinition
 
 // WorkflowNode represents a node in the workflow plan
-type WorkflowNode struct {
-	Name string `json:"name"`
-	Type string `json:"type"` // "start", "process", "end", "conditional"
-}
+type WorkflowNode = graph.NodeDefinition
 
 // WorkflowEdge represents an edge in the workflow plan
-type WorkflowEdge struct {
-	From      string `json:"from"`
-	To        string `json:"to"`
-	Condition string `json:"condition,omitempty"` // For conditional edges
-}
+type WorkflowEdge = graph.EdgeDefinition
 
 // CreatePlanningAgent creates an agent that first plans the workflow using LLM,
 // then executes according to the generated plan
@@ -79,20 +70,16 @@ func CreatePlanningAgent(model llms.Model, nodes []*graph.Node, inputTools []too
 		}
 		planningMessages = append(planningMessages, messages...)
 
-		if options.Verbose {
-			fmt.Println("🤔 Planning workflow...")
-		}
+		logProgress(ctx, options.Verbose, "planning workflow")
 
 		// Call LLM to generate the plan
-		resp, err := model.GenerateContent(ctx, planningMessages)
+		resp, err := graph.GenerateContent(ctx, model, planningMessages)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate plan: %w", err)
 		}
 
 		planText := resp.Choices[0].Content
-		if options.Verbose {
-			fmt.Printf("📋 Generated plan:\n%s\n\n", planText)
-		}
+		logProgress(ctx, options.Verbose, "workflow plan generated", "plan", planText)
 
 		// Parse the workflow plan
 		workflowPlan, err := parseWorkflowPlan(planText)
@@ -124,9 +111,7 @@ func CreatePlanningAgent(model llms.Model, nodes []*graph.Node, inputTools []too
 			return nil, fmt.Errorf("workflow_plan not found in state")
 		}
 
-		if options.Verbose {
-			fmt.Println("🚀 Executing planned workflow...")
-		}
+		logProgress(ctx, options.Verbose, "executing planned workflow")
 
 		// Build the dynamic workflow
 		dynamicWorkflow := graph.NewStateGraph()
@@ -148,9 +133,7 @@ func CreatePlanningAgent(model llms.Model, nodes []*graph.Node, inputTools []too
 			// Add the node with its original function
 			dynamicWorkflow.AddNode(actualNode.Name, actualNode.Description, actualNode.Function)
 
-			if options.Verbose {
-				fmt.Printf("  ✓ Added node: %s\n", actualNode.Name)
-			}
+			logProgress(ctx, options.Verbose, "added planned node", "planned_node", actualNode.Name)
 		}
 
 		// Add edges from the plan
@@ -180,17 +163,13 @@ func CreatePlanningAgent(model llms.Model, nodes []*graph.Node, inputTools []too
 				dynamicWorkflow.AddEdge(edge.From, edge.To)
 			}
 
-			if options.Verbose {
-				fmt.Printf("  ✓ Added edge: %s -> %s\n", edge.From, edge.To)
-			}
+			logProgress(ctx, options.Verbose, "added planned edge", "from", edge.From, "to", edge.To)
 		}
 
 		// Add edges to END for terminal nodes
 		for nodeName := range endNodes {
 			dynamicWorkflow.AddEdge(nodeName, graph.END)
-			if options.Verbose {
-				fmt.Printf("  ✓ Added edge: %s -> END\n", nodeName)
-			}
+			logProgress(ctx, options.Verbose, "added planned edge", "from", nodeName, "to", graph.END)
 		}
 
 		if entryPoint == "" {
@@ -211,9 +190,7 @@ func CreatePlanningAgent(model llms.Model, nodes []*graph.Node, inputTools []too
 			return nil, fmt.Errorf("failed to execute dynamic workflow: %w", err)
 		}
 
-		if options.Verbose {
-			fmt.Println("✅ Workflow execution completed")
-		}
+		logProgress(ctx, options.Verbose, "planned workflow completed")
 
 		return result, nil
 	})
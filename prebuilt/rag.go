@@ -342,7 +342,7 @@ func (p *RAGPipeline) generateNode(ctx context.Context, state interface{}) (inte
 	}
 
 	// Generate answer
-	response, err := p.config.LLM.GenerateContent(ctx, messages)
+	response, err := graph.GenerateContent(ctx, p.config.LLM, messages)
 	if err != nil {
 		return nil, fmt.Errorf("generation failed: %w", err)
 	}
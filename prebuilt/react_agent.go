@@ -2,7 +2,6 @@ package prebuilt
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 
 	"github.com/smallnest/langgraphgo/graph"
@@ -10,10 +9,20 @@ import (
 	"github.com/tmc/langchaingo/tools"
 )
 
-// CreateReactAgent creates a new ReAct agent graph
-func CreateReactAgent(model llms.Model, inputTools []tools.Tool) (*graph.StateRunnable, error) {
+// CreateReactAgent creates a new ReAct agent graph. It accepts the options of
+// CreateAgent, such as WithSystemMessage, WithMaxIterations or WithResponseFormat,
+// except WithSkillDir which is ignored.
+func CreateReactAgent(model llms.Model, inputTools []tools.Tool, opts ...CreateAgentOption) (*graph.StateRunnable, error) {
+	options := &CreateAgentOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+
 	// Define the tool executor
-	toolExecutor := NewToolExecutor(inputTools)
+	toolExecutor := NewToolExecutor(inputTools, options.ToolOptions...)
 
 	// Define the graph
 	workflow := graph.NewStateGraph()
@@ -36,58 +45,11 @@ func CreateReactAgent(model llms.Model, inputTools []tools.Tool) (*graph.StateRu
 			return nil, fmt.Errorf("messages key not found or invalid type")
 		}
 
-		// Convert tools to ToolInfo for the model
-		var toolDefs []llms.Tool
-		for _, t := range inputTools {
-			toolDefs = append(toolDefs, llms.Tool{
-				Type: "function",
-				Function: &llms.FunctionDefinition{
-					Name:        t.Name(),
-					Description: t.Description(),
-					Parameters: map[string]interface{}{
-						"type": "object",
-						"properties": map[string]interface{}{
-							"input": map[string]interface{}{
-								"type":        "string",
-								"description": "The input query for the tool",
-							},
-						},
-						"required":             []string{"input"},
-						"additionalProperties": false,
-					},
-				},
-			})
-		}
-
-		// We need to pass tools to the model
-		opts := []llms.CallOption{
-			llms.WithTools(toolDefs),
-		}
-
-		resp, err := model.GenerateContent(ctx, messages, opts...)
+		aiMsg, err := options.callModel(ctx, model, messages, inputTools)
 		if err != nil {
 			return nil, err
 		}
 
-		choice := resp.Choices[0]
-
-		// Create AIMessage
-		aiMsg := llms.MessageContent{
-			Role: llms.ChatMessageTypeAI,
-		}
-
-		if choice.Content != "" {
-			aiMsg.Parts = append(aiMsg.Parts, llms.TextPart(choice.Content))
-		}
-
-		// Handle tool calls
-		if len(choice.ToolCalls) > 0 {
-			for _, tc := range choice.ToolCalls {
-				// ToolCall implements ContentPart
-				aiMsg.Parts = append(aiMsg.Parts, tc)
-			}
-		}
-
 		return map[string]interface{}{
 			"messages": []llms.MessageContent{aiMsg},
 		}, nil
@@ -107,46 +69,8 @@ func CreateReactAgent(model llms.Model, inputTools []tools.Tool) (*graph.StateRu
 			return nil, fmt.Errorf("last message is not an AI message")
 		}
 
-		var toolMessages []llms.MessageContent
-
-		for _, part := range lastMsg.Parts {
-			if tc, ok := part.(llms.ToolCall); ok {
-				// Parse arguments to get input
-				var args map[string]interface{}
-				if err := json.Unmarshal([]byte(tc.FunctionCall.Arguments), &args); err != nil {
-					// If unmarshal fails, try to use the raw string if it's not JSON object
-				}
-
-				inputVal := ""
-				if val, ok := args["input"].(string); ok {
-					inputVal = val
-				} else {
-					inputVal = tc.FunctionCall.Arguments
-				}
-
-				// Execute tool
-				res, err := toolExecutor.Execute(ctx, ToolInvocation{
-					Tool:      tc.FunctionCall.Name,
-					ToolInput: inputVal,
-				})
-				if err != nil {
-					res = fmt.Sprintf("Error: %v", err)
-				}
-
-				// Create ToolMessage
-				toolMsg := llms.MessageContent{
-					Role: llms.ChatMessageTypeTool,
-					Parts: []llms.ContentPart{
-						llms.ToolCallResponse{
-							ToolCallID: tc.ID,
-							Name:       tc.FunctionCall.Name,
-							Content:    res,
-						},
-					},
-				}
-				toolMessages = append(toolMessages, toolMsg)
-			}
-		}
+		// Execute the tool calls concurrently, reporting each result or error to the model
+		toolMessages := toolExecutor.executeToolCalls(ctx, lastMsg)
 
 		return map[string]interface{}{
 			"messages": toolMessages,
@@ -154,28 +78,8 @@ func CreateReactAgent(model llms.Model, inputTools []tools.Tool) (*graph.StateRu
 	})
 
 	// Define edges
-	workflow.SetEntryPoint("agent")
-
-	workflow.AddConditionalEdge("agent", func(ctx context.Context, state interface{}) string {
-		mState := state.(map[string]interface{})
-		messages := mState["messages"].([]llms.MessageContent)
-		lastMsg := messages[len(messages)-1]
-
-		hasToolCalls := false
-		for _, part := range lastMsg.Parts {
-			if _, ok := part.(llms.ToolCall); ok {
-				hasToolCalls = true
-				break
-			}
-		}
-
-		if hasToolCalls {
-			return "tools"
-		}
-		return graph.END
-	})
-
-	workflow.AddEdge("tools", "agent")
+	workflow.SetEntryPoint(options.entryNode())
+	options.addAgentLoop(workflow, model)
 
 	return workflow.Compile()
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/smallnest/langgraphgo/graph"
 	"github.com/stretchr/testify/assert"
 	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/tools"
@@ -112,3 +113,234 @@ func TestCreateReactAgent(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, "Final Answer", textPart.Text)
 }
+
+// toolsCaptureLLM records the tools advertised to it and calls the weather tool once
+type toolsCaptureLLM struct {
+	tools []llms.Tool
+	calls int
+}
+
+func (m *toolsCaptureLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	m.tools = opts.Tools
+	m.calls++
+	if m.calls > 1 {
+		return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "done"}}}, nil
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+		ToolCalls: []llms.ToolCall{{
+			ID:           "call-1",
+			Type:         "function",
+			FunctionCall: &llms.FunctionCall{Name: "weather", Arguments: `{"city": "Paris"}`},
+		}},
+	}}}, nil
+}
+
+func (m *toolsCaptureLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return "", nil
+}
+
+func TestCreateReactAgent_SchemaTool(t *testing.T) {
+	weather := &weatherTool{}
+	mockTool := &MockTool{name: "test-tool"}
+	model := &toolsCaptureLLM{}
+	agent, err := CreateReactAgent(model, []tools.Tool{weather, mockTool})
+	assert.NoError(t, err)
+
+	_, err = agent.Invoke(context.Background(), map[string]interface{}{
+		"messages": []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "Weather in Paris?")},
+	})
+	assert.NoError(t, err)
+
+	// The schema of the SchemaTool is advertised, other tools take an input string
+	assert.Len(t, model.tools, 2)
+	assert.Equal(t, weather.Parameters(), model.tools[0].Function.Parameters)
+	assert.Contains(t, model.tools[1].Function.Parameters.(map[string]any)["properties"], "input")
+
+	// The tool gets the raw arguments of the call
+	assert.Equal(t, []string{`{"city": "Paris"}`}, weather.inputs)
+}
+
+// scriptedLLM returns its choices in order, repeating the last one, and records
+// the messages and options of each call
+type scriptedLLM struct {
+	choices  []llms.ContentChoice
+	messages [][]llms.MessageContent
+	options  []llms.CallOptions
+}
+
+func (m *scriptedLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	m.messages = append(m.messages, messages)
+	m.options = append(m.options, opts)
+	choice := m.choices[min(len(m.messages), len(m.choices))-1]
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{&choice}}, nil
+}
+
+func (m *scriptedLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return "", nil
+}
+
+func toolCallChoice(id, name, arguments string) llms.ContentChoice {
+	return llms.ContentChoice{ToolCalls: []llms.ToolCall{{
+		ID:           id,
+		Type:         "function",
+		FunctionCall: &llms.FunctionCall{Name: name, Arguments: arguments},
+	}}}
+}
+
+func humanState(text string) map[string]interface{} {
+	return map[string]interface{}{
+		"messages": []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, text)},
+	}
+}
+
+func TestCreateReactAgent_MaxIterations(t *testing.T) {
+	// The model never stops calling tools
+	model := &scriptedLLM{choices: []llms.ContentChoice{toolCallChoice("call-1", "test-tool", `{"input": "again"}`)}}
+	agent, err := CreateReactAgent(model, []tools.Tool{&MockTool{name: "test-tool"}}, WithMaxIterations(2))
+	assert.NoError(t, err)
+
+	res, err := agent.Invoke(context.Background(), humanState("Loop"))
+	assert.NoError(t, err)
+
+	messages := res.(map[string]interface{})["messages"].([]llms.MessageContent)
+	assert.Len(t, model.messages, 3)
+	assert.Len(t, messages, 6)
+	assert.Equal(t, llms.TextParts(llms.ChatMessageTypeAI, MaxIterationsMessage), messages[len(messages)-1])
+
+	// The limit applies per user message
+	assert.Equal(t, 0, toolIterations(append(messages, llms.TextParts(llms.ChatMessageTypeHuman, "Again"))))
+
+	_, err = CreateReactAgent(model, nil, WithMaxIterations(-1))
+	assert.Error(t, err)
+}
+
+func TestCreateReactAgent_ResponseFormat(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"label":      map[string]any{"type": "string", "enum": []string{"bug", "feature"}},
+			"confidence": map[string]any{"type": "number"},
+		},
+		"required": []string{"label"},
+	}
+	format := ResponseFormat{Name: "classify", Schema: schema, StateKey: "classification"}
+
+	model := &scriptedLLM{choices: []llms.ContentChoice{
+		{Content: "This is a bug report."},
+		toolCallChoice("call-1", "classify", `{"label": "bug", "confidence": 0.9}`),
+	}}
+	agent, err := CreateReactAgent(model, []tools.Tool{&MockTool{name: "test-tool"}}, WithResponseFormat(format))
+	assert.NoError(t, err)
+
+	res, err := agent.Invoke(context.Background(), humanState("The app crashes on start"))
+	assert.NoError(t, err)
+
+	mState := res.(map[string]interface{})
+	assert.Equal(t, map[string]any{"label": "bug", "confidence": 0.9}, mState["classification"])
+	assert.Len(t, mState["messages"], 2)
+
+	// The response is requested with a forced call of the response tool
+	assert.Len(t, model.options, 2)
+	assert.Equal(t, "classify", model.options[1].Tools[0].Function.Name)
+	assert.Equal(t, llms.ToolChoice{Type: "function", Function: &llms.FunctionReference{Name: "classify"}}, model.options[1].ToolChoice)
+
+	// Responses are validated against the schema
+	model = &scriptedLLM{choices: []llms.ContentChoice{
+		{Content: "Not sure."},
+		{Content: `{"label": "question"}`},
+	}}
+	agent, err = CreateReactAgent(model, nil, WithResponseFormat(format))
+	assert.NoError(t, err)
+	_, err = agent.Invoke(context.Background(), humanState("How do I log in?"))
+	assert.ErrorContains(t, err, "invalid structured response: arguments.label must be one of")
+
+	_, err = CreateReactAgent(model, nil, WithResponseFormat(ResponseFormat{Name: "classify"}))
+	assert.Error(t, err)
+}
+
+func TestCreateReactAgent_ToolChoice(t *testing.T) {
+	model := &scriptedLLM{choices: []llms.ContentChoice{
+		toolCallChoice("call-1", "test-tool", `{"input": "x"}`),
+		{Content: "done"},
+	}}
+	agent, err := CreateReactAgent(model, []tools.Tool{&MockTool{name: "test-tool"}}, WithToolChoice("test-tool"))
+	assert.NoError(t, err)
+
+	_, err = agent.Invoke(context.Background(), humanState("Use the tool"))
+	assert.NoError(t, err)
+
+	// The choice is only forced until the first tool call
+	assert.Len(t, model.options, 2)
+	assert.Equal(t, llms.ToolChoice{Type: "function", Function: &llms.FunctionReference{Name: "test-tool"}}, model.options[0].ToolChoice)
+	assert.Nil(t, model.options[1].ToolChoice)
+
+	assert.Equal(t, "required", toolChoice("required", []tools.Tool{&MockTool{name: "test-tool"}}))
+}
+
+func TestCreateReactAgent_ModelHooks(t *testing.T) {
+	model := &scriptedLLM{choices: []llms.ContentChoice{
+		toolCallChoice("call-1", "test-tool", `{"input": "x"}`),
+		{Content: "done"},
+	}}
+
+	var preCalls, postCalls int
+	pre := func(ctx context.Context, state map[string]interface{}) (map[string]interface{}, error) {
+		preCalls++
+		return map[string]interface{}{"model_calls": preCalls}, nil
+	}
+	post := func(ctx context.Context, state map[string]interface{}) (map[string]interface{}, error) {
+		postCalls++
+		messages := state["messages"].([]llms.MessageContent)
+		if hasToolCalls(messages[len(messages)-1]) {
+			return nil, nil
+		}
+		return map[string]interface{}{
+			"messages": []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeAI, "checked")},
+		}, nil
+	}
+
+	agent, err := CreateReactAgent(model, []tools.Tool{&MockTool{name: "test-tool"}},
+		WithSystemMessage("Be brief"), WithPreModelHook(pre), WithPostModelHook(post))
+	assert.NoError(t, err)
+
+	res, err := agent.Invoke(context.Background(), humanState("Use the tool"))
+	assert.NoError(t, err)
+
+	mState := res.(map[string]interface{})
+	assert.Equal(t, 2, preCalls)
+	assert.Equal(t, 2, postCalls)
+	assert.Equal(t, 2, mState["model_calls"])
+
+	messages := mState["messages"].([]llms.MessageContent)
+	assert.Equal(t, llms.TextParts(llms.ChatMessageTypeAI, "checked"), messages[len(messages)-1])
+
+	// The system message is sent to the model but not stored in the state
+	assert.Equal(t, llms.ChatMessageTypeSystem, model.messages[0][0].Role)
+	assert.Equal(t, llms.ChatMessageTypeHuman, messages[0].Role)
+}
+
+func TestCreateReactAgent_InvalidModelOutput(t *testing.T) {
+	// A model returning no choices fails the run instead of panicking
+	agent, err := CreateReactAgent(&MockLLM{responses: []llms.ContentResponse{{}}}, nil)
+	assert.NoError(t, err)
+	_, err = agent.Invoke(context.Background(), humanState("Hi"))
+	assert.ErrorContains(t, err, "no response from model")
+
+	// Invalid states after the model call end the run instead of panicking
+	options := &CreateAgentOptions{}
+	ctx := context.Background()
+	assert.Equal(t, graph.END, options.route(ctx, "not a map"))
+	assert.Equal(t, graph.END, options.route(ctx, map[string]interface{}{"messages": "redacted"}))
+	assert.Equal(t, graph.END, options.route(ctx, map[string]interface{}{"messages": []llms.MessageContent{}}))
+	assert.Equal(t, "tools", options.route(ctx, map[string]interface{}{
+		"messages": []llms.MessageContent{{Role: llms.ChatMessageTypeAI, Parts: []llms.ContentPart{llms.ToolCall{ID: "call-1"}}}},
+	}))
+}
@@ -89,12 +89,12 @@ func CreateReflectionAgent(config ReflectionAgentConfig) (*graph.StateRunnable,
 
 	// Add conditional edge from generate
 	workflow.AddConditionalEdge("generate", func(ctx context.Context, state interface{}) string {
-		return shouldContinueAfterGenerate(state, config.MaxIterations, config.Verbose)
+		return shouldContinueAfterGenerate(ctx, state, config.MaxIterations, config.Verbose)
 	})
 
 	// Add conditional edge from reflect
 	workflow.AddConditionalEdge("reflect", func(ctx context.Context, state interface{}) string {
-		return shouldContinueAfterReflect(state, config.Verbose)
+		return shouldContinueAfterReflect(ctx, state, config.Verbose)
 	})
 
 	return workflow.Compile()
@@ -121,9 +121,7 @@ func generateNode(ctx context.Context, state interface{}, model llms.Model, syst
 
 	if iteration == 0 {
 		// First generation
-		if verbose {
-			fmt.Println("🎨 Generating initial response...")
-		}
+		logProgress(ctx, verbose, "generating initial response")
 
 		promptMessages = []llms.MessageContent{
 			{
@@ -141,9 +139,7 @@ func generateNode(ctx context.Context, state interface{}, model llms.Model, syst
 
 		previousDraft, _ := mState["draft"].(string)
 
-		if verbose {
-			fmt.Printf("🔄 Revising response (iteration %d)...\n", iteration)
-		}
+		logProgress(ctx, verbose, "revising response", "iteration", iteration)
 
 		// Construct revision prompt
 		revisionPrompt := fmt.Sprintf(`You are revising your previous response based on reflection.
@@ -173,16 +169,14 @@ Generate an improved response that addresses the issues raised in the reflection
 	}
 
 	// Generate response
-	resp, err := model.GenerateContent(ctx, promptMessages)
+	resp, err := graph.GenerateContent(ctx, model, promptMessages)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate response: %w", err)
 	}
 
 	draft := resp.Choices[0].Content
 
-	if verbose {
-		fmt.Printf("📝 Draft generated (%d chars)\n", len(draft))
-	}
+	logProgress(ctx, verbose, "draft generated", "chars", len(draft))
 
 	// Create AI message
 	aiMsg := llms.MessageContent{
@@ -213,9 +207,7 @@ func reflectNode(ctx context.Context, state interface{}, model llms.Model, refle
 
 	originalRequest := getOriginalRequest(messages)
 
-	if verbose {
-		fmt.Println("🤔 Reflecting on the response...")
-	}
+	logProgress(ctx, verbose, "reflecting on response")
 
 	// Build reflection prompt
 	reflectionMessages := []llms.MessageContent{
@@ -238,16 +230,14 @@ Provide a critical reflection on this response.`, originalRequest, draft)),
 	}
 
 	// Generate reflection
-	resp, err := model.GenerateContent(ctx, reflectionMessages)
+	resp, err := graph.GenerateContent(ctx, model, reflectionMessages)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate reflection: %w", err)
 	}
 
 	reflection := resp.Choices[0].Content
 
-	if verbose {
-		fmt.Printf("💭 Reflection:\n%s\n\n", reflection)
-	}
+	logProgress(ctx, verbose, "reflection generated", "reflection", reflection)
 
 	// Determine if response is satisfactory
 	isSatisfactory := isResponseSatisfactory(reflection)
@@ -259,7 +249,7 @@ Provide a critical reflection on this response.`, originalRequest, draft)),
 }
 
 // shouldContinueAfterGenerate decides whether to reflect or end
-func shouldContinueAfterGenerate(state interface{}, maxIterations int, verbose bool) string {
+func shouldContinueAfterGenerate(ctx context.Context, state interface{}, maxIterations int, verbose bool) string {
 	mState := state.(map[string]interface{})
 
 	iteration, _ := mState["iteration"].(int)
@@ -271,18 +261,14 @@ func shouldContinueAfterGenerate(state interface{}, maxIterations int, verbose b
 
 	// If we've reached max iterations, stop
 	if iteration >= maxIterations {
-		if verbose {
-			fmt.Println("✅ Max iterations reached, finalizing response")
-		}
+		logProgress(ctx, verbose, "max iterations reached, finalizing response", "iteration", iteration)
 		return graph.END
 	}
 
 	// Check if previous reflection was satisfactory
 	isSatisfactory, _ := mState["is_satisfactory"].(bool)
 	if isSatisfactory {
-		if verbose {
-			fmt.Println("✅ Response is satisfactory, finalizing")
-		}
+		logProgress(ctx, verbose, "response is satisfactory, finalizing", "iteration", iteration)
 		return graph.END
 	}
 
@@ -291,15 +277,13 @@ func shouldContinueAfterGenerate(state interface{}, maxIterations int, verbose b
 }
 
 // shouldContinueAfterReflect decides whether to revise or accept
-func shouldContinueAfterReflect(state interface{}, verbose bool) string {
+func shouldContinueAfterReflect(ctx context.Context, state interface{}, verbose bool) string {
 	mState := state.(map[string]interface{})
 
 	isSatisfactory, _ := mState["is_satisfactory"].(bool)
 
 	if isSatisfactory {
-		if verbose {
-			fmt.Println("✅ Reflection indicates response is satisfactory")
-		}
+		logProgress(ctx, verbose, "reflection indicates response is satisfactory")
 		return graph.END
 	}
 
@@ -71,7 +71,7 @@ func CreateSupervisor(model llms.Model, members map[string]*graph.StateRunnable)
 		inputMessages = append(inputMessages, messages...)
 
 		// Call model
-		resp, err := model.GenerateContent(ctx, inputMessages,
+		resp, err := graph.GenerateContent(ctx, model, inputMessages,
 			llms.WithTools([]llms.Tool{routeTool}),
 			llms.WithToolChoice("auto"), // Let model decide, but prompt strongly encourages it
 		)
@@ -2,8 +2,15 @@ package prebuilt
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
+	"github.com/smallnest/langgraphgo/graph"
+	"github.com/smallnest/langgraphgo/tool"
+	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/tools"
 )
 
@@ -13,44 +20,232 @@ type ToolInvocation struct {
 	ToolInput string `json:"tool_input"`
 }
 
+// ToolResult is the output or the error of a tool invocation
+type ToolResult struct {
+	Output string
+	Err    error
+}
+
+// DefaultMaxParallelTools is the default number of tool calls a ToolExecutor runs at once
+const DefaultMaxParallelTools = 8
+
+// ErrToolTimeout is matched by the errors of tool calls that exceeded their timeout
+var ErrToolTimeout = errors.New("tool call timed out")
+
 // ToolExecutor executes tools based on invocations
 type ToolExecutor struct {
-	tools map[string]tools.Tool
+	tools       map[string]tools.Tool
+	maxParallel int
+	timeout     time.Duration
+	timeouts    map[string]time.Duration
+}
+
+// ToolExecutorOption configures a ToolExecutor
+type ToolExecutorOption func(*ToolExecutor)
+
+// WithMaxParallelTools limits how many tool calls run at once (DefaultMaxParallelTools
+// by default). Limits below 1 run the calls one at a time.
+func WithMaxParallelTools(limit int) ToolExecutorOption {
+	return func(te *ToolExecutor) {
+		te.maxParallel = limit
+	}
+}
+
+// WithToolTimeout sets the timeout of every tool call
+func WithToolTimeout(timeout time.Duration) ToolExecutorOption {
+	return func(te *ToolExecutor) {
+		te.timeout = timeout
+	}
+}
+
+// WithToolTimeoutFor sets the timeout of the calls of one tool, overriding WithToolTimeout
+func WithToolTimeoutFor(name string, timeout time.Duration) ToolExecutorOption {
+	return func(te *ToolExecutor) {
+		if te.timeouts == nil {
+			te.timeouts = make(map[string]time.Duration)
+		}
+		te.timeouts[name] = timeout
+	}
 }
 
 // NewToolExecutor creates a new ToolExecutor with the given tools
-func NewToolExecutor(inputTools []tools.Tool) *ToolExecutor {
+func NewToolExecutor(inputTools []tools.Tool, opts ...ToolExecutorOption) *ToolExecutor {
 	toolMap := make(map[string]tools.Tool)
 	for _, t := range inputTools {
 		toolMap[t.Name()] = t
 	}
-	return &ToolExecutor{
-		tools: toolMap,
+	te := &ToolExecutor{
+		tools:       toolMap,
+		maxParallel: DefaultMaxParallelTools,
 	}
+	for _, opt := range opts {
+		opt(te)
+	}
+	return te
 }
 
-// Execute executes a single tool invocation
+// Execute executes a single tool invocation, reporting it to the callbacks of the
+// graph config in ctx as a child of the calling node. The input of a tool.SchemaTool
+// is validated against its parameters schema before the call.
 func (te *ToolExecutor) Execute(ctx context.Context, invocation ToolInvocation) (string, error) {
-	tool, ok := te.tools[invocation.Tool]
+	t, ok := te.tools[invocation.Tool]
 	if !ok {
 		return "", fmt.Errorf("tool not found: %s", invocation.Tool)
 	}
 
-	return tool.Call(ctx, invocation.ToolInput)
+	if st, ok := t.(tool.SchemaTool); ok {
+		if err := tool.ValidateArguments(st.Parameters(), invocation.ToolInput); err != nil {
+			return "", fmt.Errorf("invalid arguments for tool %s: %w", invocation.Tool, err)
+		}
+	}
+
+	timeout := te.timeout
+	if d, ok := te.timeouts[invocation.Tool]; ok {
+		timeout = d
+	}
+	if timeout <= 0 {
+		return callTool(ctx, t, invocation.ToolInput)
+	}
+
+	// Run the tool in the background so that a tool ignoring its context does not
+	// hold the caller past the timeout
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	done := make(chan ToolResult, 1)
+	go func() {
+		out, err := callTool(callCtx, t, invocation.ToolInput)
+		done <- ToolResult{Output: out, Err: err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.Output, res.Err
+	case <-callCtx.Done():
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("%w: %s after %v", ErrToolTimeout, invocation.Tool, timeout)
+	}
 }
 
-// ExecuteMany executes multiple tool invocations in parallel (if needed, but here sequential for simplicity)
-// In a real graph, this might be a ParallelNode, but here we provide a helper.
-func (te *ToolExecutor) ExecuteMany(ctx context.Context, invocations []ToolInvocation) ([]string, error) {
-	results := make([]string, len(invocations))
+// callTool calls the tool, turning panics into errors
+func callTool(ctx context.Context, t tools.Tool, input string) (out string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("tool %s panicked: %v", t.Name(), r)
+		}
+	}()
+	return graph.CallTool(ctx, t, input)
+}
+
+// invocation converts a tool call of the model into an invocation. SchemaTools get
+// the raw JSON arguments; other tools get the "input" argument, or the raw
+// arguments when there is none.
+func (te *ToolExecutor) invocation(call *llms.FunctionCall) ToolInvocation {
+	if _, ok := te.tools[call.Name].(tool.SchemaTool); ok {
+		return ToolInvocation{Tool: call.Name, ToolInput: call.Arguments}
+	}
+
+	input := call.Arguments
+	var args map[string]interface{}
+	if err := json.Unmarshal([]byte(call.Arguments), &args); err == nil {
+		if val, ok := args["input"].(string); ok {
+			input = val
+		}
+	}
+	return ToolInvocation{Tool: call.Name, ToolInput: input}
+}
+
+// toolDefinitions returns the definitions advertising the tools to the model, with
+// the parameters schema of each tool (see tool.ToolParameters)
+func toolDefinitions(inputTools []tools.Tool) []llms.Tool {
+	var toolDefs []llms.Tool
+	for _, t := range inputTools {
+		toolDefs = append(toolDefs, llms.Tool{
+			Type: "function",
+			Function: &llms.FunctionDefinition{
+				Name:        t.Name(),
+				Description: t.Description(),
+				Parameters:  tool.ToolParameters(t),
+			},
+		})
+	}
+	return toolDefs
+}
+
+// ExecuteAll executes the invocations concurrently, running up to the executor's
+// limit at once, and returns the result of each invocation in order
+func (te *ToolExecutor) ExecuteAll(ctx context.Context, invocations []ToolInvocation) []ToolResult {
+	results := make([]ToolResult, len(invocations))
+	limit := te.maxParallel
+	if limit < 1 {
+		limit = 1
+	}
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
 	for i, inv := range invocations {
-		res, err := te.Execute(ctx, inv)
-		if err != nil {
-			return nil, err // Or continue and return partial errors?
+		wg.Add(1)
+		go func(i int, inv ToolInvocation) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results[i] = ToolResult{Err: ctx.Err()}
+				return
+			}
+			out, err := te.Execute(ctx, inv)
+			results[i] = ToolResult{Output: out, Err: err}
+		}(i, inv)
+	}
+	wg.Wait()
+	return results
+}
+
+// ExecuteMany executes the invocations concurrently like ExecuteAll, returning their
+// outputs, or the error of the first failed invocation
+func (te *ToolExecutor) ExecuteMany(ctx context.Context, invocations []ToolInvocation) ([]string, error) {
+	outputs := make([]string, len(invocations))
+	for i, res := range te.ExecuteAll(ctx, invocations) {
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		outputs[i] = res.Output
+	}
+	return outputs, nil
+}
+
+// executeToolCalls executes the tool calls of an AI message concurrently and returns
+// one tool message per call, in call order. Failed calls are reported to the model
+// in the content of their message rather than failing the node.
+func (te *ToolExecutor) executeToolCalls(ctx context.Context, msg llms.MessageContent) []llms.MessageContent {
+	var calls []llms.ToolCall
+	var invocations []ToolInvocation
+	for _, part := range msg.Parts {
+		if tc, ok := part.(llms.ToolCall); ok && tc.FunctionCall != nil {
+			calls = append(calls, tc)
+			invocations = append(invocations, te.invocation(tc.FunctionCall))
+		}
+	}
+
+	var toolMessages []llms.MessageContent
+	for i, res := range te.ExecuteAll(ctx, invocations) {
+		content := res.Output
+		if res.Err != nil {
+			content = fmt.Sprintf("Error executing tool %s: %v", calls[i].FunctionCall.Name, res.Err)
 		}
-		results[i] = res
+		toolMessages = append(toolMessages, llms.MessageContent{
+			Role: llms.ChatMessageTypeTool,
+			Parts: []llms.ContentPart{
+				llms.ToolCallResponse{
+					ToolCallID: calls[i].ID,
+					Name:       calls[i].FunctionCall.Name,
+					Content:    content,
+				},
+			},
+		})
 	}
-	return results, nil
+	return toolMessages
 }
 
 // ToolNode is a graph node function that executes tools
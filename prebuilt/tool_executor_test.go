@@ -2,9 +2,15 @@ package prebuilt
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/smallnest/langgraphgo/graph"
 	"github.com/stretchr/testify/assert"
+	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/tools"
 )
 
@@ -52,3 +58,170 @@ func TestToolExecutor(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "Executed test-tool with map-input", resMap)
 }
+
+// toolCallbacks records the tool runs reported to it
+type toolCallbacks struct {
+	graph.NoOpCallbackHandler
+	parents map[string]*string
+	outputs []string
+}
+
+func (h *toolCallbacks) OnToolStart(ctx context.Context, serialized map[string]interface{}, inputStr string, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	h.parents[serialized["name"].(string)] = parentRunID
+}
+
+func (h *toolCallbacks) OnToolEnd(ctx context.Context, output string, runID string) {
+	h.outputs = append(h.outputs, output)
+}
+
+func TestToolExecutor_Callbacks(t *testing.T) {
+	executor := NewToolExecutor([]tools.Tool{&MockTool{name: "test-tool"}})
+	handler := &toolCallbacks{parents: make(map[string]*string)}
+
+	g := graph.NewStateGraph()
+	g.AddNode("tools", "tools", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return executor.Execute(ctx, ToolInvocation{Tool: "test-tool", ToolInput: "input"})
+	})
+	g.AddEdge("tools", graph.END)
+	g.SetEntryPoint("tools")
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	_, err = runnable.InvokeWithConfig(context.Background(), "state", &graph.Config{
+		Callbacks: []graph.CallbackHandler{handler},
+	})
+	assert.NoError(t, err)
+
+	// The tool call is nested under the node run, which is nested under the graph run
+	assert.NotNil(t, handler.parents["tools"])
+	assert.NotNil(t, handler.parents["test-tool"])
+	assert.NotEqual(t, *handler.parents["tools"], *handler.parents["test-tool"])
+	assert.Equal(t, []string{"Executed test-tool with input", `"Executed test-tool with input"`}, handler.outputs)
+}
+
+// weatherTool is a SchemaTool recording the raw arguments it is called with
+type weatherTool struct {
+	inputs []string
+}
+
+func (t *weatherTool) Name() string        { return "weather" }
+func (t *weatherTool) Description() string { return "Get the weather of a city" }
+
+func (t *weatherTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"city": map[string]any{"type": "string"},
+			"unit": map[string]any{"type": "string", "enum": []string{"celsius", "fahrenheit"}},
+		},
+		"required":             []string{"city"},
+		"additionalProperties": false,
+	}
+}
+
+func (t *weatherTool) Call(ctx context.Context, input string) (string, error) {
+	t.inputs = append(t.inputs, input)
+	return "sunny", nil
+}
+
+func TestToolExecutor_SchemaTool(t *testing.T) {
+	weather := &weatherTool{}
+	executor := NewToolExecutor([]tools.Tool{weather, &MockTool{name: "test-tool"}})
+
+	// SchemaTools receive the raw arguments, other tools the "input" argument
+	args := `{"city": "Paris", "unit": "celsius"}`
+	assert.Equal(t, ToolInvocation{Tool: "weather", ToolInput: args}, executor.invocation(&llms.FunctionCall{Name: "weather", Arguments: args}))
+	assert.Equal(t, ToolInvocation{Tool: "test-tool", ToolInput: "query"}, executor.invocation(&llms.FunctionCall{Name: "test-tool", Arguments: `{"input": "query"}`}))
+
+	res, err := executor.Execute(context.Background(), ToolInvocation{Tool: "weather", ToolInput: args})
+	assert.NoError(t, err)
+	assert.Equal(t, "sunny", res)
+	assert.Equal(t, []string{args}, weather.inputs)
+
+	// Invalid arguments are rejected before the call
+	_, err = executor.Execute(context.Background(), ToolInvocation{Tool: "weather", ToolInput: `{"unit": "kelvin"}`})
+	assert.EqualError(t, err, `invalid arguments for tool weather: arguments is missing required property "city"`)
+	_, err = executor.Execute(context.Background(), ToolInvocation{Tool: "weather", ToolInput: `{"city": "Paris", "unit": "kelvin"}`})
+	assert.EqualError(t, err, "invalid arguments for tool weather: arguments.unit must be one of [celsius fahrenheit]")
+	assert.Len(t, weather.inputs, 1)
+}
+
+// funcTool is a tool calling fn
+type funcTool struct {
+	name string
+	fn   func(ctx context.Context, input string) (string, error)
+}
+
+func (t *funcTool) Name() string        { return t.name }
+func (t *funcTool) Description() string { return "A function tool" }
+func (t *funcTool) Call(ctx context.Context, input string) (string, error) {
+	return t.fn(ctx, input)
+}
+
+func TestToolExecutor_ExecuteAll(t *testing.T) {
+	var running, maxRunning int32
+	slow := &funcTool{name: "slow", fn: func(ctx context.Context, input string) (string, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		if input == "fail" {
+			return "", errors.New("failed")
+		}
+		return "done " + input, nil
+	}}
+	executor := NewToolExecutor([]tools.Tool{slow}, WithMaxParallelTools(3))
+
+	var invocations []ToolInvocation
+	for i := 0; i < 8; i++ {
+		invocations = append(invocations, ToolInvocation{Tool: "slow", ToolInput: fmt.Sprint(i)})
+	}
+	invocations[5].ToolInput = "fail"
+	results := executor.ExecuteAll(context.Background(), invocations)
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&maxRunning))
+	assert.Len(t, results, 8)
+	assert.Equal(t, ToolResult{Output: "done 0"}, results[0])
+	assert.EqualError(t, results[5].Err, "failed")
+	assert.Equal(t, ToolResult{Output: "done 7"}, results[7])
+
+	// ExecuteMany returns the first error
+	_, err := executor.ExecuteMany(context.Background(), invocations)
+	assert.EqualError(t, err, "failed")
+}
+
+func TestToolExecutor_TimeoutAndPanic(t *testing.T) {
+	hang := &funcTool{name: "hang", fn: func(ctx context.Context, input string) (string, error) {
+		time.Sleep(time.Second)
+		return "late", nil
+	}}
+	quick := &funcTool{name: "quick", fn: func(ctx context.Context, input string) (string, error) {
+		time.Sleep(20 * time.Millisecond)
+		return "quick", nil
+	}}
+	broken := &funcTool{name: "broken", fn: func(ctx context.Context, input string) (string, error) {
+		panic("boom")
+	}}
+	executor := NewToolExecutor([]tools.Tool{hang, quick, broken},
+		WithToolTimeout(100*time.Millisecond),
+		WithToolTimeoutFor("hang", 10*time.Millisecond),
+	)
+
+	start := time.Now()
+	_, err := executor.Execute(context.Background(), ToolInvocation{Tool: "hang"})
+	assert.ErrorIs(t, err, ErrToolTimeout)
+	assert.EqualError(t, err, "tool call timed out: hang after 10ms")
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	out, err := executor.Execute(context.Background(), ToolInvocation{Tool: "quick"})
+	assert.NoError(t, err)
+	assert.Equal(t, "quick", out)
+
+	_, err = executor.Execute(context.Background(), ToolInvocation{Tool: "broken"})
+	assert.EqualError(t, err, "tool broken panicked: boom")
+}
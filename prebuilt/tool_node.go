@@ -2,7 +2,6 @@ package prebuilt
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 
 	"github.com/tmc/langchaingo/llms"
@@ -15,14 +14,16 @@ type ToolNode struct {
 	Executor *ToolExecutor
 }
 
-// NewToolNode creates a new ToolNode with the given tools.
-func NewToolNode(inputTools []tools.Tool) *ToolNode {
+// NewToolNode creates a new ToolNode with the given tools. Options configure the
+// concurrency and timeouts of the tool calls.
+func NewToolNode(inputTools []tools.Tool, opts ...ToolExecutorOption) *ToolNode {
 	return &ToolNode{
-		Executor: NewToolExecutor(inputTools),
+		Executor: NewToolExecutor(inputTools, opts...),
 	}
 }
 
-// Invoke executes the tool calls found in the last message.
+// Invoke executes the tool calls found in the last message, returning one tool message
+// per call.
 func (tn *ToolNode) Invoke(ctx context.Context, state interface{}) (interface{}, error) {
 	mState, ok := state.(map[string]interface{})
 	if !ok {
@@ -49,52 +50,8 @@ func (tn *ToolNode) Invoke(ctx context.Context, state interface{}) (interface{},
 		return nil, fmt.Errorf("last message is not an AI message")
 	}
 
-	var toolMessages []llms.MessageContent
-
-	for _, part := range lastMsg.Parts {
-		if tc, ok := part.(llms.ToolCall); ok {
-			// Parse arguments to get input
-			var args map[string]interface{}
-			// Arguments is a JSON string
-			if err := json.Unmarshal([]byte(tc.FunctionCall.Arguments), &args); err != nil {
-				// If unmarshal fails, it might be a simple string or malformed.
-				// We'll try to use it as is if it's not a JSON object, but usually it is.
-				// For now, let's log/ignore or treat as empty?
-				// Let's assume it might be a direct string input if not JSON.
-			}
-
-			inputVal := ""
-			if val, ok := args["input"].(string); ok {
-				inputVal = val
-			} else {
-				// Fallback: pass the whole arguments string if "input" key is missing
-				// This depends on how the tool expects input.
-				inputVal = tc.FunctionCall.Arguments
-			}
-
-			// Execute tool
-			res, err := tn.Executor.Execute(ctx, ToolInvocation{
-				Tool:      tc.FunctionCall.Name,
-				ToolInput: inputVal,
-			})
-			if err != nil {
-				res = fmt.Sprintf("Error executing tool %s: %v", tc.FunctionCall.Name, err)
-			}
-
-			// Create ToolMessage
-			toolMsg := llms.MessageContent{
-				Role: llms.ChatMessageTypeTool,
-				Parts: []llms.ContentPart{
-					llms.ToolCallResponse{
-						ToolCallID: tc.ID,
-						Name:       tc.FunctionCall.Name,
-						Content:    res,
-					},
-				},
-			}
-			toolMessages = append(toolMessages, toolMsg)
-		}
-	}
+	// Execute the tool calls concurrently, reporting each result or error to the model
+	toolMessages := tn.Executor.executeToolCalls(ctx, lastMsg)
 
 	if len(toolMessages) == 0 {
 		// No tool calls found
@@ -2,8 +2,12 @@ package prebuilt
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/smallnest/langgraphgo/tool"
 	"github.com/stretchr/testify/assert"
 	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/tools"
@@ -56,3 +60,80 @@ func TestToolNode(t *testing.T) {
 	assert.Equal(t, "test-tool", toolResp.Name)
 	assert.Equal(t, "Executed test-tool with test-input", toolResp.Content)
 }
+
+func TestToolNode_FuncTool(t *testing.T) {
+	type addInput struct {
+		A int `json:"a"`
+		B int `json:"b"`
+	}
+	add := tool.FromFunc("add", "Adds two numbers", func(ctx context.Context, in addInput) (int, error) {
+		return in.A + in.B, nil
+	})
+	toolNode := NewToolNode([]tools.Tool{add})
+
+	aiMsg := llms.MessageContent{
+		Role: llms.ChatMessageTypeAI,
+		Parts: []llms.ContentPart{
+			llms.ToolCall{ID: "call_1", Type: "function", FunctionCall: &llms.FunctionCall{Name: "add", Arguments: `{"a": 2, "b": 3}`}},
+			llms.ToolCall{ID: "call_2", Type: "function", FunctionCall: &llms.FunctionCall{Name: "add", Arguments: `{"a": 2}`}},
+		},
+	}
+	res, err := toolNode.Invoke(context.Background(), map[string]interface{}{
+		"messages": []llms.MessageContent{aiMsg},
+	})
+	assert.NoError(t, err)
+
+	messages := res.(map[string]interface{})["messages"].([]llms.MessageContent)
+	assert.Len(t, messages, 2)
+	assert.Equal(t, "5", messages[0].Parts[0].(llms.ToolCallResponse).Content)
+	assert.Equal(t, `Error executing tool add: invalid arguments for tool add: arguments is missing required property "b"`, messages[1].Parts[0].(llms.ToolCallResponse).Content)
+}
+
+func TestToolNode_ParallelCalls(t *testing.T) {
+	// The lookups wait for each other, so they only complete when run concurrently
+	var barrier sync.WaitGroup
+	barrier.Add(2)
+	allStarted := make(chan struct{})
+	go func() {
+		barrier.Wait()
+		close(allStarted)
+	}()
+	lookup := &funcTool{name: "lookup", fn: func(ctx context.Context, input string) (string, error) {
+		barrier.Done()
+		select {
+		case <-allStarted:
+			return "found " + input, nil
+		case <-time.After(time.Second):
+			return "", errors.New("calls did not run concurrently")
+		}
+	}}
+	failing := &funcTool{name: "failing", fn: func(ctx context.Context, input string) (string, error) {
+		return "", errors.New("service unavailable")
+	}}
+	toolNode := NewToolNode([]tools.Tool{lookup, failing})
+
+	call := func(id, name, input string) llms.ToolCall {
+		return llms.ToolCall{ID: id, Type: "function", FunctionCall: &llms.FunctionCall{Name: name, Arguments: `{"input": "` + input + `"}`}}
+	}
+	aiMsg := llms.MessageContent{
+		Role:  llms.ChatMessageTypeAI,
+		Parts: []llms.ContentPart{call("call_1", "lookup", "a"), call("call_2", "failing", "b"), call("call_3", "lookup", "c")},
+	}
+	res, err := toolNode.Invoke(context.Background(), map[string]interface{}{
+		"messages": []llms.MessageContent{aiMsg},
+	})
+	assert.NoError(t, err)
+
+	// One response per call, in call order, with the failure reported to the model
+	messages := res.(map[string]interface{})["messages"].([]llms.MessageContent)
+	assert.Len(t, messages, 3)
+	expected := []llms.ToolCallResponse{
+		{ToolCallID: "call_1", Name: "lookup", Content: "found a"},
+		{ToolCallID: "call_2", Name: "failing", Content: "Error executing tool failing: service unavailable"},
+		{ToolCallID: "call_3", Name: "lookup", Content: "found c"},
+	}
+	for i, msg := range messages {
+		assert.Equal(t, llms.ChatMessageTypeTool, msg.Role)
+		assert.Equal(t, expected[i], msg.Parts[0])
+	}
+}
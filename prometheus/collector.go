@@ -0,0 +1,430 @@
+// Package prometheus exposes graph, node, tool and LLM activity as Prometheus metrics.
+//
+// A Collector is both a prometheus.Collector and a graph callback/listener: pass it in
+// graph.Config.Callbacks (and/or add it as a NodeListener on a listenable graph), then
+// register it with a Prometheus registry or serve it directly with Handler.
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/smallnest/langgraphgo/graph"
+)
+
+// Option configures a Collector
+type Option func(*options)
+
+type options struct {
+	namespace string
+	graphName string
+	buckets   []float64
+}
+
+// WithNamespace sets the metric namespace, default "langgraph"
+func WithNamespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// WithGraphName sets the value of the "graph" label.
+// When unset, the RunName of the graph config is used.
+func WithGraphName(name string) Option {
+	return func(o *options) {
+		o.graphName = name
+	}
+}
+
+// WithBuckets sets the histogram buckets for latencies, in seconds
+func WithBuckets(buckets []float64) Option {
+	return func(o *options) {
+		o.buckets = buckets
+	}
+}
+
+// errNodeFailed stands in for error events reported without an error
+var errNodeFailed = errors.New("node failed")
+
+// callRun tracks an in-flight callback run
+type callRun struct {
+	start time.Time
+	graph string
+	node  string
+	name  string
+	model string
+	// isNode is set for node executions, i.e. direct children of a graph run
+	isNode bool
+}
+
+// Collector collects graph execution metrics for Prometheus
+type Collector struct {
+	graph.NoOpCallbackHandler
+
+	graphName string
+
+	graphRuns      *prom.CounterVec
+	graphDuration  *prom.HistogramVec
+	nodeDuration   *prom.HistogramVec
+	nodeErrors     *prom.CounterVec
+	nodeRetries    *prom.CounterVec
+	circuitTrips   *prom.CounterVec
+	rateLimited    *prom.CounterVec
+	cacheHits      *prom.CounterVec
+	llmTokens      *prom.CounterVec
+	llmDuration    *prom.HistogramVec
+	toolCalls      *prom.CounterVec
+	toolDuration   *prom.HistogramVec
+	collectorsList []prom.Collector
+
+	mu         sync.Mutex
+	runs       map[string]*callRun
+	nodeStarts map[nodeExecution]time.Time
+}
+
+// nodeExecution identifies a node execution reported to OnNodeEvent by the node and
+// the runs it belongs to
+type nodeExecution struct {
+	runID       string
+	parentRunID string
+	node        string
+}
+
+// nodeExecutionOf returns the node execution of a node event
+func nodeExecutionOf(ctx context.Context, nodeName string) nodeExecution {
+	e := nodeExecution{runID: graph.RunIDFromContext(ctx), node: nodeName}
+	if parent := graph.ParentRunIDFromContext(ctx); parent != nil {
+		e.parentRunID = *parent
+	}
+	return e
+}
+
+// NewCollector creates a Prometheus collector
+func NewCollector(opts ...Option) *Collector {
+	o := &options{
+		namespace: "langgraph",
+		buckets:   prom.DefBuckets,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	c := &Collector{
+		graphName:  o.graphName,
+		runs:       make(map[string]*callRun),
+		nodeStarts: make(map[nodeExecution]time.Time),
+	}
+
+	c.graphRuns = prom.NewCounterVec(prom.CounterOpts{
+		Namespace: o.namespace, Name: "graph_runs_total",
+		Help: "Number of graph runs by outcome.",
+	}, []string{"graph", "status"})
+	c.graphDuration = prom.NewHistogramVec(prom.HistogramOpts{
+		Namespace: o.namespace, Name: "graph_duration_seconds",
+		Help: "Duration of graph runs.", Buckets: o.buckets,
+	}, []string{"graph", "status"})
+	c.nodeDuration = prom.NewHistogramVec(prom.HistogramOpts{
+		Namespace: o.namespace, Name: "node_duration_seconds",
+		Help: "Duration of node executions.", Buckets: o.buckets,
+	}, []string{"graph", "node", "status"})
+	c.nodeErrors = prom.NewCounterVec(prom.CounterOpts{
+		Namespace: o.namespace, Name: "node_errors_total",
+		Help: "Number of failed node executions.",
+	}, []string{"graph", "node"})
+	c.nodeRetries = prom.NewCounterVec(prom.CounterOpts{
+		Namespace: o.namespace, Name: "node_retries_total",
+		Help: "Number of node retry attempts.",
+	}, []string{"graph", "node"})
+	c.circuitTrips = prom.NewCounterVec(prom.CounterOpts{
+		Namespace: o.namespace, Name: "circuit_breaker_trips_total",
+		Help: "Number of times a node circuit breaker opened.",
+	}, []string{"graph", "node"})
+	c.rateLimited = prom.NewCounterVec(prom.CounterOpts{
+		Namespace: o.namespace, Name: "rate_limit_waits_total",
+		Help: "Number of node calls held back by a rate limiter.",
+	}, []string{"graph", "node"})
+	c.cacheHits = prom.NewCounterVec(prom.CounterOpts{
+		Namespace: o.namespace, Name: "node_cache_hits_total",
+		Help: "Number of node results served from the cache.",
+	}, []string{"graph", "node"})
+	c.llmTokens = prom.NewCounterVec(prom.CounterOpts{
+		Namespace: o.namespace, Name: "llm_tokens_total",
+		Help: "Number of LLM tokens used by type.",
+	}, []string{"graph", "node", "model", "type"})
+	c.llmDuration = prom.NewHistogramVec(prom.HistogramOpts{
+		Namespace: o.namespace, Name: "llm_duration_seconds",
+		Help: "Duration of LLM calls.", Buckets: o.buckets,
+	}, []string{"graph", "node", "model", "status"})
+	c.toolCalls = prom.NewCounterVec(prom.CounterOpts{
+		Namespace: o.namespace, Name: "tool_calls_total",
+		Help: "Number of tool calls by outcome.",
+	}, []string{"graph", "node", "tool", "status"})
+	c.toolDuration = prom.NewHistogramVec(prom.HistogramOpts{
+		Namespace: o.namespace, Name: "tool_duration_seconds",
+		Help: "Duration of tool calls.", Buckets: o.buckets,
+	}, []string{"graph", "node", "tool", "status"})
+
+	c.collectorsList = []prom.Collector{
+		c.graphRuns, c.graphDuration, c.nodeDuration, c.nodeErrors, c.nodeRetries,
+		c.circuitTrips, c.rateLimited, c.cacheHits, c.llmTokens, c.llmDuration,
+		c.toolCalls, c.toolDuration,
+	}
+
+	return c
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prom.Desc) {
+	for _, collector := range c.collectorsList {
+		collector.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prom.Metric) {
+	for _, collector := range c.collectorsList {
+		collector.Collect(ch)
+	}
+}
+
+// Handler returns an HTTP handler serving the collector's metrics in the Prometheus
+// exposition format, e.g. for mounting on /metrics
+func (c *Collector) Handler() http.Handler {
+	registry := prom.NewRegistry()
+	registry.MustRegister(c)
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// graphLabel returns the graph label for a run started with the given context
+func (c *Collector) graphLabel(ctx context.Context) string {
+	if c.graphName != "" {
+		return c.graphName
+	}
+	if config := graph.GetConfig(ctx); config != nil {
+		return config.RunName
+	}
+	return ""
+}
+
+// status returns the status label of a finished call
+func status(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// serializedName returns the name of a run from its serialized description
+func serializedName(serialized map[string]interface{}) string {
+	if name, ok := serialized["name"].(string); ok {
+		return name
+	}
+	return ""
+}
+
+// startRun records the start of a callback run, inheriting the node of its parent
+func (c *Collector) startRun(ctx context.Context, runID string, parentRunID *string, name string) *callRun {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r := &callRun{start: time.Now(), name: name, graph: c.graphLabel(ctx)}
+	if parentRunID != nil {
+		if parent, ok := c.runs[*parentRunID]; ok {
+			r.graph = parent.graph
+			r.node = parent.node
+			if parent.node == "" && parent.name == "" {
+				// Direct children of a graph run are node executions
+				r.node = name
+				r.isNode = true
+			}
+		}
+	}
+	if r.node == "" {
+		r.node = graph.NodeNameFromContext(ctx)
+	}
+	c.runs[runID] = r
+	return r
+}
+
+// endRun removes a callback run
+func (c *Collector) endRun(runID string) (*callRun, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.runs[runID]
+	delete(c.runs, runID)
+	return r, ok
+}
+
+// OnChainStart records the start of a graph run or nested chain
+func (c *Collector) OnChainStart(ctx context.Context, serialized map[string]interface{}, inputs map[string]interface{}, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	name := serializedName(serialized)
+	if parentRunID == nil {
+		// Graph runs are identified by an empty name so that their children become nodes
+		name = ""
+	}
+	c.startRun(ctx, runID, parentRunID, name)
+}
+
+// OnChainEnd records the end of a graph run or node
+func (c *Collector) OnChainEnd(ctx context.Context, outputs map[string]interface{}, runID string) {
+	c.endChain(runID, nil)
+}
+
+// OnChainError records a failed graph run or node
+func (c *Collector) OnChainError(ctx context.Context, err error, runID string) {
+	c.endChain(runID, err)
+}
+
+func (c *Collector) endChain(runID string, err error) {
+	r, ok := c.endRun(runID)
+	if !ok {
+		return
+	}
+	elapsed := time.Since(r.start).Seconds()
+
+	switch {
+	case r.name == "":
+		c.graphRuns.WithLabelValues(r.graph, status(err)).Inc()
+		c.graphDuration.WithLabelValues(r.graph, status(err)).Observe(elapsed)
+	case r.isNode:
+		c.observeNode(r.graph, r.node, elapsed, err)
+	}
+}
+
+// observeNode records a node execution
+func (c *Collector) observeNode(graphName, node string, elapsed float64, err error) {
+	c.nodeDuration.WithLabelValues(graphName, node, status(err)).Observe(elapsed)
+	if err != nil {
+		c.nodeErrors.WithLabelValues(graphName, node).Inc()
+	}
+}
+
+// OnLLMStart records the start of an LLM call
+func (c *Collector) OnLLMStart(ctx context.Context, serialized map[string]interface{}, prompts []string, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	model := serializedName(serialized)
+	if m, ok := serialized["model"].(string); ok {
+		model = m
+	} else if m, ok := metadata["model"].(string); ok {
+		model = m
+	}
+	r := c.startRun(ctx, runID, parentRunID, model)
+	r.model = model
+}
+
+// OnLLMEnd records the duration and token usage of an LLM call
+func (c *Collector) OnLLMEnd(ctx context.Context, response interface{}, runID string) {
+	r, ok := c.endRun(runID)
+	if !ok {
+		return
+	}
+	c.llmDuration.WithLabelValues(r.graph, r.node, r.model, status(nil)).Observe(time.Since(r.start).Seconds())
+
+	usage := graph.TokenUsageFromResponse(response)
+	if !usage.IsZero() {
+		c.llmTokens.WithLabelValues(r.graph, r.node, r.model, "input").Add(float64(usage.InputTokens))
+		c.llmTokens.WithLabelValues(r.graph, r.node, r.model, "output").Add(float64(usage.OutputTokens))
+	}
+}
+
+// OnLLMError records a failed LLM call
+func (c *Collector) OnLLMError(ctx context.Context, err error, runID string) {
+	if r, ok := c.endRun(runID); ok {
+		c.llmDuration.WithLabelValues(r.graph, r.node, r.model, status(err)).Observe(time.Since(r.start).Seconds())
+	}
+}
+
+// OnToolStart records the start of a tool call. Tool events reported directly under a
+// graph run are node executions.
+func (c *Collector) OnToolStart(ctx context.Context, serialized map[string]interface{}, inputStr string, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	c.startRun(ctx, runID, parentRunID, serializedName(serialized))
+}
+
+// OnToolEnd records a successful tool call
+func (c *Collector) OnToolEnd(ctx context.Context, output string, runID string) {
+	c.endTool(runID, nil)
+}
+
+// OnToolError records a failed tool call
+func (c *Collector) OnToolError(ctx context.Context, err error, runID string) {
+	c.endTool(runID, err)
+}
+
+func (c *Collector) endTool(runID string, err error) {
+	r, ok := c.endRun(runID)
+	if !ok {
+		return
+	}
+	elapsed := time.Since(r.start).Seconds()
+
+	if r.isNode {
+		c.observeNode(r.graph, r.node, elapsed, err)
+		return
+	}
+	c.toolCalls.WithLabelValues(r.graph, r.node, r.name, status(err)).Inc()
+	c.toolDuration.WithLabelValues(r.graph, r.node, r.name, status(err)).Observe(elapsed)
+}
+
+// OnNodeEvent implements graph.NodeListener, timing nodes of listenable graphs.
+// Cache hits are counted through OnCacheHit only, so they are not recorded twice.
+func (c *Collector) OnNodeEvent(ctx context.Context, event graph.NodeEvent, nodeName string, _ interface{}, err error) {
+	graphName := c.graphLabel(ctx)
+
+	switch event {
+	case graph.NodeEventStart:
+		c.mu.Lock()
+		c.nodeStarts[nodeExecutionOf(ctx, nodeName)] = time.Now()
+		c.mu.Unlock()
+
+	case graph.NodeEventComplete, graph.NodeEventError:
+		c.mu.Lock()
+		key := nodeExecutionOf(ctx, nodeName)
+		start, ok := c.nodeStarts[key]
+		delete(c.nodeStarts, key)
+		c.mu.Unlock()
+
+		var elapsed float64
+		if ok {
+			elapsed = time.Since(start).Seconds()
+		}
+		if event == graph.NodeEventError && err == nil {
+			err = errNodeFailed
+		}
+		c.observeNode(graphName, nodeName, elapsed, err)
+	}
+}
+
+// OnNodeRetry implements graph.ResilienceCallbackHandler
+func (c *Collector) OnNodeRetry(ctx context.Context, nodeName string, attempt int, err error) {
+	c.nodeRetries.WithLabelValues(c.graphLabel(ctx), nodeName).Inc()
+}
+
+// OnCircuitBreakerOpen implements graph.ResilienceCallbackHandler
+func (c *Collector) OnCircuitBreakerOpen(ctx context.Context, nodeName string) {
+	c.circuitTrips.WithLabelValues(c.graphLabel(ctx), nodeName).Inc()
+}
+
+// OnRateLimited implements graph.ResilienceCallbackHandler
+func (c *Collector) OnRateLimited(ctx context.Context, nodeName string, wait time.Duration) {
+	c.rateLimited.WithLabelValues(c.graphLabel(ctx), nodeName).Inc()
+}
+
+// OnCacheHit implements graph.CacheCallbackHandler
+func (c *Collector) OnCacheHit(ctx context.Context, nodeName string, key string) {
+	c.cacheHits.WithLabelValues(c.graphLabel(ctx), nodeName).Inc()
+}
+
+// OnGraphStep implements graph.GraphCallbackHandler
+func (c *Collector) OnGraphStep(ctx context.Context, stepNode string, state interface{}) {}
+
+var (
+	_ prom.Collector                  = (*Collector)(nil)
+	_ graph.GraphCallbackHandler      = (*Collector)(nil)
+	_ graph.NodeListener              = (*Collector)(nil)
+	_ graph.ResilienceCallbackHandler = (*Collector)(nil)
+	_ graph.CacheCallbackHandler      = (*Collector)(nil)
+)
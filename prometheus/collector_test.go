@@ -0,0 +1,212 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/smallnest/langgraphgo/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestCollector_GraphRun(t *testing.T) {
+	c := NewCollector(WithGraphName("test_graph"))
+
+	g := graph.NewMessageGraph()
+	g.AddNode("agent", "agent", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return state.(string) + " agent", nil
+	})
+	g.AddNode("tools", "tools", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return state.(string) + " tools", nil
+	})
+	g.SetEntryPoint("agent")
+	g.AddEdge("agent", "tools")
+	g.AddEdge("tools", graph.END)
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	_, err = runnable.InvokeWithConfig(context.Background(), "in", &graph.Config{
+		Callbacks: []graph.CallbackHandler{c},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.graphRuns.WithLabelValues("test_graph", "ok")))
+	assert.Equal(t, 2, testutil.CollectAndCount(c.nodeDuration))
+	assert.Equal(t, 0, testutil.CollectAndCount(c.toolCalls))
+}
+
+func TestCollector_NestedCalls(t *testing.T) {
+	c := NewCollector(WithGraphName("test_graph"))
+	ctx := context.Background()
+
+	graphRun := "graph-run"
+	nodeRun := "node-run"
+	c.OnChainStart(ctx, map[string]interface{}{"name": "graph"}, nil, graphRun, nil, nil, nil)
+	c.OnChainStart(ctx, map[string]interface{}{"name": "agent"}, nil, nodeRun, &graphRun, nil, nil)
+
+	c.OnLLMStart(ctx, map[string]interface{}{"model": "gpt-4o"}, []string{"hi"}, "llm-run", &nodeRun, nil, nil)
+	c.OnLLMEnd(ctx, &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+		GenerationInfo: map[string]interface{}{"PromptTokens": 12, "CompletionTokens": 5},
+	}}}, "llm-run")
+
+	c.OnToolStart(ctx, map[string]interface{}{"name": "search"}, "q", "tool-run", &nodeRun, nil, nil)
+	c.OnToolError(ctx, errors.New("boom"), "tool-run")
+
+	c.OnChainError(ctx, errors.New("failed"), nodeRun)
+	c.OnChainError(ctx, errors.New("failed"), graphRun)
+
+	assert.Equal(t, 12.0, testutil.ToFloat64(c.llmTokens.WithLabelValues("test_graph", "agent", "gpt-4o", "input")))
+	assert.Equal(t, 5.0, testutil.ToFloat64(c.llmTokens.WithLabelValues("test_graph", "agent", "gpt-4o", "output")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.toolCalls.WithLabelValues("test_graph", "agent", "search", "error")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.nodeErrors.WithLabelValues("test_graph", "agent")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.graphRuns.WithLabelValues("test_graph", "error")))
+}
+
+func TestCollector_ToolNamedAfterNode(t *testing.T) {
+	c := NewCollector(WithGraphName("test_graph"))
+	ctx := context.Background()
+
+	graphRun := "graph-run"
+	nodeRun := "node-run"
+	c.OnChainStart(ctx, map[string]interface{}{"name": "graph"}, nil, graphRun, nil, nil, nil)
+	c.OnToolStart(ctx, map[string]interface{}{"name": "search"}, "q", nodeRun, &graphRun, nil, nil)
+
+	// A tool called by the node it shares its name with is still a tool call
+	c.OnToolStart(ctx, map[string]interface{}{"name": "search"}, "q", "tool-run", &nodeRun, nil, nil)
+	c.OnToolEnd(ctx, "result", "tool-run")
+
+	c.OnToolEnd(ctx, "done", nodeRun)
+	c.OnChainEnd(ctx, nil, graphRun)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.toolCalls.WithLabelValues("test_graph", "search", "search", "ok")))
+	assert.Equal(t, 1, testutil.CollectAndCount(c.nodeDuration))
+}
+
+func TestCollector_ResilienceEvents(t *testing.T) {
+	c := NewCollector()
+
+	attempts := 0
+	g := graph.NewMessageGraph()
+	g.AddNodeWithRetry("flaky", "flaky", func(ctx context.Context, state interface{}) (interface{}, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("transient")
+		}
+		return state, nil
+	}, &graph.RetryConfig{
+		MaxAttempts:   3,
+		InitialDelay:  time.Millisecond,
+		MaxDelay:      time.Millisecond,
+		BackoffFactor: 1,
+	})
+	g.SetEntryPoint("flaky")
+	g.AddEdge("flaky", graph.END)
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+
+	_, err = runnable.InvokeWithConfig(context.Background(), "in", &graph.Config{
+		RunName:   "retry_graph",
+		Callbacks: []graph.CallbackHandler{c},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2.0, testutil.ToFloat64(c.nodeRetries.WithLabelValues("retry_graph", "flaky")))
+
+	ctx := graph.WithConfig(context.Background(), &graph.Config{RunName: "retry_graph", Callbacks: []graph.CallbackHandler{c}})
+	c.OnCircuitBreakerOpen(ctx, "flaky")
+	c.OnRateLimited(ctx, "flaky", time.Second)
+	c.OnCacheHit(ctx, "flaky", "key")
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.circuitTrips.WithLabelValues("retry_graph", "flaky")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.rateLimited.WithLabelValues("retry_graph", "flaky")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.cacheHits.WithLabelValues("retry_graph", "flaky")))
+}
+
+func TestCollector_ListenerAndHandler(t *testing.T) {
+	c := NewCollector(WithGraphName("listenable"))
+
+	g := graph.NewListenableMessageGraph()
+	node := g.AddNode("step", "step", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return state, nil
+	})
+	node.AddListener(c)
+	g.SetEntryPoint("step")
+	g.AddEdge("step", graph.END)
+
+	runnable, err := g.CompileListenable()
+	assert.NoError(t, err)
+	_, err = runnable.Invoke(context.Background(), "in")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, testutil.CollectAndCount(c.nodeDuration))
+
+	server := httptest.NewServer(c.Handler())
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `langgraph_node_duration_seconds_count{graph="listenable",node="step",status="ok"} 1`)
+}
+
+func TestCollector_ListenerConcurrentRuns(t *testing.T) {
+	c := NewCollector(WithGraphName("concurrent"))
+
+	var started sync.WaitGroup
+	started.Add(2)
+	g := graph.NewListenableMessageGraph()
+	node := g.AddNode("work", "work", func(ctx context.Context, state interface{}) (interface{}, error) {
+		started.Done()
+		started.Wait()
+		if state == "slow" {
+			time.Sleep(60 * time.Millisecond)
+		} else {
+			time.Sleep(30 * time.Millisecond)
+		}
+		return state, nil
+	})
+	node.AddListener(c)
+	g.SetEntryPoint("work")
+	g.AddEdge("work", graph.END)
+
+	runnable, err := g.CompileListenable()
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for _, state := range []string{"slow", "fast"} {
+		wg.Add(1)
+		go func(state string) {
+			defer wg.Done()
+			_, err := runnable.Invoke(context.Background(), state)
+			assert.NoError(t, err)
+		}(state)
+	}
+	wg.Wait()
+
+	// Each run is timed from its own start
+	server := httptest.NewServer(c.Handler())
+	defer server.Close()
+	resp, err := server.Client().Get(server.URL)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	match := regexp.MustCompile(`langgraph_node_duration_seconds_sum\{graph="concurrent",node="work",status="ok"\} (\S+)`).FindSubmatch(body)
+	if assert.NotNil(t, match) {
+		sum, err := strconv.ParseFloat(string(match[1]), 64)
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, sum, 0.09)
+	}
+	assert.Empty(t, c.nodeStarts)
+}
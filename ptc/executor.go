@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/smallnest/langgraphgo/log"
+	langtool "github.com/smallnest/langgraphgo/tool"
 	"github.com/tmc/langchaingo/tools"
 )
 
@@ -114,8 +115,7 @@ func (ce *CodeExecutor) GetToolServerURL() string {
 
 // Execute runs the generated code with access to tools
 func (ce *CodeExecutor) Execute(ctx context.Context, code string) (*ExecutionResult, error) {
-	log.Debug("Executing code in %s mode with language %s", ce.Mode, ce.Language)
-	log.Debug("Code length: %d bytes", len(code))
+	log.DebugContext(ctx, "Executing code in sandbox", "mode", ce.Mode, "language", ce.Language, "code_bytes", len(code))
 
 	var result *ExecutionResult
 	var err error
@@ -127,14 +127,14 @@ func (ce *CodeExecutor) Execute(ctx context.Context, code string) (*ExecutionRes
 		result, err = ce.executeGo(ctx, code)
 	default:
 		err = fmt.Errorf("unsupported language: %s", ce.Language)
-		log.Error("Unsupported language: %s", ce.Language)
+		log.ErrorContext(ctx, "Unsupported language", "language", ce.Language)
 		return nil, err
 	}
 
 	if err != nil {
-		log.Error("Code execution failed: %v", err)
+		log.ErrorContext(ctx, "Code execution failed", "language", ce.Language, "error", err)
 	} else {
-		log.Info("Code execution succeeded, output length: %d bytes", len(result.Output))
+		log.InfoContext(ctx, "Code execution succeeded", "language", ce.Language, "output_bytes", len(result.Output))
 	}
 
 	return result, err
@@ -1099,7 +1099,14 @@ func (ce *CodeExecutor) GetToolDefinitions() string {
 	for _, tool := range ce.Tools {
 		def := fmt.Sprintf("\n## %s\n", tool.Name())
 		def += fmt.Sprintf("Description: %s\n", tool.Description())
-		def += fmt.Sprintf("Usage: %s(input_string)\n", sanitizeFunctionName(tool.Name()))
+		if st, ok := tool.(langtool.SchemaTool); ok {
+			// Tools with structured arguments take a JSON object matching their schema
+			params, _ := json.Marshal(langtool.ToolParameters(st))
+			def += fmt.Sprintf("Usage: %s(json_arguments)\n", sanitizeFunctionName(tool.Name()))
+			def += fmt.Sprintf("Parameters (JSON Schema): %s\n", params)
+		} else {
+			def += fmt.Sprintf("Usage: %s(input_string)\n", sanitizeFunctionName(tool.Name()))
+		}
 		defs = append(defs, def)
 	}
 
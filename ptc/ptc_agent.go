@@ -155,7 +155,7 @@ func agentNode(ctx context.Context, state interface{}, model llms.Model, systemP
 	}
 
 	// Call the model
-	resp, err := model.GenerateContent(ctx, messages)
+	resp, err := graph.GenerateContent(ctx, model, messages)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate content: %w", err)
 	}
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/smallnest/langgraphgo/graph"
 	"github.com/smallnest/langgraphgo/ptc"
 	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/tools"
@@ -281,6 +282,43 @@ func TestPTCAgentWithCustomConfig(t *testing.T) {
 	}
 }
 
+// llmCallRecorder counts the LLM callbacks of a run
+type llmCallRecorder struct {
+	graph.NoOpCallbackHandler
+	starts, ends int
+}
+
+func (r *llmCallRecorder) OnLLMStart(ctx context.Context, serialized map[string]interface{}, prompts []string, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	r.starts++
+}
+
+func (r *llmCallRecorder) OnLLMEnd(ctx context.Context, response interface{}, runID string) {
+	r.ends++
+}
+
+// TestPTCAgentReportsLLMCalls tests that the model calls of the agent reach the run callbacks
+func TestPTCAgentReportsLLMCalls(t *testing.T) {
+	agent, err := ptc.CreatePTCAgent(ptc.PTCAgentConfig{
+		Model: &MockLLM{response: "The answer is 42"},
+		Tools: []tools.Tool{MockTool{name: "test", description: "Test", response: "ok"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	recorder := &llmCallRecorder{}
+	_, err = agent.InvokeWithConfig(context.Background(), map[string]interface{}{
+		"messages": []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "What is the answer?")},
+	}, &graph.Config{Callbacks: []graph.CallbackHandler{recorder}})
+	if err != nil {
+		t.Fatalf("Failed to invoke agent: %v", err)
+	}
+
+	if recorder.starts != 1 || recorder.ends != 1 {
+		t.Errorf("Expected 1 LLM start and end callback, got %d and %d", recorder.starts, recorder.ends)
+	}
+}
+
 // TestSanitizeFunctionName tests function name sanitization
 // This is an indirect test through tool definitions
 func TestSanitizeFunctionName(t *testing.T) {
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/smallnest/langgraphgo/log"
+	"github.com/smallnest/langgraphgo/tool"
 	"github.com/tmc/langchaingo/tools"
 )
 
@@ -67,7 +68,7 @@ func (ts *ToolServer) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to find available port: %w", err)
 	}
 	ts.port = listener.Addr().(*net.TCPAddr).Port
-	log.Info("Tool server starting on port %d", ts.port)
+	log.InfoContext(ctx, "Tool server starting on port", "port", ts.port)
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/tools", ts.handleListTools)
@@ -85,13 +86,13 @@ func (ts *ToolServer) Start(ctx context.Context) error {
 	// Start server in goroutine
 	go func() {
 		if err := ts.server.Serve(listener); err != nil && err != http.ErrServerClosed {
-			log.Error("Tool server error: %v", err)
+			log.ErrorContext(ctx, "Tool server error", "port", ts.port, "error", err)
 		}
 	}()
 
 	// Wait a bit for server to start
 	time.Sleep(100 * time.Millisecond)
-	log.Info("Tool server started successfully on http://127.0.0.1:%d", ts.port)
+	log.InfoContext(ctx, "Tool server started successfully", "url", fmt.Sprintf("http://127.0.0.1:%d", ts.port))
 
 	return nil
 }
@@ -143,12 +144,17 @@ func (ts *ToolServer) handleListTools(w http.ResponseWriter, r *http.Request) {
 	ts.mu.RLock()
 	defer ts.mu.RUnlock()
 
-	toolList := make([]map[string]string, 0, len(ts.tools))
-	for name, tool := range ts.tools {
-		toolList = append(toolList, map[string]string{
+	toolList := make([]map[string]interface{}, 0, len(ts.tools))
+	for name, t := range ts.tools {
+		entry := map[string]interface{}{
 			"name":        name,
-			"description": tool.Description(),
-		})
+			"description": t.Description(),
+		}
+		// Tools with structured arguments also list their JSON schema
+		if st, ok := t.(tool.SchemaTool); ok {
+			entry["parameters"] = tool.ToolParameters(st)
+		}
+		toolList = append(toolList, entry)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -166,19 +172,19 @@ func (ts *ToolServer) handleCallTool(w http.ResponseWriter, r *http.Request) {
 
 	var req ToolRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Warn("Invalid tool call request: %v", err)
+		log.WarnContext(r.Context(), "Invalid tool call request", "error", err)
 		ts.sendErrorResponse(w, "", nil, fmt.Sprintf("Invalid request: %v", err))
 		return
 	}
 
-	log.Debug("Tool call request: %s", req.ToolName)
+	log.DebugContext(r.Context(), "Tool call request", "tool", req.ToolName)
 
 	ts.mu.RLock()
-	tool, exists := ts.tools[req.ToolName]
+	t, exists := ts.tools[req.ToolName]
 	ts.mu.RUnlock()
 
 	if !exists {
-		log.Warn("Tool not found: %s", req.ToolName)
+		log.WarnContext(r.Context(), "Tool not found", "tool", req.ToolName)
 		ts.sendErrorResponse(w, req.ToolName, req.Input, fmt.Sprintf("Tool not found: %s", req.ToolName))
 		return
 	}
@@ -196,20 +202,29 @@ func (ts *ToolServer) handleCallTool(w http.ResponseWriter, r *http.Request) {
 		inputStr = string(inputBytes)
 	}
 
-	log.Debug("Executing tool %s with input length: %d bytes", req.ToolName, len(inputStr))
+	// Validate the arguments of tools with structured arguments
+	if st, ok := t.(tool.SchemaTool); ok {
+		if err := tool.ValidateArguments(st.Parameters(), inputStr); err != nil {
+			log.WarnContext(r.Context(), "Invalid tool arguments", "tool", req.ToolName, "error", err)
+			ts.sendErrorResponse(w, req.ToolName, req.Input, fmt.Sprintf("Invalid arguments: %v", err))
+			return
+		}
+	}
+
+	log.DebugContext(r.Context(), "Executing tool", "tool", req.ToolName, "input_bytes", len(inputStr))
 
 	// Execute tool
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
 
-	result, err := tool.Call(ctx, inputStr)
+	result, err := t.Call(ctx, inputStr)
 	if err != nil {
-		log.Error("Tool %s execution failed: %v", req.ToolName, err)
+		log.ErrorContext(ctx, "Tool execution failed", "tool", req.ToolName, "error", err)
 		ts.sendErrorResponse(w, req.ToolName, req.Input, fmt.Sprintf("Tool execution failed: %v", err))
 		return
 	}
 
-	log.Info("Tool %s executed successfully, result length: %d bytes", req.ToolName, len(result))
+	log.InfoContext(ctx, "Tool executed successfully", "tool", req.ToolName, "result_bytes", len(result))
 	ts.sendSuccessResponse(w, req.ToolName, req.Input, result)
 }
 
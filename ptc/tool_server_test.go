@@ -0,0 +1,90 @@
+package ptc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/smallnest/langgraphgo/ptc"
+	"github.com/smallnest/langgraphgo/tool"
+	"github.com/tmc/langchaingo/tools"
+)
+
+type addInput struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+func newAddTool() tools.Tool {
+	return tool.FromFunc("add", "Adds two numbers", func(ctx context.Context, in addInput) (int, error) {
+		return in.A + in.B, nil
+	})
+}
+
+func callTool(t *testing.T, baseURL string, input interface{}) ptc.ToolResponse {
+	t.Helper()
+	body, _ := json.Marshal(ptc.ToolRequest{ToolName: "add", Input: input})
+	resp, err := http.Post(baseURL+"/call", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to call tool: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result ptc.ToolResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return result
+}
+
+func TestToolServer_SchemaTool(t *testing.T) {
+	server := ptc.NewToolServer([]tools.Tool{newAddTool()})
+	ctx := context.Background()
+	if err := server.Start(ctx); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop(ctx)
+
+	// The tool list includes the schema of the arguments
+	resp, err := http.Get(server.GetBaseURL() + "/tools")
+	if err != nil {
+		t.Fatalf("Failed to list tools: %v", err)
+	}
+	defer resp.Body.Close()
+	var list struct {
+		Tools []map[string]interface{} `json:"tools"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode tool list: %v", err)
+	}
+	if len(list.Tools) != 1 || list.Tools[0]["parameters"] == nil {
+		t.Fatalf("Expected the add tool with its parameters, got %v", list.Tools)
+	}
+
+	// Structured arguments are passed to the tool
+	result := callTool(t, server.GetBaseURL(), map[string]interface{}{"a": 2, "b": 3})
+	if !result.Success || result.Result != "5" {
+		t.Errorf("Expected result 5, got %+v", result)
+	}
+
+	// Invalid arguments are rejected
+	result = callTool(t, server.GetBaseURL(), map[string]interface{}{"a": "two"})
+	if result.Success || !strings.Contains(result.Error, "Invalid arguments") {
+		t.Errorf("Expected invalid arguments error, got %+v", result)
+	}
+}
+
+func TestToolDefinitions_SchemaTool(t *testing.T) {
+	executor := ptc.NewCodeExecutor(ptc.LanguagePython, []tools.Tool{newAddTool()})
+	defs := executor.GetToolDefinitions()
+
+	if !strings.Contains(defs, "Usage: add(json_arguments)") {
+		t.Errorf("Expected JSON arguments usage, got %s", defs)
+	}
+	if !strings.Contains(defs, `"required":["a","b"]`) {
+		t.Errorf("Expected the parameters schema, got %s", defs)
+	}
+}
@@ -0,0 +1,364 @@
+// Package replay records graph runs to JSONL files and replays them offline.
+//
+// A Recorder is a graph callback handler that writes every chain, node, LLM, tool,
+// retriever, step and routing event of a run as one JSON object per line. The
+// recorded LLM responses and tool outputs can then be served by a ReplayModel and
+// ReplayTools, so the same graph runs deterministically without network access and
+// its new recording can be compared with Diff.
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/smallnest/langgraphgo/graph"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// EventType identifies the kind of a recorded event
+type EventType string
+
+const (
+	EventChainStart     EventType = "chain_start"
+	EventChainEnd       EventType = "chain_end"
+	EventChainError     EventType = "chain_error"
+	EventNodeStart      EventType = "node_start"
+	EventNodeEnd        EventType = "node_end"
+	EventNodeError      EventType = "node_error"
+	EventLLMStart       EventType = "llm_start"
+	EventLLMEnd         EventType = "llm_end"
+	EventLLMError       EventType = "llm_error"
+	EventToolStart      EventType = "tool_start"
+	EventToolEnd        EventType = "tool_end"
+	EventToolError      EventType = "tool_error"
+	EventRetrieverStart EventType = "retriever_start"
+	EventRetrieverEnd   EventType = "retriever_end"
+	EventRetrieverError EventType = "retriever_error"
+	EventStep           EventType = "step"
+	EventRoute          EventType = "route"
+)
+
+// Event is one line of a recording
+type Event struct {
+	Seq         int                   `json:"seq"`
+	Time        time.Time             `json:"time"`
+	Type        EventType             `json:"type"`
+	RunID       string                `json:"run_id,omitempty"`
+	ParentRunID string                `json:"parent_run_id,omitempty"`
+	Name        string                `json:"name,omitempty"`
+	Node        string                `json:"node,omitempty"`
+	Input       interface{}           `json:"input,omitempty"`
+	Output      interface{}           `json:"output,omitempty"`
+	Prompts     []string              `json:"prompts,omitempty"`
+	Response    *llms.ContentResponse `json:"response,omitempty"`
+	Error       string                `json:"error,omitempty"`
+}
+
+// runInfo describes a run started but not yet finished
+type runInfo struct {
+	name  string
+	node  bool
+	graph bool
+}
+
+// Recorder is a callback handler writing the events of graph runs as JSONL
+type Recorder struct {
+	graph.NoOpCallbackHandler
+
+	mu     sync.Mutex
+	w      *bufio.Writer
+	closer io.Closer
+	seq    int
+	events []Event
+	runs   map[string]runInfo
+	err    error
+}
+
+// NewRecorder creates a recorder writing to w. Each event is flushed as it is recorded.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{
+		w:    bufio.NewWriter(w),
+		runs: make(map[string]runInfo),
+	}
+}
+
+// NewFileRecorder creates a recorder writing to the file at path, truncating it
+func NewFileRecorder(path string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording file: %w", err)
+	}
+	r := NewRecorder(f)
+	r.closer = f
+	return r, nil
+}
+
+// Events returns the events recorded so far, as they would be read back from the file
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+// Err returns the first error that occurred while writing the recording
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Close flushes the recording and closes the file opened by NewFileRecorder
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.w.Flush(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if r.closer != nil {
+		if err := r.closer.Close(); err != nil && r.err == nil {
+			r.err = err
+		}
+		r.closer = nil
+	}
+	return r.err
+}
+
+// record appends an event to the recording
+func (r *Recorder) record(ctx context.Context, event Event) {
+	event.Input = normalize(event.Input)
+	event.Output = normalize(event.Output)
+	if event.Response != nil {
+		event.Response = normalizeResponse(event.Response)
+	}
+	if event.Node == "" {
+		switch event.Type {
+		case EventNodeStart, EventNodeEnd, EventNodeError:
+			event.Node = event.Name
+		default:
+			event.Node = graph.NodeNameFromContext(ctx)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seq++
+	event.Seq = r.seq
+	event.Time = time.Now().UTC().Round(0)
+	r.events = append(r.events, event)
+
+	if r.err != nil {
+		return
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		r.err = fmt.Errorf("failed to marshal event: %w", err)
+		return
+	}
+	if _, err := r.w.Write(append(data, '\n')); err != nil {
+		r.err = err
+		return
+	}
+	if err := r.w.Flush(); err != nil {
+		r.err = err
+	}
+}
+
+// normalize converts a value to its JSON representation, so recorded values compare
+// equal to values read back from a file
+func normalize(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return string(data)
+	}
+	return out
+}
+
+// normalizeResponse copies a response through its JSON representation
+func normalizeResponse(resp *llms.ContentResponse) *llms.ContentResponse {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return resp
+	}
+	var out llms.ContentResponse
+	if err := json.Unmarshal(data, &out); err != nil {
+		return resp
+	}
+	return &out
+}
+
+// parentID dereferences an optional parent run ID
+func parentID(parentRunID *string) string {
+	if parentRunID == nil {
+		return ""
+	}
+	return *parentRunID
+}
+
+// startRun registers a run; chain and tool runs directly under a graph run are nodes
+func (r *Recorder) startRun(runID string, parentRunID *string, name string, graphRun bool) runInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	info := runInfo{name: name, graph: graphRun}
+	if parentRunID != nil {
+		info.node = r.runs[*parentRunID].graph
+	}
+	r.runs[runID] = info
+	return info
+}
+
+// endRun unregisters a run
+func (r *Recorder) endRun(runID string) runInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info := r.runs[runID]
+	delete(r.runs, runID)
+	return info
+}
+
+func serializedName(serialized map[string]interface{}) string {
+	name, _ := serialized["name"].(string)
+	return name
+}
+
+// OnChainStart implements graph.CallbackHandler
+func (r *Recorder) OnChainStart(ctx context.Context, serialized map[string]interface{}, inputs map[string]interface{}, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	name := serializedName(serialized)
+	info := r.startRun(runID, parentRunID, name, parentRunID == nil)
+	eventType := EventChainStart
+	if info.node {
+		eventType = EventNodeStart
+	}
+	r.record(ctx, Event{Type: eventType, RunID: runID, ParentRunID: parentID(parentRunID), Name: name, Input: inputs})
+}
+
+// OnChainEnd implements graph.CallbackHandler
+func (r *Recorder) OnChainEnd(ctx context.Context, outputs map[string]interface{}, runID string) {
+	info := r.endRun(runID)
+	eventType := EventChainEnd
+	if info.node {
+		eventType = EventNodeEnd
+	}
+	r.record(ctx, Event{Type: eventType, RunID: runID, Name: info.name, Output: outputs})
+}
+
+// OnChainError implements graph.CallbackHandler
+func (r *Recorder) OnChainError(ctx context.Context, err error, runID string) {
+	info := r.endRun(runID)
+	eventType := EventChainError
+	if info.node {
+		eventType = EventNodeError
+	}
+	r.record(ctx, Event{Type: eventType, RunID: runID, Name: info.name, Error: err.Error()})
+}
+
+// OnLLMStart implements graph.CallbackHandler
+func (r *Recorder) OnLLMStart(ctx context.Context, serialized map[string]interface{}, prompts []string, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	name := serializedName(serialized)
+	if model, ok := serialized["model"].(string); ok {
+		name = model
+	}
+	r.startRun(runID, parentRunID, name, false)
+	r.record(ctx, Event{Type: EventLLMStart, RunID: runID, ParentRunID: parentID(parentRunID), Name: name, Prompts: prompts})
+}
+
+// OnLLMEnd implements graph.CallbackHandler
+func (r *Recorder) OnLLMEnd(ctx context.Context, response interface{}, runID string) {
+	info := r.endRun(runID)
+	event := Event{Type: EventLLMEnd, RunID: runID, Name: info.name}
+	switch resp := response.(type) {
+	case *llms.ContentResponse:
+		event.Response = resp
+	case llms.ContentResponse:
+		event.Response = &resp
+	default:
+		event.Output = response
+	}
+	r.record(ctx, event)
+}
+
+// OnLLMError implements graph.CallbackHandler
+func (r *Recorder) OnLLMError(ctx context.Context, err error, runID string) {
+	info := r.endRun(runID)
+	r.record(ctx, Event{Type: EventLLMError, RunID: runID, Name: info.name, Error: err.Error()})
+}
+
+// OnToolStart implements graph.CallbackHandler
+func (r *Recorder) OnToolStart(ctx context.Context, serialized map[string]interface{}, inputStr string, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	name := serializedName(serialized)
+	info := r.startRun(runID, parentRunID, name, false)
+	eventType := EventToolStart
+	if info.node {
+		eventType = EventNodeStart
+	}
+	r.record(ctx, Event{Type: eventType, RunID: runID, ParentRunID: parentID(parentRunID), Name: name, Input: inputStr})
+}
+
+// OnToolEnd implements graph.CallbackHandler
+func (r *Recorder) OnToolEnd(ctx context.Context, output string, runID string) {
+	info := r.endRun(runID)
+	eventType := EventToolEnd
+	if info.node {
+		eventType = EventNodeEnd
+	}
+	r.record(ctx, Event{Type: eventType, RunID: runID, Name: info.name, Output: output})
+}
+
+// OnToolError implements graph.CallbackHandler
+func (r *Recorder) OnToolError(ctx context.Context, err error, runID string) {
+	info := r.endRun(runID)
+	eventType := EventToolError
+	if info.node {
+		eventType = EventNodeError
+	}
+	r.record(ctx, Event{Type: eventType, RunID: runID, Name: info.name, Error: err.Error()})
+}
+
+// OnRetrieverStart implements graph.CallbackHandler
+func (r *Recorder) OnRetrieverStart(ctx context.Context, serialized map[string]interface{}, query string, runID string, parentRunID *string, tags []string, metadata map[string]interface{}) {
+	name := serializedName(serialized)
+	r.startRun(runID, parentRunID, name, false)
+	r.record(ctx, Event{Type: EventRetrieverStart, RunID: runID, ParentRunID: parentID(parentRunID), Name: name, Input: query})
+}
+
+// OnRetrieverEnd implements graph.CallbackHandler
+func (r *Recorder) OnRetrieverEnd(ctx context.Context, documents []interface{}, runID string) {
+	info := r.endRun(runID)
+	r.record(ctx, Event{Type: EventRetrieverEnd, RunID: runID, Name: info.name, Output: documents})
+}
+
+// OnRetrieverError implements graph.CallbackHandler
+func (r *Recorder) OnRetrieverError(ctx context.Context, err error, runID string) {
+	info := r.endRun(runID)
+	r.record(ctx, Event{Type: EventRetrieverError, RunID: runID, Name: info.name, Error: err.Error()})
+}
+
+// OnGraphStep implements graph.GraphCallbackHandler, recording the state after each step
+func (r *Recorder) OnGraphStep(ctx context.Context, stepNode string, state interface{}) {
+	r.record(ctx, Event{Type: EventStep, Name: stepNode, Output: state})
+}
+
+// OnRoute implements graph.RoutingCallbackHandler
+func (r *Recorder) OnRoute(ctx context.Context, from string, to []string) {
+	r.record(ctx, Event{Type: EventRoute, Name: from, Output: to})
+}
+
+var (
+	_ graph.GraphCallbackHandler   = (*Recorder)(nil)
+	_ graph.RoutingCallbackHandler = (*Recorder)(nil)
+)
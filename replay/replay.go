@@ -0,0 +1,382 @@
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/tools"
+)
+
+// ErrNoRecordedCall is returned when a replay has no recorded call left to serve
+var ErrNoRecordedCall = errors.New("no recorded call left to replay")
+
+// ErrUnmatchedCall is returned when no recorded call left matches the request of a
+// call, e.g. because a change of the graph altered its prompts or tool inputs
+var ErrUnmatchedCall = errors.New("no recorded call matches the request")
+
+// Option configures a ReplayModel or ReplayTool
+type Option func(*replayer)
+
+// WithFallback answers calls that match no recorded call with the next unused
+// recorded call instead of failing with ErrUnmatchedCall. The requests served this
+// way are reported by Mismatches.
+func WithFallback() Option {
+	return func(r *replayer) {
+		r.fallback = true
+	}
+}
+
+// ReadEvents reads a JSONL recording
+func ReadEvents(r io.Reader) ([]Event, error) {
+	var events []Event
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("invalid event on line %d: %w", line, err)
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+	return events, nil
+}
+
+// LoadEvents reads the JSONL recording at path
+func LoadEvents(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer f.Close()
+	return ReadEvents(f)
+}
+
+// recordedCall is a recorded LLM or tool call with its result
+type recordedCall struct {
+	name     string
+	prompts  []string
+	input    string
+	response *llms.ContentResponse
+	output   string
+	err      string
+	used     bool
+}
+
+// pairCalls matches start events with their end or error events by run ID
+func pairCalls(events []Event, start, end, failure EventType) []*recordedCall {
+	var calls []*recordedCall
+	byRun := make(map[string]*recordedCall)
+	for _, event := range events {
+		switch event.Type {
+		case start:
+			call := &recordedCall{name: event.Name, prompts: event.Prompts}
+			if input, ok := event.Input.(string); ok {
+				call.input = input
+			}
+			byRun[event.RunID] = call
+		case end, failure:
+			call, ok := byRun[event.RunID]
+			if !ok {
+				continue
+			}
+			delete(byRun, event.RunID)
+			call.response = event.Response
+			if output, ok := event.Output.(string); ok {
+				call.output = output
+			}
+			call.err = event.Error
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// replayer hands out the first unused recorded call whose request matches, or with
+// WithFallback the first unused call in recording order
+type replayer struct {
+	mu         sync.Mutex
+	calls      []*recordedCall
+	fallback   bool
+	mismatches []string
+}
+
+// init sets the calls to replay and applies the options
+func (r *replayer) init(calls []*recordedCall, opts []Option) {
+	r.calls = calls
+	for _, opt := range opts {
+		opt(r)
+	}
+}
+
+// next returns the recorded call for a request, described by request in errors and
+// mismatches
+func (r *replayer) next(request string, match func(*recordedCall) bool) (*recordedCall, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var fallback *recordedCall
+	for _, call := range r.calls {
+		if call.used {
+			continue
+		}
+		if match(call) {
+			call.used = true
+			return call, nil
+		}
+		if fallback == nil {
+			fallback = call
+		}
+	}
+	if fallback == nil {
+		return nil, ErrNoRecordedCall
+	}
+	if !r.fallback {
+		return nil, fmt.Errorf("%w: %s", ErrUnmatchedCall, request)
+	}
+	r.mismatches = append(r.mismatches, request)
+	fallback.used = true
+	return fallback, nil
+}
+
+// mismatchedRequests returns the requests answered by a fallback
+func (r *replayer) mismatchedRequests() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.mismatches...)
+}
+
+// remaining returns the number of recorded calls not served yet
+func (r *replayer) remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, call := range r.calls {
+		if !call.used {
+			n++
+		}
+	}
+	return n
+}
+
+// ReplayModel is an llms.Model serving the LLM responses of a recording.
+// A call is answered with the first unused recorded call made with the same prompts,
+// so runs with parallel nodes replay deterministically, and fails with
+// ErrUnmatchedCall when none matches unless WithFallback is given.
+type ReplayModel struct {
+	replayer
+}
+
+// NewReplayModel creates a model replaying the LLM calls of the recorded events
+func NewReplayModel(events []Event, opts ...Option) *ReplayModel {
+	m := &ReplayModel{}
+	m.init(pairCalls(events, EventLLMStart, EventLLMEnd, EventLLMError), opts)
+	return m
+}
+
+// GenerateContent implements llms.Model
+func (m *ReplayModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	prompts := textPrompts(messages)
+	call, err := m.next(fmt.Sprintf("prompts %q", prompts), func(c *recordedCall) bool {
+		return reflect.DeepEqual(c.prompts, prompts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if call.err != "" {
+		return nil, errors.New(call.err)
+	}
+	if call.response == nil {
+		return &llms.ContentResponse{}, nil
+	}
+	return call.response, nil
+}
+
+// Call implements llms.Model
+func (m *ReplayModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// Remaining returns the number of recorded LLM calls not replayed yet
+func (m *ReplayModel) Remaining() int {
+	return m.remaining()
+}
+
+// Mismatches returns the requests that matched no recorded call and were answered
+// with WithFallback
+func (m *ReplayModel) Mismatches() []string {
+	return m.mismatchedRequests()
+}
+
+// textPrompts returns the text parts of the messages, as reported to OnLLMStart
+func textPrompts(messages []llms.MessageContent) []string {
+	var prompts []string
+	for _, msg := range messages {
+		for _, part := range msg.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				prompts = append(prompts, text.Text)
+			}
+		}
+	}
+	return prompts
+}
+
+// ReplayTool is a tools.Tool serving the recorded outputs of a tool.
+// A call is answered with the first unused recorded call with the same input, and
+// fails with ErrUnmatchedCall when none matches unless WithFallback is given.
+type ReplayTool struct {
+	replayer
+	name        string
+	description string
+}
+
+// NewReplayTool creates a tool replaying the recorded calls of the named tool
+func NewReplayTool(events []Event, name, description string, opts ...Option) *ReplayTool {
+	var calls []*recordedCall
+	for _, call := range pairCalls(events, EventToolStart, EventToolEnd, EventToolError) {
+		if call.name == name {
+			calls = append(calls, call)
+		}
+	}
+	t := &ReplayTool{name: name, description: description}
+	t.init(calls, opts)
+	return t
+}
+
+// Name implements tools.Tool
+func (t *ReplayTool) Name() string {
+	return t.name
+}
+
+// Description implements tools.Tool
+func (t *ReplayTool) Description() string {
+	return t.description
+}
+
+// Call implements tools.Tool
+func (t *ReplayTool) Call(ctx context.Context, input string) (string, error) {
+	call, err := t.next(fmt.Sprintf("input %q", input), func(c *recordedCall) bool {
+		return c.input == input
+	})
+	if err != nil {
+		return "", fmt.Errorf("tool %s: %w", t.name, err)
+	}
+	if call.err != "" {
+		return "", errors.New(call.err)
+	}
+	return call.output, nil
+}
+
+// Remaining returns the number of recorded calls of the tool not replayed yet
+func (t *ReplayTool) Remaining() int {
+	return t.remaining()
+}
+
+// Mismatches returns the inputs that matched no recorded call and were answered
+// with WithFallback
+func (t *ReplayTool) Mismatches() []string {
+	return t.mismatchedRequests()
+}
+
+// comparableEvent returns the parts of an event that must match between two runs,
+// leaving out sequence numbers, timestamps, run IDs and the name of LLM runs, which
+// names the model implementation and differs when replaying
+func comparableEvent(event Event) Event {
+	switch event.Type {
+	case EventLLMStart, EventLLMEnd, EventLLMError:
+		event.Name = ""
+	}
+	return Event{
+		Type:     event.Type,
+		Name:     event.Name,
+		Node:     event.Node,
+		Input:    event.Input,
+		Output:   event.Output,
+		Prompts:  event.Prompts,
+		Response: event.Response,
+		Error:    event.Error,
+	}
+}
+
+// Diff compares a replayed run with its recording and describes each event that
+// differs, ignoring sequence numbers, timestamps, run IDs and LLM implementation names.
+// Events are compared in order per node, so the events of parallel nodes may
+// interleave differently in the two runs. It returns nil when the runs match.
+func Diff(expected, actual []Event) []string {
+	want, nodes := eventsByNode(expected)
+	got, actualNodes := eventsByNode(actual)
+	for _, node := range actualNodes {
+		if _, ok := want[node]; !ok {
+			nodes = append(nodes, node)
+		}
+	}
+
+	var diffs []string
+	for _, node := range nodes {
+		diffs = append(diffs, diffEvents(node, want[node], got[node])...)
+	}
+	return diffs
+}
+
+// eventsByNode groups events by the node they belong to, with "" for the events of
+// the graph itself, and returns the nodes in order of appearance
+func eventsByNode(events []Event) (map[string][]Event, []string) {
+	byNode := make(map[string][]Event)
+	var nodes []string
+	for _, event := range events {
+		if _, ok := byNode[event.Node]; !ok {
+			nodes = append(nodes, event.Node)
+		}
+		byNode[event.Node] = append(byNode[event.Node], event)
+	}
+	return byNode, nodes
+}
+
+// diffEvents compares the events of a node by position
+func diffEvents(node string, expected, actual []Event) []string {
+	scope := "graph"
+	if node != "" {
+		scope = "node " + node
+	}
+
+	var diffs []string
+	n := len(expected)
+	if len(actual) > n {
+		n = len(actual)
+	}
+	for i := 0; i < n; i++ {
+		switch {
+		case i >= len(actual):
+			diffs = append(diffs, fmt.Sprintf("%s event %d: missing %s %s", scope, i+1, expected[i].Type, expected[i].Name))
+		case i >= len(expected):
+			diffs = append(diffs, fmt.Sprintf("%s event %d: unexpected %s %s", scope, i+1, actual[i].Type, actual[i].Name))
+		default:
+			want, _ := json.Marshal(comparableEvent(expected[i]))
+			got, _ := json.Marshal(comparableEvent(actual[i]))
+			if string(want) != string(got) {
+				diffs = append(diffs, fmt.Sprintf("%s event %d: expected %s, got %s", scope, i+1, want, got))
+			}
+		}
+	}
+	return diffs
+}
+
+var (
+	_ llms.Model = (*ReplayModel)(nil)
+	_ tools.Tool = (*ReplayTool)(nil)
+)
@@ -0,0 +1,209 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smallnest/langgraphgo/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/tools"
+)
+
+// liveModel answers with a response derived from the prompt
+type liveModel struct {
+	calls int
+}
+
+func (m *liveModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.calls++
+	prompt := textPrompts(messages)[0]
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+		Content:        fmt.Sprintf("search for %s", strings.ToUpper(prompt)),
+		GenerationInfo: map[string]interface{}{"PromptTokens": 3, "CompletionTokens": 4},
+	}}}, nil
+}
+
+func (m *liveModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// liveTool returns a result derived from its input
+type liveTool struct{}
+
+func (liveTool) Name() string        { return "search" }
+func (liveTool) Description() string { return "search the web" }
+func (liveTool) Call(ctx context.Context, input string) (string, error) {
+	return "results for " + input, nil
+}
+
+// buildAgent builds a graph asking the model what to search and running the search
+func buildAgent(t *testing.T, model llms.Model, search tools.Tool) *graph.StateRunnable {
+	g := graph.NewStateGraph()
+	g.AddNode("plan", "plan", func(ctx context.Context, state interface{}) (interface{}, error) {
+		resp, err := graph.GenerateContent(ctx, model, []llms.MessageContent{
+			llms.TextParts(llms.ChatMessageTypeHuman, state.(string)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return resp.Choices[0].Content, nil
+	})
+	g.AddNode("search", "search", func(ctx context.Context, state interface{}) (interface{}, error) {
+		return graph.CallTool(ctx, search, state.(string))
+	})
+	g.SetEntryPoint("plan")
+	g.AddConditionalEdge("plan", func(ctx context.Context, state interface{}) string {
+		if strings.HasPrefix(state.(string), "search") {
+			return "search"
+		}
+		return graph.END
+	})
+	g.AddEdge("search", graph.END)
+
+	runnable, err := g.Compile()
+	assert.NoError(t, err)
+	return runnable
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "run.jsonl")
+
+	// Record a live run
+	recorder, err := NewFileRecorder(path)
+	assert.NoError(t, err)
+	live := &liveModel{}
+	result, err := buildAgent(t, live, liveTool{}).InvokeWithConfig(ctx, "golang", &graph.Config{
+		Callbacks: []graph.CallbackHandler{recorder},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "results for search for GOLANG", result)
+	assert.NoError(t, recorder.Close())
+
+	recorded, err := LoadEvents(path)
+	assert.NoError(t, err)
+	assert.Equal(t, recorder.Events(), recorded)
+
+	var types []EventType
+	for _, event := range recorded {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []EventType{
+		EventChainStart, EventNodeStart, EventLLMStart, EventLLMEnd, EventNodeEnd, EventRoute, EventStep,
+		EventNodeStart, EventToolStart, EventToolEnd, EventNodeEnd, EventStep, EventChainEnd,
+	}, types)
+	assert.Equal(t, "plan", recorded[1].Name)
+	assert.Equal(t, `"golang"`, recorded[1].Input)
+	assert.Equal(t, recorded[0].RunID, recorded[1].ParentRunID)
+	assert.Equal(t, "plan", recorded[2].Node)
+	assert.Equal(t, []string{"golang"}, recorded[2].Prompts)
+	assert.Equal(t, recorded[1].RunID, recorded[2].ParentRunID)
+	assert.Equal(t, "plan", recorded[5].Name)
+	assert.Equal(t, []interface{}{"search"}, recorded[5].Output)
+	assert.Equal(t, "search", recorded[8].Node)
+	assert.Equal(t, recorded[7].RunID, recorded[8].ParentRunID)
+
+	// Replay it offline
+	model := NewReplayModel(recorded)
+	search := NewReplayTool(recorded, "search", "search the web")
+	var buf bytes.Buffer
+	replayRecorder := NewRecorder(&buf)
+	result, err = buildAgent(t, model, search).InvokeWithConfig(ctx, "golang", &graph.Config{
+		Callbacks: []graph.CallbackHandler{replayRecorder},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "results for search for GOLANG", result)
+	assert.Equal(t, 0, model.Remaining())
+	assert.Equal(t, 0, search.Remaining())
+	assert.Equal(t, 1, live.calls)
+
+	replayed, err := ReadEvents(&buf)
+	assert.NoError(t, err)
+	assert.Nil(t, Diff(recorded, replayed))
+
+	// A changed run is reported
+	changed := append([]Event(nil), replayed...)
+	changed[9].Output = "other results"
+	diffs := Diff(recorded, changed[:10])
+	assert.Len(t, diffs, 4)
+	assert.Contains(t, diffs, "graph event 4: missing step step:[search]")
+	assert.Contains(t, diffs[2], "node search event 3: expected")
+	assert.Contains(t, diffs, "node search event 4: missing node_end search")
+
+	// Nothing is left to replay
+	_, err = model.GenerateContent(ctx, []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "golang")})
+	assert.True(t, errors.Is(err, ErrNoRecordedCall))
+}
+
+func TestReplayMatchesRequests(t *testing.T) {
+	events := []Event{
+		{Type: EventLLMStart, RunID: "1", Prompts: []string{"a"}},
+		{Type: EventLLMEnd, RunID: "1", Response: &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "A"}}}},
+		{Type: EventLLMStart, RunID: "2", Prompts: []string{"b"}},
+		{Type: EventLLMError, RunID: "2", Error: "rate limited"},
+		{Type: EventToolStart, RunID: "3", Name: "calc", Input: "1+1"},
+		{Type: EventToolEnd, RunID: "3", Output: "2"},
+		{Type: EventToolStart, RunID: "4", Name: "calc", Input: "2+2"},
+		{Type: EventToolEnd, RunID: "4", Output: "4"},
+		{Type: EventToolStart, RunID: "5", Name: "other", Input: "x"},
+		{Type: EventToolEnd, RunID: "5", Output: "y"},
+	}
+	ctx := context.Background()
+
+	// Calls made in a different order get the response recorded for their request
+	model := NewReplayModel(events)
+	_, err := model.GenerateContent(ctx, []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "b")})
+	assert.EqualError(t, err, "rate limited")
+	resp, err := model.GenerateContent(ctx, []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "a")})
+	assert.NoError(t, err)
+	assert.Equal(t, "A", resp.Choices[0].Content)
+
+	calc := NewReplayTool(events, "calc", "calculator")
+	out, err := calc.Call(ctx, "2+2")
+	assert.NoError(t, err)
+	assert.Equal(t, "4", out)
+	// Unknown inputs fail the replay
+	_, err = calc.Call(ctx, "3+3")
+	assert.ErrorIs(t, err, ErrUnmatchedCall)
+	out, err = calc.Call(ctx, "1+1")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", out)
+	_, err = calc.Call(ctx, "1+1")
+	assert.True(t, errors.Is(err, ErrNoRecordedCall))
+
+	// With a fallback they get the next recorded call and are reported
+	lenient := NewReplayTool(events, "calc", "calculator", WithFallback())
+	out, err = lenient.Call(ctx, "3+3")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", out)
+	assert.Equal(t, []string{`input "3+3"`}, lenient.Mismatches())
+
+	_, err = NewReplayModel(events).GenerateContent(ctx, []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "c")})
+	assert.ErrorIs(t, err, ErrUnmatchedCall)
+}
+
+func TestDiffParallelNodes(t *testing.T) {
+	recorded := []Event{
+		{Type: EventChainStart, Name: "graph"},
+		{Type: EventNodeStart, Name: "a", Node: "a"},
+		{Type: EventNodeStart, Name: "b", Node: "b"},
+		{Type: EventNodeEnd, Name: "b", Node: "b", Output: "B"},
+		{Type: EventNodeEnd, Name: "a", Node: "a", Output: "A"},
+		{Type: EventChainEnd, Name: "graph"},
+	}
+	// The parallel nodes interleave differently in the replay
+	replayed := []Event{recorded[0], recorded[2], recorded[1], recorded[4], recorded[3], recorded[5]}
+	assert.Nil(t, Diff(recorded, replayed))
+
+	replayed[3].Output = "changed"
+	diffs := Diff(recorded, replayed)
+	assert.Len(t, diffs, 1)
+	assert.True(t, strings.HasPrefix(diffs[0], "node a event 2: expected"))
+	assert.Contains(t, diffs[0], `"output":"changed"`)
+}
@@ -0,0 +1,252 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FuncTool is a SchemaTool calling a typed Go function. The schema of its arguments
+// is derived from the input type, and the model's arguments are validated and
+// decoded into it before each call.
+type FuncTool[In, Out any] struct {
+	name        string
+	description string
+	fn          func(ctx context.Context, in In) (Out, error)
+	parameters  map[string]any
+}
+
+var _ SchemaTool = &FuncTool[struct{}, string]{}
+
+// FromFunc creates a tool calling fn. In is usually a struct whose fields are the
+// tool arguments, named by their json tags. Fields without omitempty are required,
+// and a jsonschema tag adds a description, enum values or marks a field required:
+//
+//	type WeatherInput struct {
+//		City string `json:"city" jsonschema:"description=Name of the city"`
+//		Unit string `json:"unit,omitempty" jsonschema:"enum=celsius,enum=fahrenheit"`
+//	}
+//
+// Results are returned as is when Out is a string and encoded as JSON otherwise.
+func FromFunc[In, Out any](name, description string, fn func(ctx context.Context, in In) (Out, error)) *FuncTool[In, Out] {
+	return &FuncTool[In, Out]{
+		name:        name,
+		description: description,
+		fn:          fn,
+		parameters:  SchemaOf(reflect.TypeOf((*In)(nil)).Elem()),
+	}
+}
+
+// Name returns the name of the tool
+func (t *FuncTool[In, Out]) Name() string {
+	return t.name
+}
+
+// Description returns the description of the tool
+func (t *FuncTool[In, Out]) Description() string {
+	return t.description
+}
+
+// Parameters returns the JSON Schema derived from the input type
+func (t *FuncTool[In, Out]) Parameters() map[string]any {
+	return t.parameters
+}
+
+// Call validates and decodes the JSON arguments, calls the function and encodes its result
+func (t *FuncTool[In, Out]) Call(ctx context.Context, input string) (string, error) {
+	if strings.TrimSpace(input) == "" {
+		input = "{}"
+	}
+	if err := ValidateArguments(t.parameters, input); err != nil {
+		return "", fmt.Errorf("invalid arguments for tool %s: %w", t.name, err)
+	}
+
+	var in In
+	if err := json.Unmarshal([]byte(input), &in); err != nil {
+		return "", fmt.Errorf("invalid arguments for tool %s: %w", t.name, err)
+	}
+
+	out, err := t.fn(ctx, in)
+	if err != nil {
+		return "", err
+	}
+
+	if s, ok := any(out).(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode result of tool %s: %w", t.name, err)
+	}
+	return string(data), nil
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// SchemaOf returns the JSON Schema of the JSON encoding of a Go type, using the
+// json and jsonschema tags of struct fields (see FromFunc)
+func SchemaOf(typ reflect.Type) map[string]any {
+	return schemaOf(typ, make(map[reflect.Type]bool))
+}
+
+func schemaOf(typ reflect.Type, visiting map[reflect.Type]bool) map[string]any {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	switch typ {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]any{}
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 && typ.Kind() == reflect.Slice {
+			// []byte is encoded as a base64 string
+			return map[string]any{"type": "string"}
+		}
+		return map[string]any{"type": "array", "items": schemaOf(typ.Elem(), visiting)}
+	case reflect.Map:
+		if typ.Key().Kind() != reflect.String {
+			return map[string]any{"type": "object"}
+		}
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(typ.Elem(), visiting)}
+	case reflect.Struct:
+		if visiting[typ] {
+			// Recursive types are not described below their first level
+			return map[string]any{"type": "object"}
+		}
+		visiting[typ] = true
+		defer delete(visiting, typ)
+
+		properties := make(map[string]any)
+		required := []string{}
+		addStructFields(typ, properties, &required, visiting)
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}
+	default:
+		// Interfaces accept any value; other kinds are not encoded by encoding/json
+		return map[string]any{}
+	}
+}
+
+// addStructFields adds the properties of the exported fields of a struct, including
+// the fields of embedded structs as encoding/json does
+func addStructFields(typ reflect.Type, properties map[string]any, required *[]string, visiting map[reflect.Type]bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		jsonTag := field.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(jsonTag, ",")
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			addStructFields(fieldType, properties, required, visiting)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := schemaOf(field.Type, visiting)
+		isRequired := !strings.Contains(","+opts+",", ",omitempty,")
+		applySchemaTag(field.Tag.Get("jsonschema"), fieldType, schema, &isRequired)
+		properties[name] = schema
+		if isRequired {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// applySchemaTag applies a jsonschema tag such as
+// `jsonschema:"description=The city,enum=paris,enum=london,required"`, where commas in
+// values are escaped as \,
+func applySchemaTag(tag string, typ reflect.Type, schema map[string]any, required *bool) {
+	if tag == "" {
+		return
+	}
+	var enum []any
+	for _, part := range splitTag(tag) {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "description":
+			schema["description"] = value
+		case "enum":
+			enum = append(enum, enumValue(typ, value))
+		case "required":
+			*required = true
+		}
+	}
+	if len(enum) > 0 {
+		schema["enum"] = enum
+	}
+}
+
+// splitTag splits a tag on the commas that are not escaped
+func splitTag(tag string) []string {
+	var parts []string
+	var current strings.Builder
+	for i := 0; i < len(tag); i++ {
+		switch {
+		case tag[i] == '\\' && i+1 < len(tag) && tag[i+1] == ',':
+			current.WriteByte(',')
+			i++
+		case tag[i] == ',':
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(tag[i])
+		}
+	}
+	return append(parts, current.String())
+}
+
+// enumValue converts an enum value of a tag to the JSON type of the field
+func enumValue(typ reflect.Type, value string) any {
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
@@ -0,0 +1,91 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Paging struct {
+	Limit int `json:"limit,omitempty" jsonschema:"description=Maximum number of results,enum=10,enum=50"`
+}
+
+type searchInput struct {
+	Paging
+	Query   string            `json:"query" jsonschema:"description=Search query\\, in plain words"`
+	Sort    string            `json:"sort,omitempty" jsonschema:"enum=relevance,enum=date"`
+	Tags    []string          `json:"tags,omitempty"`
+	Filters map[string]string `json:"filters,omitempty" jsonschema:"required"`
+	Since   *time.Time        `json:"since,omitempty"`
+	Debug   bool              `json:"-"`
+	secret  string
+}
+
+type searchResult struct {
+	Titles []string `json:"titles"`
+	Total  int      `json:"total"`
+}
+
+func TestSchemaOf(t *testing.T) {
+	schema := SchemaOf(reflect.TypeOf(searchInput{}))
+	assert.Equal(t, map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"limit":   map[string]any{"type": "integer", "description": "Maximum number of results", "enum": []any{int64(10), int64(50)}},
+			"query":   map[string]any{"type": "string", "description": "Search query, in plain words"},
+			"sort":    map[string]any{"type": "string", "enum": []any{"relevance", "date"}},
+			"tags":    map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			"filters": map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}},
+			"since":   map[string]any{"type": "string", "format": "date-time"},
+		},
+		"required":             []string{"query", "filters"},
+		"additionalProperties": false,
+	}, schema)
+
+	// Recursive types stop at their first level
+	type node struct {
+		Children []node `json:"children"`
+	}
+	assert.Equal(t, map[string]any{"type": "object"}, SchemaOf(reflect.TypeOf(node{}))["properties"].(map[string]any)["children"].(map[string]any)["items"])
+}
+
+func TestFromFunc(t *testing.T) {
+	var received searchInput
+	search := FromFunc("search", "Search documents", func(ctx context.Context, in searchInput) (searchResult, error) {
+		received = in
+		if in.Query == "fail" {
+			return searchResult{}, errors.New("backend unavailable")
+		}
+		return searchResult{Titles: []string{"a", "b"}, Total: 2}, nil
+	})
+	var _ SchemaTool = search
+	assert.Equal(t, "search", search.Name())
+	assert.Equal(t, "Search documents", search.Description())
+	assert.Equal(t, SchemaOf(reflect.TypeOf(searchInput{})), search.Parameters())
+
+	out, err := search.Call(context.Background(), `{"query": "go", "limit": 10, "filters": {"lang": "en"}}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"titles": ["a", "b"], "total": 2}`, out)
+	assert.Equal(t, searchInput{Paging: Paging{Limit: 10}, Query: "go", Filters: map[string]string{"lang": "en"}}, received)
+
+	_, err = search.Call(context.Background(), `{"query": "go", "limit": 20, "filters": {}}`)
+	assert.EqualError(t, err, "invalid arguments for tool search: arguments.limit must be one of [10 50]")
+
+	_, err = search.Call(context.Background(), `{"query": "fail", "filters": {}}`)
+	assert.EqualError(t, err, "backend unavailable")
+
+	// String results are returned as they are
+	echo := FromFunc("echo", "Echo", func(ctx context.Context, in struct {
+		Text string `json:"text"`
+	}) (string, error) {
+		return in.Text, nil
+	})
+	out, err = echo.Call(context.Background(), `{"text": "hello"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", out)
+}
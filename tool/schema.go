@@ -0,0 +1,191 @@
+package tool
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/tmc/langchaingo/tools"
+)
+
+// SchemaTool is a tool whose arguments are described by a JSON Schema. Agents
+// advertise the schema to the model and call the tool with the raw JSON arguments
+// of the model's tool call, instead of a single "input" string.
+type SchemaTool interface {
+	tools.Tool
+
+	// Parameters returns the JSON Schema of the tool arguments
+	Parameters() map[string]any
+}
+
+// InputSchema is the schema advertised for tools that are not SchemaTools: an
+// object with a single "input" string
+func InputSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"input": map[string]any{
+				"type":        "string",
+				"description": "The input query for the tool",
+			},
+		},
+		"required":             []string{"input"},
+		"additionalProperties": false,
+	}
+}
+
+// ToolParameters returns the JSON Schema advertised for a tool. SchemaTools without
+// parameters take an empty object.
+func ToolParameters(t tools.Tool) map[string]any {
+	st, ok := t.(SchemaTool)
+	if !ok {
+		return InputSchema()
+	}
+	if params := st.Parameters(); params != nil {
+		return params
+	}
+	return map[string]any{"type": "object", "properties": map[string]any{}}
+}
+
+// ValidateArguments checks the JSON arguments of a tool call against the schema. It
+// supports the keywords used to describe tool arguments: type, properties, required,
+// additionalProperties, items and enum.
+func ValidateArguments(schema map[string]any, arguments string) error {
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+	var value any
+	if err := json.Unmarshal([]byte(arguments), &value); err != nil {
+		return fmt.Errorf("arguments are not valid JSON: %w", err)
+	}
+	return validateValue(schema, value, "arguments")
+}
+
+func validateValue(schema map[string]any, value any, path string) error {
+	if schema == nil {
+		return nil
+	}
+
+	if typ, ok := schema["type"].(string); ok && !hasType(value, typ) {
+		return fmt.Errorf("%s must be of type %s, got %s", path, typ, jsonType(value))
+	}
+
+	if enum, ok := schema["enum"]; ok {
+		if !inEnum(enum, value) {
+			return fmt.Errorf("%s must be one of %v", path, enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		for _, name := range stringList(schema["required"]) {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s is missing required property %q", path, name)
+			}
+		}
+
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			propSchema, known := properties[name].(map[string]any)
+			if !known {
+				if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+					return fmt.Errorf("%s has unknown property %q", path, name)
+				}
+				propSchema, _ = schema["additionalProperties"].(map[string]any)
+			}
+			if err := validateValue(propSchema, v[name], path+"."+name); err != nil {
+				return err
+			}
+		}
+	case []any:
+		items, _ := schema["items"].(map[string]any)
+		for i, item := range v {
+			if err := validateValue(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hasType reports whether a decoded JSON value has the JSON Schema type
+func hasType(value any, typ string) bool {
+	switch typ {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return jsonType(value) == typ
+	}
+}
+
+// jsonType returns the JSON Schema type of a decoded JSON value
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// inEnum reports whether the value is one of the enum values, which may come from
+// a Go literal or from decoded JSON
+func inEnum(enum any, value any) bool {
+	list := reflect.ValueOf(enum)
+	if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+		return true
+	}
+	for i := 0; i < list.Len(); i++ {
+		if sameJSON(list.Index(i).Interface(), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameJSON compares values by their JSON encoding, so that e.g. int 1 matches 1.0
+func sameJSON(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
+
+// stringList returns the strings of a []string or a decoded []any
+func stringList(value any) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []any:
+		var out []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
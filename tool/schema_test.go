@@ -0,0 +1,65 @@
+package tool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateArguments(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"query": map[string]any{"type": "string"},
+			"limit": map[string]any{"type": "integer"},
+			"tags":  map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			"mode":  map[string]any{"type": "string", "enum": []any{"fast", "deep"}},
+		},
+		"required":             []any{"query"},
+		"additionalProperties": false,
+	}
+
+	tests := []struct {
+		name      string
+		arguments string
+		err       string
+	}{
+		{name: "valid", arguments: `{"query": "go", "limit": 3, "tags": ["a"], "mode": "deep"}`},
+		{name: "missing required", arguments: `{"limit": 3}`, err: `arguments is missing required property "query"`},
+		{name: "empty", arguments: "", err: `arguments is missing required property "query"`},
+		{name: "wrong type", arguments: `{"query": 1}`, err: "arguments.query must be of type string, got number"},
+		{name: "not an integer", arguments: `{"query": "go", "limit": 1.5}`, err: "arguments.limit must be of type integer, got number"},
+		{name: "item type", arguments: `{"query": "go", "tags": ["a", 2]}`, err: "arguments.tags[1] must be of type string, got number"},
+		{name: "enum", arguments: `{"query": "go", "mode": "slow"}`, err: "arguments.mode must be one of [fast deep]"},
+		{name: "unknown property", arguments: `{"query": "go", "page": 2}`, err: `arguments has unknown property "page"`},
+		{name: "not an object", arguments: `["go"]`, err: "arguments must be of type object, got array"},
+		{name: "invalid JSON", arguments: `{"query":`, err: "arguments are not valid JSON: unexpected end of JSON input"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateArguments(schema, tt.arguments)
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
+
+type plainTool struct{}
+
+func (plainTool) Name() string                                        { return "plain" }
+func (plainTool) Description() string                                 { return "plain tool" }
+func (plainTool) Call(ctx context.Context, in string) (string, error) { return in, nil }
+
+type noParamsTool struct{ plainTool }
+
+func (noParamsTool) Parameters() map[string]any { return nil }
+
+func TestToolParameters(t *testing.T) {
+	assert.Equal(t, InputSchema(), ToolParameters(plainTool{}))
+	assert.Equal(t, map[string]any{"type": "object", "properties": map[string]any{}}, ToolParameters(noParamsTool{}))
+}